@@ -6,5 +6,6 @@ require (
 	github.com/dop251/goja v0.0.0-20201022115936-e21ccf39bfce
 	github.com/loadimpact/k6 v0.29.0
 	github.com/stretchr/testify v1.2.2
+	golang.org/x/text v0.3.3
 	gopkg.in/yaml.v2 v2.3.0
 )
@@ -0,0 +1,343 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Agents is the $262.agent of a test, running every agent it starts in a runtime of its own on
+// a goroutine. SharedArrayBuffer is stubbed, but broadcast hands the agents the memory of any
+// ArrayBuffer, so only the stub has to go once goja supports it. Until then the tests only using
+// sleep and monotonicNow run.
+type tc39Agents struct {
+	ctx  *tc39TestCtx
+	name string
+	out  *tc39Output
+	// canBlock is false for the tests with the CanBlockIsFalse flag, whose main thread must not
+	// block.
+	canBlock bool
+	start    time.Time
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	agents  []*tc39Agent
+	reports []string
+	errs    []string
+}
+
+type tc39Agent struct {
+	vm         *goja.Runtime
+	broadcasts chan tc39Broadcast
+	// exited is closed once the agent can't receive broadcasts anymore.
+	exited chan struct{}
+}
+
+type tc39Broadcast struct {
+	buf []byte // nil if what was broadcast wasn't an ArrayBuffer
+	num interface{}
+}
+
+func newAgents(ctx *tc39TestCtx, name string, canBlock bool, out *tc39Output) *tc39Agents {
+	return &tc39Agents{
+		ctx: ctx, name: name, out: out, canBlock: canBlock, start: time.Now(), done: make(chan struct{}),
+	}
+}
+
+// install adds $262.agent with the functions of the main thread to the runtime of the test.
+func (a *tc39Agents) install(vm *goja.Runtime) {
+	agent := vm.NewObject()
+	_ = agent.Set("start", func(call goja.FunctionCall) goja.Value {
+		a.startAgent(vm, call.Argument(0).String())
+		return goja.Undefined()
+	})
+	_ = agent.Set("broadcast", func(call goja.FunctionCall) goja.Value {
+		a.checkCanBlock(vm, "broadcast")
+		a.broadcast(vm, call.Argument(0), call.Argument(1))
+		return goja.Undefined()
+	})
+	_ = agent.Set("getReport", func(goja.FunctionCall) goja.Value {
+		if report, ok := a.getReport(); ok {
+			return vm.ToValue(report)
+		}
+		return goja.Null()
+	})
+	_ = agent.Set("sleep", func(call goja.FunctionCall) goja.Value {
+		a.checkCanBlock(vm, "sleep")
+		a.sleep(call.Argument(0).ToInteger())
+		return goja.Undefined()
+	})
+	_ = agent.Set("monotonicNow", a.monotonicNow)
+	if _262, ok := vm.Get("$262").(*goja.Object); ok {
+		_ = _262.Set("agent", agent)
+	}
+}
+
+// canBlock returns whether the main thread of the test can block, calling Atomics.wait or the
+// blocking functions of $262.agent. The runner's can unless the test has the CanBlockIsFalse flag,
+// a test with CanBlockIsTrue too can't be run either way.
+func canBlock(meta *tc39Meta) (bool, error) {
+	isFalse, isTrue := meta.hasFlag("CanBlockIsFalse"), meta.hasFlag("CanBlockIsTrue")
+	if isFalse && isTrue {
+		return false, errors.New("both CanBlockIsFalse and CanBlockIsTrue are set")
+	}
+	return !isFalse, nil
+}
+
+func (a *tc39Agents) checkCanBlock(vm *goja.Runtime, what string) {
+	if !a.canBlock {
+		panic(vm.NewTypeError("$262.agent.%s blocks, which the main thread of a CanBlockIsFalse test can't", what))
+	}
+}
+
+func (a *tc39Agents) startAgent(main *goja.Runtime, src string) {
+	vm, _, _ := a.ctx.newRuntime(a.name, a.out)
+	a.mu.Lock()
+	agent := &tc39Agent{vm: vm, broadcasts: make(chan tc39Broadcast), exited: make(chan struct{})}
+	a.agents = append(a.agents, agent)
+	id := len(a.agents)
+	a.mu.Unlock()
+	prg := a.ctx.compileScript(main, fmt.Sprintf("%s (agent %d)", a.name, id), src)
+
+	var receive goja.Callable
+	api := vm.NewObject()
+	_ = api.Set("receiveBroadcast", func(call goja.FunctionCall) goja.Value {
+		fn, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(vm.NewTypeError("receiveBroadcast needs a function"))
+		}
+		receive = fn
+		return goja.Undefined()
+	})
+	_ = api.Set("report", func(call goja.FunctionCall) goja.Value {
+		a.report(call.Argument(0).String())
+		return goja.Undefined()
+	})
+	_ = api.Set("leaving", func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	_ = api.Set("sleep", func(call goja.FunctionCall) goja.Value {
+		a.sleep(call.Argument(0).ToInteger())
+		return goja.Undefined()
+	})
+	_ = api.Set("monotonicNow", a.monotonicNow)
+	_ = vm.Get("$262").(*goja.Object).Set("agent", api)
+
+	// not through ctx.spawn, see tc39Spawner
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer close(agent.exited)
+		defer func() {
+			if x := recover(); x != nil {
+				a.fail(id, fmt.Errorf("panic: %v", x))
+			}
+		}()
+		if _, err := vm.RunProgram(prg); err != nil {
+			a.fail(id, err)
+			return
+		}
+		for receive != nil {
+			select {
+			case b := <-agent.broadcasts:
+				buf := goja.Undefined()
+				if b.buf != nil {
+					buf = vm.ToValue(vm.NewArrayBuffer(b.buf))
+				}
+				if _, err := receive(goja.Undefined(), buf, vm.ToValue(b.num)); err != nil {
+					a.fail(id, err)
+					return
+				}
+			case <-a.done:
+				return
+			}
+		}
+	}()
+}
+
+// broadcast blocks until every agent still running got the message.
+func (a *tc39Agents) broadcast(vm *goja.Runtime, buf, num goja.Value) {
+	b := tc39Broadcast{num: num.Export()}
+	if o, ok := buf.(*goja.Object); ok {
+		if ab, ok := o.Export().(goja.ArrayBuffer); ok {
+			b.buf = ab.Bytes()
+		}
+	}
+	a.mu.Lock()
+	agents := append([]*tc39Agent(nil), a.agents...)
+	a.mu.Unlock()
+	for _, agent := range agents {
+		select {
+		case agent.broadcasts <- b:
+		case <-agent.exited:
+		case <-a.done:
+			panic(vm.NewTypeError("the test ended"))
+		}
+	}
+}
+
+func (a *tc39Agents) report(report string) {
+	a.mu.Lock()
+	a.reports = append(a.reports, report)
+	a.mu.Unlock()
+}
+
+// getReport returns the oldest report no one got yet.
+func (a *tc39Agents) getReport() (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.reports) == 0 {
+		return "", false
+	}
+	report := a.reports[0]
+	a.reports = a.reports[1:]
+	return report, true
+}
+
+func (a *tc39Agents) sleep(ms int64) {
+	timer := time.NewTimer(time.Duration(ms) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-a.done:
+	}
+}
+
+// monotonicNow is the milliseconds since the test started, goja converting it for whichever
+// runtime calls it.
+func (a *tc39Agents) monotonicNow() float64 {
+	return float64(time.Since(a.start)) / float64(time.Millisecond)
+}
+
+func (a *tc39Agents) fail(id int, err error) {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		return
+	}
+	a.mu.Lock()
+	a.errs = append(a.errs, fmt.Sprintf("agent %d: %v", id, err))
+	a.mu.Unlock()
+}
+
+// stop interrupts the agents still running and waits for their goroutines to end, returning what
+// the agents threw. Only the first call does anything.
+func (a *tc39Agents) stop() []string {
+	var errs []string
+	a.stopOnce.Do(func() {
+		close(a.done)
+		a.mu.Lock()
+		for _, agent := range a.agents {
+			agent.vm.Interrupt("the test ended")
+		}
+		a.mu.Unlock()
+		a.wg.Wait()
+		a.mu.Lock()
+		errs = a.errs
+		a.mu.Unlock()
+	})
+	return errs
+}
+
+func TestAgentReports(t *testing.T) {
+	a := newAgents(newFixtureCtx(t), "test/agents.js", true, &tc39Output{})
+	defer a.stop()
+	_, ok := a.getReport()
+	require.False(t, ok)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		a.report(fmt.Sprint(i))
+		// concurrent reports are all kept, in some order
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.report("concurrent")
+		}()
+	}
+	wg.Wait()
+	var reports []string
+	for report, ok := a.getReport(); ok; report, ok = a.getReport() {
+		if report != "concurrent" {
+			reports = append(reports, report)
+		}
+	}
+	require.Equal(t, []string{"0", "1", "2"}, reports)
+}
+
+func TestAgents(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	run := func(name, src string, flags ...string) TestResult {
+		tb := &tc39CountingTB{TB: t}
+		ctx.runTC39Test(tb, name, src, &tc39Meta{Flags: flags}, false)
+		return ctx.results.resultsCopy()[name+"-strict:false"]
+	}
+
+	result := run("test/agents.js", `
+for (var i = 0; i < 3; i++) {
+  $262.agent.start(
+    "$262.agent.receiveBroadcast(function(buf, num) {" +
+    "  $262.agent.sleep(10 * num);" +
+    "  $262.agent.report(String(num + new Uint8Array(buf)[0]));" +
+    "  $262.agent.leaving();" +
+    "});");
+}
+var buf = new ArrayBuffer(1);
+new Uint8Array(buf)[0] = 40;
+$262.agent.broadcast(buf, 2);
+var reports = [], start = $262.agent.monotonicNow();
+while (reports.length < 3 && $262.agent.monotonicNow() - start < 5000) {
+  var report = $262.agent.getReport();
+  if (report === null) {
+    $262.agent.sleep(1);
+  } else {
+    reports.push(report);
+  }
+}
+assert.sameValue(reports.join(), "42,42,42");
+assert($262.agent.monotonicNow() - start >= 20, "the agents slept");`)
+	require.Equal(t, CategoryPass, result.Category, result.Message)
+
+	// the main thread of a CanBlockIsFalse test can't block
+	for _, src := range []string{`$262.agent.sleep(1);`, `$262.agent.broadcast(new ArrayBuffer(1), 1);`} {
+		result = run("test/cant-block.js", src, "CanBlockIsFalse")
+		require.Equal(t, CategoryNewFailure, result.Category)
+		require.Contains(t, result.Message, "TypeError: $262.agent.", src)
+		require.Contains(t, result.Message, "the main thread of a CanBlockIsFalse test can't", src)
+	}
+	require.Equal(t, CategoryPass, run("test/can-block.js", `$262.agent.sleep(1);`).Category)
+	require.Equal(t, CategoryPass, run("test/can-block-true.js", `$262.agent.sleep(1);`, "CanBlockIsTrue").Category)
+
+	// the agents don't outlive the test
+	start := time.Now()
+	result = run("test/forever.js", `$262.agent.start("while (true) {}");
+$262.agent.start("$262.agent.receiveBroadcast(function() {});");
+$262.agent.start("$262.agent.sleep(60000);");`)
+	require.Equal(t, CategoryPass, result.Category, result.Message)
+	require.True(t, time.Since(start) < 30*time.Second)
+
+	result = run("test/agent-throws.js", `$262.agent.start("throw new TypeError('in the agent')");
+$262.agent.sleep(50);`)
+	require.Equal(t, CategoryNewFailure, result.Category)
+	require.True(t, strings.HasPrefix(result.Message, "test/agent-throws.js: agent 1: TypeError: in the agent"), result.Message)
+}
+
+func TestCanBlock(t *testing.T) {
+	for flags, expected := range map[string]bool{"": true, "CanBlockIsTrue": true, "CanBlockIsFalse": false} {
+		blocking, err := canBlock(&tc39Meta{Flags: strings.Fields(flags)})
+		require.NoError(t, err, flags)
+		require.Equal(t, expected, blocking, flags)
+	}
+
+	ctx := newFixtureCtx(t)
+	t.Run("test/can-block-both.js", func(t *testing.T) {
+		ctx.runTC39File("test/can-block-both.js", "test/can-block-both.js", t)
+	})
+	result := ctx.results.resultsCopy()["test/can-block-both.js-strict:false"]
+	require.Equal(t, CategorySkippedFeature, result.Category)
+	require.Equal(t, "Unsupported blocking mode: both CanBlockIsFalse and CanBlockIsTrue are set", result.Message)
+}
@@ -0,0 +1,210 @@
+package test262
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	tc39ArtifactsDir = "artifacts"
+	tc39ManifestFile = "manifest.json"
+)
+
+// writeArtifact creates the file name and writes it buffered through write. A file that couldn't
+// be completely flushed and closed is as broken as one that couldn't be written, so all three
+// errors are returned, naming the file.
+func writeArtifact(name string, write func(w io.Writer) error) error {
+	release := openFiles.acquire()
+	defer release()
+	f, err := os.Create(name) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	return writeArtifactTo(name, f, write)
+}
+
+func writeArtifactTo(name string, wc io.WriteCloser, write func(w io.Writer) error) (err error) {
+	defer func() {
+		if closeErr := wc.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			err = fmt.Errorf("writing %s: %w", name, err)
+		}
+	}()
+	bw := bufio.NewWriter(wc)
+	if err = write(bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// tc39Artifacts keeps track of the files written by a run. The ones without a path given by
+// the user are put in a directory of the run, artifacts/<run id>/, where flush writes the
+// manifest listing all of them once the run is done reporting.
+type tc39Artifacts struct {
+	runID string
+	dir   string
+	// env is set before the tests start
+	env tc39Environment
+
+	mu      sync.Mutex
+	entries []tc39Artifact
+}
+
+type tc39Artifact struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+type tc39Manifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	RunID         string          `json:"runId"`
+	Environment   tc39Environment `json:"environment"`
+	Artifacts     []tc39Artifact  `json:"artifacts"`
+}
+
+// newRunID returns an id sorting by the time the run started, with a random suffix so runs
+// starting at the same time don't share it.
+func newRunID(now time.Time) string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+func newArtifacts(base string) *tc39Artifacts {
+	runID := newRunID(time.Now())
+	return &tc39Artifacts{runID: runID, dir: filepath.Join(base, runID)}
+}
+
+// path returns where to write the artifact with the given slash separated name, creating the
+// directories it's in.
+func (a *tc39Artifacts) path(name string) (string, error) {
+	file := osPath(a.dir, name)
+	return file, os.MkdirAll(filepath.Dir(file), 0o755)
+}
+
+// add records that an artifact of the given type was written.
+func (a *tc39Artifacts) add(typ, file string) {
+	a.mu.Lock()
+	a.entries = append(a.entries, tc39Artifact{Type: typ, Path: filepath.ToSlash(file)})
+	a.mu.Unlock()
+}
+
+// flush writes the manifest, its presence meaning that the run wrote everything it had to.
+func (a *tc39Artifacts) flush() (string, error) {
+	a.mu.Lock()
+	manifest := tc39Manifest{
+		SchemaVersion: tc39ResultsSchemaVersion, RunID: a.runID, Environment: a.env,
+		Artifacts: append(make([]tc39Artifact, 0), a.entries...),
+	}
+	a.mu.Unlock()
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	file, err := a.path(tc39ManifestFile)
+	if err != nil {
+		return "", err
+	}
+	return file, writeArtifact(file, func(w io.Writer) error {
+		_, err := w.Write(append(b, '\n'))
+		return err
+	})
+}
+
+func TestArtifactsManifest(t *testing.T) {
+	base, err := ioutil.TempDir("", "tc39-artifacts")
+	require.NoError(t, err)
+	defer os.RemoveAll(base) //nolint:errcheck
+
+	a := newArtifacts(base)
+	a.env = newEnvironment([]string{"TC39_BENCH=1"})
+	require.Regexp(t, regexp.MustCompile(`^\d{8}T\d{6}Z-[0-9a-f]{6}$`), a.runID)
+	require.NotEqual(t, a.runID, newArtifacts(base).runID)
+
+	file, err := a.path("traces/test/built-ins/Array/length.js.trace")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(file, nil, 0o644))
+	a.add("trace", file)
+	a.add("bench-report", "bench.json")
+
+	manifestFile, err := a.flush()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(base, a.runID, tc39ManifestFile), manifestFile)
+	b, err := ioutil.ReadFile(manifestFile) //nolint:gosec
+	require.NoError(t, err)
+	var manifest tc39Manifest
+	require.NoError(t, json.Unmarshal(b, &manifest))
+	require.Equal(t, tc39Manifest{SchemaVersion: tc39ResultsSchemaVersion, RunID: a.runID, Environment: a.env, Artifacts: []tc39Artifact{
+		{Type: "trace", Path: filepath.ToSlash(file)},
+		{Type: "bench-report", Path: "bench.json"},
+	}}, manifest)
+}
+
+// failingWriter fails like a full disk would, either when written to or only when closed.
+type failingWriter struct {
+	writeErr, closeErr error
+	closed             bool
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if w.writeErr != nil {
+		return 0, w.writeErr
+	}
+	return len(b), nil
+}
+
+func (w *failingWriter) Close() error {
+	w.closed = true
+	return w.closeErr
+}
+
+func TestWriteArtifactErrors(t *testing.T) {
+	errDiskFull := errors.New("no space left on device")
+	small := func(w io.Writer) error {
+		_, err := io.WriteString(w, "{}")
+		return err
+	}
+	errWrite := errors.New("couldn't marshal")
+
+	tests := []struct {
+		name   string
+		w      *failingWriter
+		write  func(w io.Writer) error
+		expect error
+	}{
+		{"ok", &failingWriter{}, small, nil},
+		// the buffer only hits the writer when flushed
+		{"flush", &failingWriter{writeErr: errDiskFull}, small, errDiskFull},
+		{"close", &failingWriter{closeErr: errDiskFull}, small, errDiskFull},
+		{"write", &failingWriter{closeErr: errDiskFull}, func(io.Writer) error { return errWrite }, errWrite},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := writeArtifactTo("out/report.json", test.w, test.write)
+			require.True(t, test.w.closed)
+			if test.expect == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, test.expect), err)
+			require.Contains(t, err.Error(), "out/report.json")
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	tc39DefaultAsyncTimeout = 10 * time.Second
+
+	tc39AsyncComplete = "Test262:AsyncTestComplete"
+	tc39AsyncFailure  = "Test262:AsyncTestFailure:"
+)
+
+//nolint:gochecknoglobals
+var (
+	// asyncTimeout is how long an async test has to call $DONE, counting the jobs it queued.
+	asyncTimeout = tc39DefaultAsyncTimeout
+
+	// unhandledRejectionTests leave a rejected promise unhandled on purpose, so it doesn't fail them.
+	unhandledRejectionTests = map[string]bool{}
+)
+
+func asyncTimeoutFromEnv() (time.Duration, error) {
+	v := os.Getenv("TC39_ASYNC_TIMEOUT")
+	if v == "" {
+		return tc39DefaultAsyncTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("TC39_ASYNC_TIMEOUT must be a positive duration, got %q", v)
+	}
+	return d, nil
+}
+
+// tc39Jobs is the job queue of a runtime. goja has no promises, the ones of core-js run their
+// reactions through whatever the host has to run something later, and newRuntime gives it a
+// Dispatch.now queueing them here, so they run once the test did instead of never.
+type tc39Jobs struct {
+	jobs []goja.Callable
+	// unhandled are the rejected promises without a handler, in the order core-js found them.
+	unhandled []tc39Rejection
+}
+
+type tc39Rejection struct {
+	promise, reason goja.Value
+}
+
+// trackRejections adds the handlers core-js calls for a rejected promise nothing handled by the
+// time its reactions ran, and for one that got a handler only later. It looks them up on the global
+// object whenever it needs them, they are there but not enumerable.
+func (j *tc39Jobs) trackRejections(vm *goja.Runtime) {
+	unhandled := func(call goja.FunctionCall) goja.Value {
+		event := call.Argument(0).ToObject(vm)
+		j.unhandled = append(j.unhandled, tc39Rejection{promise: event.Get("promise"), reason: event.Get("reason")})
+		return goja.Undefined()
+	}
+	handled := func(call goja.FunctionCall) goja.Value {
+		promise := call.Argument(0).ToObject(vm).Get("promise")
+		for i, r := range j.unhandled {
+			if r.promise.SameAs(promise) {
+				j.unhandled = append(j.unhandled[:i], j.unhandled[i+1:]...)
+				break
+			}
+		}
+		return goja.Undefined()
+	}
+	global := vm.GlobalObject()
+	_ = global.DefineDataProperty("onunhandledrejection", vm.ToValue(unhandled), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	_ = global.DefineDataProperty("onrejectionhandled", vm.ToValue(handled), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+}
+
+// unhandledRejections returns what the promises still unhandled were rejected with.
+func (j *tc39Jobs) unhandledRejections() []string {
+	reasons := make([]string, len(j.unhandled))
+	for i, r := range j.unhandled {
+		reasons[i] = thrownValue{r.reason}.Error()
+	}
+	return reasons
+}
+
+func (j *tc39Jobs) now(call goja.FunctionCall) goja.Value {
+	if fn, ok := goja.AssertFunction(call.Argument(0)); ok {
+		j.jobs = append(j.jobs, fn)
+	}
+	return goja.Undefined()
+}
+
+// drain runs the queued jobs, and the ones they queue, until there are none left.
+func (j *tc39Jobs) drain() error {
+	for len(j.jobs) > 0 {
+		job := j.jobs[0]
+		j.jobs = j.jobs[1:]
+		if _, err := job(goja.Undefined()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// asyncTimeoutError interrupts an async test that is still running after asyncTimeout.
+type asyncTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e asyncTimeoutError) Error() string {
+	return fmt.Sprintf("the async test didn't finish in TC39_ASYNC_TIMEOUT (%s)", e.timeout)
+}
+
+// runJobs drains the job queue of the test, interrupting an async one once asyncTimeout passed
+// since it started. The error of an interrupted test is only the timeout, as the stack it was
+// interrupted at differs between the runs.
+func runJobs(meta *tc39Meta, vm *goja.Runtime, jobs *tc39Jobs, run func() error) error {
+	if meta.hasFlag("async") {
+		timeout := asyncTimeoutError{asyncTimeout}
+		defer interruptAfter(vm, asyncTimeout, timeout)()
+		err := run()
+		if err == nil {
+			err = jobs.drain()
+		}
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) && interrupted.Value() == timeout {
+			return timeout
+		}
+		return err
+	}
+	if err := run(); err != nil {
+		return err
+	}
+	return jobs.drain()
+}
+
+// asyncFailure returns why an async test, which printed lines, didn't complete: its $DONE got an
+// error, it never called it or called it more than once.
+func asyncFailure(lines []string) string {
+	var completed int
+	for _, line := range lines {
+		if strings.HasPrefix(line, tc39AsyncFailure) {
+			return "async test failed: " + strings.TrimPrefix(line, tc39AsyncFailure)
+		}
+		if line == tc39AsyncComplete {
+			completed++
+		}
+	}
+	switch completed {
+	case 0:
+		return "async test didn't call $DONE"
+	case 1:
+		return ""
+	default:
+		return fmt.Sprintf("async test called $DONE %d times", completed)
+	}
+}
+
+func TestAsyncTests(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	async := &tc39Meta{Flags: []string{"async"}}
+	tb := &tc39CountingTB{TB: t}
+	run := func(name, src string) TestResult {
+		ctx.runTC39Test(tb, name, src, async, false)
+		return ctx.results.resultsCopy()[name+"-strict:false"]
+	}
+
+	result := run("test/async-pass.js", `var log = [];
+Promise.resolve(1).then(function(v) {
+  log.push(v);
+  return Promise.resolve(2);
+}).then(function(v) {
+  log.push(v);
+  assert.sameValue(log.join(), "0,1,2", "the reactions run after the test");
+}).then($DONE, $DONE);
+log.push(0);`)
+	require.Equal(t, CategoryPass, result.Category, result.Message)
+
+	for name, c := range map[string]struct{ src, message string }{
+		"test/async-fail.js": {
+			`Promise.resolve().then(function() { assert.sameValue(1, 2); }).then($DONE, $DONE);`,
+			"test/async-fail.js: async test failed: Test262Error: Test262Error: Expected SameValue(«1», «2») to be true",
+		},
+		"test/async-thrown.js": {
+			`Promise.reject("boom").then($DONE, $DONE);`,
+			"test/async-thrown.js: async test failed: Test262Error: boom",
+		},
+		"test/async-never.js": {
+			`Promise.resolve().then(function() {});`,
+			"test/async-never.js: async test didn't call $DONE",
+		},
+		"test/async-twice.js": {
+			`$DONE(); Promise.resolve().then(function() { $DONE(); });`,
+			"test/async-twice.js: async test called $DONE 2 times",
+		},
+	} {
+		result = run(name, c.src)
+		require.Equal(t, CategoryNewFailure, result.Category, name)
+		require.Equal(t, c.message, result.Message, name)
+	}
+
+	defer func(old time.Duration) { asyncTimeout = old }(asyncTimeout)
+	asyncTimeout = 50 * time.Millisecond
+	result = run("test/async-forever.js", `function again() { Promise.resolve().then(again); }
+again();`)
+	require.Equal(t, CategoryTimeout, result.Category)
+	require.Equal(t, "test/async-forever.js: the async test didn't finish in TC39_ASYNC_TIMEOUT (50ms)", result.Message)
+	require.Equal(t, 5, tb.errors)
+}
+
+func TestUnhandledRejections(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	tb := &tc39CountingTB{TB: t}
+	run := func(name, src string) TestResult {
+		ctx.runTC39Test(tb, name, src, &tc39Meta{}, false)
+		return ctx.results.resultsCopy()[name+"-strict:false"]
+	}
+
+	// only handled after core-js reported it as unhandled
+	result := run("test/handled-late.js", `var p = Promise.reject(1);
+setImmediate(function() {
+  setImmediate(function() {
+    p.catch(function() {});
+  });
+});`)
+	require.Equal(t, CategoryPass, result.Category, result.Message)
+	require.Equal(t, CategoryPass, run("test/handled.js", `Promise.reject(1).catch(function() {});`).Category)
+
+	result = run("test/unhandled.js", `Promise.reject(new TypeError("nobody"));
+Promise.resolve().then(function() { throw 2; });`)
+	require.Equal(t, CategoryNewFailure, result.Category)
+	require.Equal(t, "test/unhandled.js: unhandled promise rejections: TypeError: nobody, 2", result.Message)
+	require.Equal(t, 1, tb.errors)
+
+	unhandledRejectionTests["test/unhandled.js"] = true
+	defer delete(unhandledRejectionTests, "test/unhandled.js")
+	require.Equal(t, CategoryPass, run("test/unhandled.js", `Promise.reject(1);`).Category)
+	require.Equal(t, 1, tb.errors)
+}
@@ -0,0 +1,200 @@
+package test262
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39BabelCache keeps what Babel transformed the sources goja couldn't parse to in TC39_CACHE_DIR,
+// so the next runs skip the transform, by far the slowest part of compiling. A source is keyed by
+// its hash, its name and the version of k6 bundling Babel, the transform depending on nothing else.
+type tc39BabelCache struct {
+	dir     string
+	version string
+
+	mu           sync.Mutex
+	hits, misses int
+	// writeErrors counts the transforms that couldn't be kept, which only makes the next run slower
+	writeErrors int
+}
+
+func babelCacheFromEnv() (*tc39BabelCache, error) {
+	dir := os.Getenv("TC39_CACHE_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	return newBabelCache(dir)
+}
+
+func newBabelCache(dir string) (*tc39BabelCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("TC39_CACHE_DIR can't be created: %w", err)
+	}
+	return &tc39BabelCache{dir: dir, version: k6Version()}, nil
+}
+
+// k6Version returns the version of the k6 module the runner is built with.
+func k6Version() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, m := range info.Deps {
+			if m.Path == "github.com/loadimpact/k6" {
+				if m.Replace != nil {
+					return m.Replace.Path + "@" + m.Replace.Version
+				}
+				return m.Version
+			}
+		}
+	}
+	return "unknown"
+}
+
+func (c *tc39BabelCache) file(name, src string) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\x00%s\x00%s", c.version, name, src)
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(c.dir, key[:2], key+".js")
+}
+
+// transform returns what Babel transforms src to, from the cache if it's there, and if it was.
+func (c *tc39BabelCache) transform(ctx *tc39TestCtx, src, name string) (string, bool, error) {
+	file := c.file(name, src)
+	if b, err := readFile(file); err == nil {
+		c.count(&c.hits)
+		return string(b), true, nil
+	}
+	c.count(&c.misses)
+	code, _, err := ctx.compiler.Transform(src, name)
+	if err != nil {
+		return code, false, err
+	}
+	if err = writeAtomically(file, []byte(code)); err != nil {
+		c.count(&c.writeErrors)
+	}
+	return code, false, nil
+}
+
+func (c *tc39BabelCache) count(n *int) {
+	c.mu.Lock()
+	*n++
+	c.mu.Unlock()
+}
+
+func (c *tc39BabelCache) summary() string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	line := fmt.Sprintf("babel cache (TC39_CACHE_DIR %s): %d hits, %d misses", c.dir, c.hits, c.misses)
+	if c.writeErrors > 0 {
+		line += fmt.Sprintf(", %d transforms couldn't be written", c.writeErrors)
+	}
+	return line
+}
+
+// writeAtomically writes file through a temporary file next to it, so the runs writing the same
+// file at once, or reading it meanwhile, never see a partial one.
+func writeAtomically(file string, b []byte) error {
+	release := openFiles.acquire()
+	defer release()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err = tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// compileJS compiles like the k6 compiler does in the extended compatibility mode, Babel only
+// transforming what goja can't parse by itself, but with the transforms cached if there's a cache.
+// It returns the code it compiled.
+func (ctx *tc39TestCtx) compileJS(src, name, pre, post string, strict bool) (*goja.Program, string, error) {
+	return ctx.compileJSWith(src, name, pre, post, strict, nil)
+}
+
+// compileJSWith is compileJS recording whether the transform was in the cache in use, unless it's
+// nil. Nothing is recorded without a cache or for a source goja parses by itself.
+func (ctx *tc39TestCtx) compileJSWith(
+	src, name, pre, post string, strict bool, use *tc39CacheUse,
+) (*goja.Program, string, error) {
+	if ctx.babelCache == nil {
+		return ctx.compiler.Compile(src, name, pre, post, strict, lib.CompatibilityModeExtended)
+	}
+	if _, err := parser.ParseFile(nil, name, pre+src+post, 0); err == nil {
+		return ctx.compiler.Compile(src, name, pre, post, strict, lib.CompatibilityModeBase)
+	}
+	code, hit, err := ctx.babelCache.transform(ctx, src, name)
+	if use != nil {
+		use.record(hit)
+	}
+	if err != nil {
+		return nil, code, err
+	}
+	return ctx.compiler.Compile(code, name, pre, post, strict, lib.CompatibilityModeBase)
+}
+
+func TestBabelCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-babel-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	// every run has a cache of its own on the same directory
+	run := func() *tc39BabelCache {
+		ctx := newFixtureCtx(t)
+		ctx.babelCache, err = newBabelCache(dir)
+		require.NoError(t, err)
+		t.Run("test/class.js", func(t *testing.T) {
+			ctx.runTC39File("test/class.js", "test/class.js", t)
+		})
+		for _, strict := range []bool{false, true} {
+			result := ctx.results.resultsCopy()[variantKey("test/class.js", strict)]
+			require.Equal(t, CategoryPass, result.Category, result.Message)
+		}
+		return ctx.babelCache
+	}
+	first := run()
+	require.Equal(t, 1, first.hits, "the strict and the sloppy variant have the same source")
+	require.Equal(t, 1, first.misses)
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.js"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	second := run()
+	require.Equal(t, 2, second.hits)
+	require.Equal(t, 0, second.misses)
+	require.Equal(t, fmt.Sprintf("babel cache (TC39_CACHE_DIR %s): 2 hits, 0 misses", dir), second.summary())
+
+	// what goja parses by itself doesn't go through Babel, and what Babel rejects isn't kept
+	ctx := newFixtureCtx(t)
+	ctx.babelCache = second
+	_, _, err = ctx.compileJS("var a = 1;", "test/plain.js", "", "", false)
+	require.NoError(t, err)
+	_, _, err = ctx.compileJS("var 1;", "test/broken.js", "", "", false)
+	require.Error(t, err)
+	require.Equal(t, 1, second.misses)
+	files, err = filepath.Glob(filepath.Join(dir, "*", "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1, "no temporary files are left either")
+}
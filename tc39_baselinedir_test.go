@@ -0,0 +1,311 @@
+package test262
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// baselineFiles returns the files with the expected errors: the *.json files of dir, sorted, one
+// for every top directory of the tests, and after them the legacy single file as long as it exists.
+// Either can be empty, for TC39_EXPECTED_ERRORS naming only one of them. Without any of them there
+// are no files, and no expected errors, which TestTC39 warns about.
+func baselineFiles(legacy, dir string) ([]string, error) {
+	var files []string
+	if dir != "" {
+		var err error
+		if files, err = filepath.Glob(filepath.Join(dir, "*.json")); err != nil {
+			return nil, err
+		}
+	}
+	if legacy == "" {
+		return files, nil
+	}
+	if _, err := os.Stat(legacy); err == nil {
+		files = append(files, legacy)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// baselinePathsFromEnv returns where the expected errors are read from and written to, the legacy
+// file and the directory next to the tests unless TC39_EXPECTED_ERRORS names another place: a
+// *.json file is the single file, anything else a directory with a file for every area.
+func baselinePathsFromEnv() (legacy, dir string) {
+	v := os.Getenv("TC39_EXPECTED_ERRORS")
+	switch {
+	case v == "":
+		return tc39Baseline, tc39BaselineDir
+	case strings.HasSuffix(v, ".json"):
+		return v, ""
+	default:
+		return "", v
+	}
+}
+
+// baselinePath is the name the expected errors of the run are known by, their directory unless
+// they're in a single file.
+func (ctx *tc39TestCtx) baselinePath() string {
+	if ctx.baselineDir != "" {
+		return ctx.baselineDir
+	}
+	return ctx.baselineLegacy
+}
+
+// missingBaseline is the warning of a run without any expected errors, for which every failure is
+// a new one.
+func missingBaseline(legacy, dir string) string {
+	var where []string
+	for _, name := range []string{dir, legacy} {
+		if name != "" {
+			where = append(where, name)
+		}
+	}
+	return fmt.Sprintf("there are no expected errors in %s, every failing test fails the run (TC39_EXPECTED_ERRORS)",
+		strings.Join(where, " nor in "))
+}
+
+// readBaseline merges the expected errors of the files. A variant can only be in one of them, it
+// wouldn't be clear which of its errors is expected otherwise.
+func readBaseline(files []string) (map[string]tc39ExpectedEntry, error) {
+	all := make(map[string]tc39ExpectedEntry, 2000)
+	from := make(map[string]string, 2000)
+	for _, name := range files {
+		b, err := ioutil.ReadFile(name) //nolint:gosec
+		if err != nil {
+			return nil, err
+		}
+		entries, err := readExpectedEntries(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for nameKey, e := range entries {
+			if other, ok := from[nameKey]; ok {
+				return nil, fmt.Errorf("%s is expected both in %s and in %s", nameKey, other, name)
+			}
+			all[nameKey], from[nameKey] = e, name
+		}
+	}
+	return all, nil
+}
+
+// baselineArea returns the name of the file in the directory with the expected error of the
+// variant, the one of the top directory of its test below test/, or of its extra suite.
+func baselineArea(nameKey string) string {
+	name := variantName(nameKey)
+	parts := strings.Split(name, "/")
+	if len(parts) > 2 && parts[0] == "test" {
+		return parts[1] + ".json"
+	}
+	return parts[0] + ".json"
+}
+
+// writeBaselineDir writes the expected errors to dir, each to the file of its area, and removes
+// the files of the areas without any left.
+func writeBaselineDir(dir string, entries map[string]tc39ExpectedEntry) error {
+	areas := make(map[string]map[string]tc39ExpectedEntry)
+	for nameKey, e := range entries {
+		area := baselineArea(nameKey)
+		if areas[area] == nil {
+			areas[area] = make(map[string]tc39ExpectedEntry)
+		}
+		areas[area][nameKey] = e
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for area, areaEntries := range areas {
+		if err := writeExpectedEntries(filepath.Join(dir, area), areaEntries); err != nil {
+			return err
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if areas[filepath.Base(file)] == nil {
+			if err = os.Remove(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeBaselineFiles writes the expected errors back where they're kept: to the legacy single file
+// if it's still the only one, to dir otherwise, removing the legacy file whose errors are now there.
+func writeBaselineFiles(legacy, dir string, entries map[string]tc39ExpectedEntry) error {
+	if dir == "" {
+		return writeExpectedEntries(legacy, entries)
+	}
+	_, legacyErr := os.Stat(legacy)
+	if _, err := os.Stat(dir); os.IsNotExist(err) && legacyErr == nil {
+		return writeExpectedEntries(legacy, entries)
+	}
+	if err := writeBaselineDir(dir, entries); err != nil {
+		return err
+	}
+	if legacyErr == nil {
+		return os.Remove(legacy)
+	}
+	return nil
+}
+
+// checkWritableBaseline checks that the files of the expected errors can be written, and the
+// legacy one removed.
+func checkWritableBaseline(legacy, dir string) error {
+	if _, err := os.Stat(legacy); err == nil {
+		if err = checkWritableFile(legacy); err != nil {
+			return err
+		}
+		if err = checkWritableDir(filepath.Dir(legacy)); err != nil {
+			return err
+		}
+	}
+	if dir == "" {
+		return checkWritableDir(filepath.Dir(legacy))
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return checkWritableDir(filepath.Dir(dir))
+	}
+	return checkWritableDir(dir)
+}
+
+// TestTC39SplitBaseline moves the expected errors of the legacy single file to the directory with a
+// file for every area, given TC39_SPLIT_BASELINE=1, once for a checkout from before the directory.
+func TestTC39SplitBaseline(t *testing.T) {
+	if os.Getenv("TC39_SPLIT_BASELINE") == "" {
+		t.Skip("set TC39_SPLIT_BASELINE=1 to split " + tc39Baseline + " into " + tc39BaselineDir)
+	}
+	files, err := baselineFiles(tc39Baseline, tc39BaselineDir)
+	require.NoError(t, err)
+	entries, err := readBaseline(files)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(tc39BaselineDir, 0o755))
+	require.NoError(t, writeBaselineFiles(tc39Baseline, tc39BaselineDir, entries))
+}
+
+func TestBaselineDir(t *testing.T) {
+	require.Equal(t, "built-ins.json", baselineArea("test/built-ins/Array/a.js-strict:false"))
+	require.Equal(t, "annexB.json", baselineArea("test/annexB/built-ins/b.js-strict:true-mode:base"))
+	require.Equal(t, "own.json", baselineArea("own/c.js-strict:true"))
+	require.Equal(t, "test.json", baselineArea("test/d.js-strict:true"))
+
+	dir, err := ioutil.TempDir("", "tc39-baseline")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	legacy, areas := filepath.Join(dir, tc39Baseline), filepath.Join(dir, tc39BaselineDir)
+	// without either there are no expected errors
+	files, err := baselineFiles(legacy, areas)
+	require.NoError(t, err)
+	require.Empty(t, files)
+	entries, err := readBaseline(files)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// a checkout with only the legacy file keeps it
+	errs := map[string]string{
+		"test/built-ins/a.js-strict:false": "test/built-ins/a.js: a",
+		"test/language/b.js-strict:true":   "test/language/b.js: b",
+	}
+	require.NoError(t, writeExpectedErrors(legacy, errs))
+	require.NoError(t, checkWritableBaseline(legacy, areas))
+	files, err = baselineFiles(legacy, areas)
+	require.NoError(t, err)
+	require.Equal(t, []string{legacy}, files)
+	require.NoError(t, writeBaselineFiles(legacy, areas, entriesOf(errs)))
+	_, err = os.Stat(areas)
+	require.True(t, os.IsNotExist(err))
+
+	// once the directory exists both are read, a variant in both of them is an error
+	require.NoError(t, os.Mkdir(areas, 0o755))
+	require.NoError(t, writeExpectedErrors(filepath.Join(areas, "annexB.json"), map[string]string{
+		"test/annexB/c.js-strict:false": "test/annexB/c.js: c",
+	}))
+	files, err = baselineFiles(legacy, areas)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(areas, "annexB.json"), legacy}, files)
+	merged, err := readBaseline(files)
+	require.NoError(t, err)
+	require.Len(t, merged, 3)
+	require.NoError(t, writeExpectedErrors(filepath.Join(areas, "language.json"), map[string]string{
+		"test/language/b.js-strict:true": "test/language/b.js: other",
+	}))
+	files, err = baselineFiles(legacy, areas)
+	require.NoError(t, err)
+	_, err = readBaseline(files)
+	require.EqualError(t, err, fmt.Sprintf("test/language/b.js-strict:true is expected both in %s and in %s",
+		filepath.Join(areas, "language.json"), legacy))
+
+	// and writing them moves the errors of the legacy file to the files of their areas, removing
+	// the ones of the areas without errors left
+	delete(merged, "test/annexB/c.js-strict:false")
+	merged["own/d.js-strict:true"] = tc39ExpectedEntry{Error: "own/d.js: d", Category: kindRuntime, Since: "2020-01-02"}
+	require.NoError(t, writeBaselineFiles(legacy, areas, merged))
+	_, err = os.Stat(legacy)
+	require.True(t, os.IsNotExist(err))
+	files, err = baselineFiles(legacy, areas)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(areas, "built-ins.json"), filepath.Join(areas, "language.json"), filepath.Join(areas, "own.json"),
+	}, files)
+	read, err := readBaseline(files)
+	require.NoError(t, err)
+	require.Equal(t, merged, read)
+	b, err := ioutil.ReadFile(filepath.Join(areas, "language.json")) //nolint:gosec
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"test/language/b.js-strict:true\": \"test/language/b.js: b\"\n}\n", string(b))
+}
+
+func TestMissingBaseline(t *testing.T) {
+	for v, want := range map[string][2]string{
+		"":                       {tc39Baseline, tc39BaselineDir},
+		"../errors.json":         {"../errors.json", ""},
+		"../breaking_errors_dir": {"", "../breaking_errors_dir"},
+	} {
+		require.NoError(t, os.Setenv("TC39_EXPECTED_ERRORS", v))
+		legacy, dir := baselinePathsFromEnv()
+		require.Equal(t, want, [2]string{legacy, dir}, v)
+	}
+	require.NoError(t, os.Unsetenv("TC39_EXPECTED_ERRORS"))
+
+	dir, err := ioutil.TempDir("", "tc39-baseline")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, "errors.json")
+	newCtx := func() *tc39TestCtx {
+		ctx := newFixtureCtx(t)
+		ctx.baselineLegacy, ctx.baselineDir = file, ""
+		return ctx
+	}
+
+	// a missing file has no expected errors, which is warned about
+	ctx := newCtx()
+	require.NoError(t, ctx.init())
+	require.Empty(t, ctx.expectedErrors)
+	require.Equal(t, "there are no expected errors in "+file+
+		", every failing test fails the run (TC39_EXPECTED_ERRORS)", ctx.baselineWarning)
+	require.Equal(t, "there are no expected errors in "+tc39BaselineDir+" nor in "+tc39Baseline+
+		", every failing test fails the run (TC39_EXPECTED_ERRORS)", missingBaseline(tc39Baseline, tc39BaselineDir))
+
+	// an empty one neither, but that's what it says
+	require.NoError(t, ioutil.WriteFile(file, []byte("\n"), 0o644))
+	ctx = newCtx()
+	require.NoError(t, ctx.init())
+	require.Empty(t, ctx.expectedErrors)
+	require.Empty(t, ctx.baselineWarning)
+
+	// a malformed one is an error telling where it is
+	require.NoError(t, ioutil.WriteFile(file, []byte("{\n  \"test/a.js-strict:false\": \"a\",\n  \"test/b.js-strict:true\" \"b\"\n}\n"), 0o644))
+	require.EqualError(t, newCtx().init(), file+`: invalid JSON at byte 62, near "\",\n  \"test/b.js-strict:true\" \"b\"\n}\n": `+
+		`invalid character '"' after object key`)
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{"test/a.js-strict:false": 3}`), 0o644))
+	require.EqualError(t, newCtx().init(), file+": an expected error is either a string or an object, not 3")
+}
@@ -0,0 +1,598 @@
+package test262
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+type tc39BenchmarkItem struct {
+	name     string
+	features []string
+	variants []tc39BenchmarkVariant
+}
+
+// total is the sum of the medians of all variants.
+func (item tc39BenchmarkItem) total() (total time.Duration) {
+	for _, v := range item.variants {
+		total += v.median
+	}
+	return total
+}
+
+// tc39BenchmarkVariant holds the measurements of either the strict or the sloppy run of a test.
+// min, median and max only cover running the test body, harness and compile are kept separately.
+type tc39BenchmarkVariant struct {
+	strict           bool
+	samples          int
+	min, median, max time.Duration
+	harness, compile time.Duration
+
+	// peakHeap is the highest HeapAlloc sampled while the variant ran, when more than one test
+	// runs at a time it can't be blamed on this one and is marked as unattributed.
+	peakHeap             uint64
+	peakHeapUnattributed bool
+
+	// harnessCache is the state of the harness program cache during the first iteration, the rest
+	// are always warm. transformCache is the state of the Babel cache of TC39_CACHE_DIR for the
+	// test body then, none if it wasn't looked up.
+	harnessCache, transformCache string
+}
+
+// tc39Timings splits a single run of a test into its phases.
+type tc39Timings struct {
+	harness, compile, run time.Duration
+
+	harnessCache, transformCache tc39CacheUse
+}
+
+// tc39CacheUse counts how many lookups of a cache were hits while running a test.
+type tc39CacheUse struct {
+	hits, misses int
+}
+
+func (c *tc39CacheUse) record(hit bool) {
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+// state tells if a test ran with a warm (only hits) or cold (only misses) cache, or a mix of both.
+func (c tc39CacheUse) state() string {
+	switch {
+	case c.misses == 0:
+		return "warm"
+	case c.hits == 0:
+		return "cold"
+	default:
+		return "mixed"
+	}
+}
+
+type tc39BenchmarkData []tc39BenchmarkItem
+
+type tc39TestPrgKey struct {
+	name   string
+	strict bool
+}
+
+// tc39CompiledTest is a test body compiled once for all its measurements, with the code compiled.
+type tc39CompiledTest struct {
+	prg  *goja.Program
+	code string
+}
+
+func newBenchmarkVariant(strict bool, samples []tc39Timings) tc39BenchmarkVariant {
+	v := tc39BenchmarkVariant{strict: strict, samples: len(samples)}
+	if len(samples) == 0 {
+		return v
+	}
+	// the test body is compiled only by the first iteration
+	v.compile = samples[0].compile
+	v.harnessCache = samples[0].harnessCache.state()
+	v.transformCache = "none"
+	if use := samples[0].transformCache; use.hits+use.misses > 0 {
+		v.transformCache = use.state()
+	}
+
+	runs := make([]time.Duration, len(samples))
+	harness := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		runs[i], harness[i] = sample.run, sample.harness
+	}
+	v.min, v.median, v.max = durationStats(runs)
+	_, v.harness, _ = durationStats(harness)
+	return v
+}
+
+func durationStats(samples []time.Duration) (min, median, max time.Duration) {
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	min, max = samples[0], samples[len(samples)-1]
+	if mid := len(samples) / 2; len(samples)%2 == 0 {
+		median = (samples[mid-1] + samples[mid]) / 2
+	} else {
+		median = samples[mid]
+	}
+	return min, median, max
+}
+
+func (v tc39BenchmarkVariant) spread() time.Duration {
+	return v.max - v.min
+}
+
+func (v tc39BenchmarkVariant) peakHeapString() string {
+	switch {
+	case v.peakHeap == 0:
+		return "-"
+	case v.peakHeapUnattributed:
+		return fmt.Sprintf("(%.1f)", float64(v.peakHeap)/(1<<20))
+	default:
+		return fmt.Sprintf("%.1f", float64(v.peakHeap)/(1<<20))
+	}
+}
+
+func (v tc39BenchmarkVariant) mode() string {
+	if v.strict {
+		return "strict"
+	}
+	return "sloppy"
+}
+
+// runFilterActive reports whether `go test -run` selects a subset of the tc39 subtests.
+func runFilterActive() bool {
+	f := flag.Lookup("test.run")
+	return f != nil && strings.Contains(f.Value.String(), "/")
+}
+
+func (ctx *tc39TestCtx) initBench() error {
+	// bench only mode runs the tests purely for their timings, without checking their results
+	ctx.benchOnly = os.Getenv("TC39_BENCH_ONLY") != ""
+	ctx.enableBench = ctx.benchOnly || os.Getenv("TC39_BENCH") != ""
+	ctx.benchPhases = os.Getenv("TC39_BENCH_PHASES") != ""
+	ctx.benchOut = os.Getenv("TC39_BENCH_OUT")
+	ctx.benchDirDepth = 2
+	if v := os.Getenv("TC39_BENCH_DIR_DEPTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("TC39_BENCH_DIR_DEPTH must be a positive integer, got %q", v)
+		}
+		ctx.benchDirDepth = n
+	}
+	ctx.benchIterations = 1
+	if v := os.Getenv("TC39_BENCH_ITERATIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("TC39_BENCH_ITERATIONS must be a positive integer, got %q", v)
+		}
+		ctx.benchIterations = n
+	}
+	if ctx.benchIterations > 1 && !ctx.enableBench {
+		return errors.New("TC39_BENCH_ITERATIONS requires bench mode (TC39_BENCH=1)")
+	}
+	if ctx.enableBench {
+		// the timings of each run are collected by a hook
+		ctx.benchHook = newBenchHook()
+		ctx.opts.Hooks = append(ctx.opts.Hooks, ctx.benchHook)
+	}
+	return nil
+}
+
+// subset reports whether the run only covers some of the tests, selected with -run, TC39_SHARD,
+// TC39_FILTER, TC39_META_FILTER or TC39_ONLY_FAILING.
+func (ctx *tc39TestCtx) subset() bool {
+	return runFilterActive() || ctx.shard != nil || ctx.filter != nil || ctx.metaFilter != nil || ctx.onlyFailing != nil
+}
+
+// checkBenchIterations refuses to measure every test TC39_BENCH_ITERATIONS times, it has to be
+// called once the tests are selected, single being whether TC39_TEST selected one.
+func (ctx *tc39TestCtx) checkBenchIterations(single bool) error {
+	if ctx.benchIterations > 1 && !single && !ctx.subset() {
+		return errors.New("TC39_BENCH_ITERATIONS multiplies the run time, select a subset of tests with -run, " +
+			"TC39_FILTER, TC39_META_FILTER, TC39_SHARD, TC39_ONLY_FAILING or TC39_TEST")
+	}
+	return nil
+}
+
+// benchTC39Test runs one variant of a test benchIterations times, each on a fresh runtime.
+func (ctx *tc39TestCtx) benchTC39Test(
+	t testing.TB, name, src string, meta *tc39Meta, strict bool,
+) tc39BenchmarkVariant {
+	key := tc39TestPrgKey{name: name, strict: strict}
+	ctx.benchHook.start(key)
+	startTime := time.Now()
+	for i := 0; i < ctx.benchIterations; i++ {
+		ctx.runTC39Test(t, name, src, meta, strict)
+		if t.Failed() {
+			// repeating an unexpected failure only adds noise to the output
+			break
+		}
+	}
+	v := newBenchmarkVariant(strict, ctx.benchHook.take(key))
+	if ctx.memSampler != nil {
+		v.peakHeap = ctx.memSampler.peak(startTime, time.Now())
+		v.peakHeapUnattributed = tc39Workers() > 1
+	}
+	return v
+}
+
+// compileTest compiles the test body in the mode, in strict mode for the strict variant, caching it
+// while a test is measured multiple times so compilation is only paid (and measured) once. The base
+// mode compiles it with goja alone, as k6 does, and is never measured. It returns the code it
+// compiled as compileJS does, recording the use of the transform cache in use as compileJSWith.
+func (ctx *tc39TestCtx) compileTest(
+	name, src string, strict bool, mode lib.CompatibilityMode, use *tc39CacheUse,
+) (*goja.Program, string, error) {
+	if mode == lib.CompatibilityModeBase {
+		return ctx.compiler.Compile(src, name, "", "", strict, lib.CompatibilityModeBase)
+	}
+	if ctx.benchIterations <= 1 {
+		return ctx.compileJSWith(src, name, "", "", strict, use)
+	}
+
+	key := tc39TestPrgKey{name: name, strict: strict}
+	ctx.prgCacheLock.Lock()
+	defer ctx.prgCacheLock.Unlock()
+	if c, ok := ctx.testPrgCache[key]; ok {
+		return c.prg, c.code, nil
+	}
+	p, code, err := ctx.compileJSWith(src, name, "", "", strict, use)
+	if err != nil {
+		return nil, code, err
+	}
+	ctx.testPrgCache[key] = tc39CompiledTest{prg: p, code: code}
+	return p, code, nil
+}
+
+func (ctx *tc39TestCtx) forgetTestPrograms(name string) {
+	if ctx.benchIterations <= 1 {
+		return
+	}
+	ctx.prgCacheLock.Lock()
+	delete(ctx.testPrgCache, tc39TestPrgKey{name: name, strict: false})
+	delete(ctx.testPrgCache, tc39TestPrgKey{name: name, strict: true})
+	ctx.prgCacheLock.Unlock()
+}
+
+type tc39BenchmarkRow struct {
+	name string
+	tc39BenchmarkVariant
+}
+
+func (ctx *tc39TestCtx) benchmarkRows() []tc39BenchmarkRow {
+	var rows []tc39BenchmarkRow
+	for _, item := range ctx.results.benchmarkItems() {
+		for _, v := range item.variants {
+			rows = append(rows, tc39BenchmarkRow{name: item.name, tc39BenchmarkVariant: v})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].median > rows[j].median
+	})
+	return rows
+}
+
+// printBenchmark writes the slowest limit test variants, sorted by their median duration,
+// followed by the durations of all tests aggregated by directory and feature.
+func (ctx *tc39TestCtx) printBenchmark(w io.Writer, limit int) {
+	rows := ctx.benchmarkRows()
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	writeBenchmarkTable(w, rows, ctx.benchPhases)
+
+	dirs, features := ctx.benchmarkAggregates()
+	fmt.Fprintln(w)
+	writeAggregateTable(w, "directory", dirs)
+	fmt.Fprintln(w)
+	writeAggregateTable(w, "feature", features)
+}
+
+// writeBenchmarkTable writes rows as tab separated values for humans, tools should use the
+// JSON report instead.
+func writeBenchmarkTable(w io.Writer, rows []tc39BenchmarkRow, phases bool) {
+	fmt.Fprintf(w, "name\tmode\tmedian(ms)\tmin(ms)\tmax(ms)\tspread(ms)\tsamples\tpeak heap(MB)")
+	if phases {
+		fmt.Fprintf(w, "\tharness(ms)\tcompile(ms)")
+	}
+	fmt.Fprintln(w)
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s", row.name, row.mode(),
+			ms(row.median), ms(row.min), ms(row.max), ms(row.spread()), row.samples, row.peakHeapString())
+		if phases {
+			fmt.Fprintf(w, "\t%s\t%s", ms(row.harness), ms(row.compile))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func ms(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// tc39BenchmarkAggregate sums up the medians of all tests in a directory or using a feature.
+type tc39BenchmarkAggregate struct {
+	Name  string        `json:"name"`
+	Tests int           `json:"tests"`
+	Total time.Duration `json:"total"`
+	Mean  time.Duration `json:"mean"`
+}
+
+// benchmarkDirectory returns the first depth directories of a test below test/, so that
+// test/built-ins/RegExp/property-escapes/foo.js becomes built-ins/RegExp with the default of 2.
+func benchmarkDirectory(name string, depth int) string {
+	parts := strings.Split(strings.TrimPrefix(name, "test/"), "/")
+	parts = parts[:len(parts)-1]
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	if len(parts) == 0 {
+		return "."
+	}
+	return strings.Join(parts, "/")
+}
+
+// benchmarkAggregates groups all the measured tests by directory and by feature, sorted by their
+// total duration.
+func (ctx *tc39TestCtx) benchmarkAggregates() (dirs, features []tc39BenchmarkAggregate) {
+	dirMap := make(map[string]*tc39BenchmarkAggregate)
+	featureMap := make(map[string]*tc39BenchmarkAggregate)
+	add := func(m map[string]*tc39BenchmarkAggregate, key string, d time.Duration) {
+		a := m[key]
+		if a == nil {
+			a = &tc39BenchmarkAggregate{Name: key}
+			m[key] = a
+		}
+		a.Tests++
+		a.Total += d
+	}
+	for _, item := range ctx.results.benchmarkItems() {
+		total := item.total()
+		add(dirMap, benchmarkDirectory(item.name, ctx.benchDirDepth), total)
+		for _, feature := range item.features {
+			add(featureMap, feature, total)
+		}
+	}
+	return sortAggregates(dirMap), sortAggregates(featureMap)
+}
+
+func sortAggregates(m map[string]*tc39BenchmarkAggregate) []tc39BenchmarkAggregate {
+	res := make([]tc39BenchmarkAggregate, 0, len(m))
+	for _, a := range m {
+		a.Mean = a.Total / time.Duration(a.Tests)
+		res = append(res, *a)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Total != res[j].Total {
+			return res[i].Total > res[j].Total
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func writeAggregateTable(w io.Writer, title string, aggregates []tc39BenchmarkAggregate) {
+	fmt.Fprintf(w, "%s\ttests\ttotal(ms)\tmean(ms)\n", title)
+	for _, a := range aggregates {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", a.Name, a.Tests, ms(a.Total), ms(a.Mean))
+	}
+}
+
+func TestDurationStats(t *testing.T) {
+	for _, c := range []struct {
+		samples          []time.Duration
+		min, median, max time.Duration
+	}{
+		{samples: []time.Duration{5}, min: 5, median: 5, max: 5},
+		{samples: []time.Duration{3, 1, 2}, min: 1, median: 2, max: 3},
+		// with an even number of samples the median is the mean of the middle ones
+		{samples: []time.Duration{4, 1, 3, 2}, min: 1, median: 2, max: 4},
+		{samples: []time.Duration{7, 8}, min: 7, median: 7, max: 8},
+		{samples: []time.Duration{9, 1, 1, 9, 1}, min: 1, median: 1, max: 9},
+	} {
+		min, median, max := durationStats(c.samples)
+		require.Equal(t, []time.Duration{c.min, c.median, c.max}, []time.Duration{min, median, max}, c.samples)
+	}
+}
+
+func TestCacheUse(t *testing.T) {
+	var c tc39CacheUse
+	// a test without includes has nothing to look up
+	require.Equal(t, "warm", c.state())
+	c.record(true)
+	c.record(true)
+	require.Equal(t, tc39CacheUse{hits: 2}, c)
+	require.Equal(t, "warm", c.state())
+	c.record(false)
+	require.Equal(t, "mixed", c.state())
+	require.Equal(t, "cold", tc39CacheUse{misses: 3}.state())
+}
+
+func TestBenchmarkVariant(t *testing.T) {
+	v := newBenchmarkVariant(true, []tc39Timings{
+		{harness: 10, compile: 100, run: 30, harnessCache: tc39CacheUse{misses: 1}, transformCache: tc39CacheUse{hits: 1}},
+		{harness: 2, compile: 5, run: 10, harnessCache: tc39CacheUse{hits: 1}},
+		{harness: 4, run: 20, harnessCache: tc39CacheUse{hits: 1}},
+	})
+	require.Equal(t, tc39BenchmarkVariant{
+		strict: true, samples: 3, min: 10, median: 20, max: 30, harness: 4, compile: 100,
+		harnessCache: "cold", transformCache: "warm",
+	}, v)
+	require.Equal(t, time.Duration(20), v.spread())
+	require.Equal(t, "strict", v.mode())
+
+	// a test that failed before its first measurement
+	require.Equal(t, tc39BenchmarkVariant{samples: 0}, newBenchmarkVariant(false, nil))
+}
+
+func TestInitBench(t *testing.T) {
+	vars := []string{"TC39_BENCH", "TC39_BENCH_ONLY", "TC39_BENCH_DIR_DEPTH", "TC39_BENCH_ITERATIONS"}
+	initBench := func(env map[string]string) (*tc39TestCtx, error) {
+		for _, key := range vars {
+			require.NoError(t, os.Unsetenv(key))
+		}
+		for key, v := range env {
+			require.NoError(t, os.Setenv(key, v))
+		}
+		ctx := &tc39TestCtx{}
+		err := ctx.initBench()
+		return ctx, err
+	}
+	defer func() {
+		for _, key := range vars {
+			require.NoError(t, os.Unsetenv(key))
+		}
+	}()
+
+	ctx, err := initBench(nil)
+	require.NoError(t, err)
+	require.False(t, ctx.enableBench)
+	require.Equal(t, 1, ctx.benchIterations)
+	require.Equal(t, 2, ctx.benchDirDepth)
+	require.Empty(t, ctx.opts.Hooks)
+
+	ctx, err = initBench(map[string]string{"TC39_BENCH_ONLY": "1", "TC39_BENCH_ITERATIONS": "3", "TC39_BENCH_DIR_DEPTH": "1"})
+	require.NoError(t, err)
+	require.True(t, ctx.enableBench)
+	require.Equal(t, 3, ctx.benchIterations)
+	require.Equal(t, 1, ctx.benchDirDepth)
+	require.Equal(t, []TestHook{ctx.benchHook}, ctx.opts.Hooks)
+
+	for _, v := range []string{"0", "-1", "a", "1.5", " 2"} {
+		_, err = initBench(map[string]string{"TC39_BENCH": "1", "TC39_BENCH_ITERATIONS": v})
+		require.EqualError(t, err, fmt.Sprintf("TC39_BENCH_ITERATIONS must be a positive integer, got %q", v))
+		_, err = initBench(map[string]string{"TC39_BENCH": "1", "TC39_BENCH_DIR_DEPTH": v})
+		require.EqualError(t, err, fmt.Sprintf("TC39_BENCH_DIR_DEPTH must be a positive integer, got %q", v))
+	}
+	_, err = initBench(map[string]string{"TC39_BENCH_ITERATIONS": "2"})
+	require.EqualError(t, err, "TC39_BENCH_ITERATIONS requires bench mode (TC39_BENCH=1)")
+	// a single iteration is the default, with or without bench mode
+	_, err = initBench(map[string]string{"TC39_BENCH_ITERATIONS": "1"})
+	require.NoError(t, err)
+
+	// more than one iteration only over a subset of the tests
+	ctx, err = initBench(map[string]string{"TC39_BENCH": "1", "TC39_BENCH_ITERATIONS": "2"})
+	require.NoError(t, err)
+	require.Error(t, ctx.checkBenchIterations(false))
+	require.NoError(t, ctx.checkBenchIterations(true))
+	ctx.filter = &tc39Filter{}
+	require.NoError(t, ctx.checkBenchIterations(false))
+	ctx.filter, ctx.metaFilter = nil, &tc39MetaFilter{}
+	require.NoError(t, ctx.checkBenchIterations(false))
+	ctx.metaFilter, ctx.shard = nil, &tc39Shard{index: 1, count: 2}
+	require.NoError(t, ctx.checkBenchIterations(false))
+	ctx.shard, ctx.benchIterations = nil, 1
+	require.NoError(t, ctx.checkBenchIterations(false))
+}
+
+func TestBenchmarkAggregates(t *testing.T) {
+	ctx := &tc39TestCtx{results: &tc39Results{}, benchDirDepth: 2}
+	item := func(name string, features []string, medians ...time.Duration) {
+		var variants []tc39BenchmarkVariant
+		for i, median := range medians {
+			variants = append(variants, tc39BenchmarkVariant{strict: i == 0, samples: 1, min: median, median: median, max: median})
+		}
+		ctx.results.addBenchmark(tc39BenchmarkItem{name: name, features: features, variants: variants})
+	}
+	item("test/built-ins/RegExp/property-escapes/a.js", []string{"regexp-unicode-property-escapes"}, 3*time.Millisecond, time.Millisecond)
+	item("test/built-ins/RegExp/b.js", []string{"regexp-unicode-property-escapes", "Symbol"}, 2*time.Millisecond)
+	item("test/built-ins/Array/c.js", nil, 5*time.Millisecond)
+	item("test/language/d.js", []string{"Symbol"}, time.Millisecond)
+	item("test/e.js", nil, time.Millisecond)
+
+	dirs, features := ctx.benchmarkAggregates()
+	require.Equal(t, []tc39BenchmarkAggregate{
+		{Name: "built-ins/RegExp", Tests: 2, Total: 6 * time.Millisecond, Mean: 3 * time.Millisecond},
+		{Name: "built-ins/Array", Tests: 1, Total: 5 * time.Millisecond, Mean: 5 * time.Millisecond},
+		// the same total sorts by name
+		{Name: ".", Tests: 1, Total: time.Millisecond, Mean: time.Millisecond},
+		{Name: "language", Tests: 1, Total: time.Millisecond, Mean: time.Millisecond},
+	}, dirs)
+	require.Equal(t, []tc39BenchmarkAggregate{
+		{Name: "regexp-unicode-property-escapes", Tests: 2, Total: 6 * time.Millisecond, Mean: 3 * time.Millisecond},
+		{Name: "Symbol", Tests: 2, Total: 3 * time.Millisecond, Mean: 1500 * time.Microsecond},
+	}, features)
+
+	ctx.benchDirDepth = 1
+	dirs, _ = ctx.benchmarkAggregates()
+	require.Equal(t, tc39BenchmarkAggregate{Name: "built-ins", Tests: 3, Total: 11 * time.Millisecond, Mean: 11 * time.Millisecond / 3}, dirs[0])
+	require.Equal(t, "built-ins/RegExp/property-escapes", benchmarkDirectory("test/built-ins/RegExp/property-escapes/a.js", 3))
+	require.Equal(t, "built-ins/RegExp/property-escapes", benchmarkDirectory("test/built-ins/RegExp/property-escapes/a.js", 5))
+
+	// the aggregates are over all the tests, not only the slowest ones printed
+	var out strings.Builder
+	ctx.benchDirDepth = 2
+	ctx.printBenchmark(&out, 1)
+	require.Equal(t, `name	mode	median(ms)	min(ms)	max(ms)	spread(ms)	samples	peak heap(MB)
+test/built-ins/Array/c.js	strict	5.000	5.000	5.000	0.000	1	-
+
+directory	tests	total(ms)	mean(ms)
+built-ins/RegExp	2	6.000	3.000
+built-ins/Array	1	5.000	5.000
+.	1	1.000	1.000
+language	1	1.000	1.000
+
+feature	tests	total(ms)	mean(ms)
+regexp-unicode-property-escapes	2	6.000	3.000
+Symbol	2	3.000	1.500
+`, out.String())
+}
+
+func TestBenchOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-benchonly")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	var out bytes.Buffer
+	ctx := newFixtureCtx(t)
+	ctx.opts.Out = &out
+	ctx.artifacts = newArtifacts(dir)
+	require.NoError(t, os.Setenv("TC39_BENCH_ONLY", "1"))
+	err = ctx.initBench()
+	require.NoError(t, os.Unsetenv("TC39_BENCH_ONLY"))
+	require.NoError(t, err)
+	require.True(t, ctx.benchOnly)
+	require.True(t, ctx.enableBench)
+
+	// the failures are counted, but neither compared with the expected errors nor asserted
+	tb := &tc39CountingTB{TB: t}
+	t.Run("tc39", func(t *testing.T) {
+		for _, name := range []string{"test/compat/es5.js", "test/dump/fail.js"} {
+			name := name
+			t.Run(name, func(t *testing.T) {
+				ctx.runTC39File(name, name, tb)
+			})
+		}
+	})
+	require.Equal(t, 0, tb.errors)
+	require.Equal(t, 2, ctx.results.benchOnlyFailureCount())
+	require.Len(t, ctx.results.benchmarkItems(), 2)
+
+	ctx.report(t, false)
+	report := out.String()
+	require.Contains(t, report, "BENCH-ONLY RUN, correctness was not checked: 2 test variants failed\n")
+	require.NotContains(t, report, "stale")
+
+	// its results can't become the expected errors
+	ctx.updateExpected = true
+	require.EqualError(t, ctx.checkUpdateExpected(), "TC39_UPDATE_EXPECTED needs the results of all tests, "+
+		"so it can't be combined with TC39_BENCH_ONLY, -run, TC39_SHARD, TC39_FILTER or TC39_META_FILTER")
+	ctx.benchOnly = false
+	require.NoError(t, ctx.checkUpdateExpected())
+	ctx.filter = &tc39Filter{}
+	require.Error(t, ctx.checkUpdateExpected())
+}
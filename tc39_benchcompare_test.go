@@ -0,0 +1,288 @@
+package test262
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTC39BenchCompare compares two reports written through TC39_BENCH_OUT, given as
+// TC39_BENCH_COMPARE=old.json,new.json. With TC39_BENCH_MAX_SLOWDOWN=X it fails for every test
+// that got significantly slower by more than X percent.
+func TestTC39BenchCompare(t *testing.T) {
+	files := strings.Split(os.Getenv("TC39_BENCH_COMPARE"), ",")
+	if len(files) != 2 {
+		t.Skip("set TC39_BENCH_COMPARE=old.json,new.json to compare two benchmark runs")
+	}
+	maxSlowdown, err := maxSlowdownFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := readBenchReport(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := readBenchReport(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := newTestWriter(t)
+	defer out.flush()
+	// first, so it isn't missed below a long table
+	if warning := environmentWarning(old.Environment, cur.Environment); warning != "" {
+		fmt.Fprint(out, warning)
+	}
+	deltas, differing := compareBenchReports(old, cur)
+	writeBenchstatTable(out, deltas)
+	for _, d := range regressions(deltas, maxSlowdown) {
+		t.Errorf("%s got significantly slower: %s", d.key, d.deltaString())
+	}
+	if len(differing) > 0 {
+		fmt.Fprintf(out, "\n%d tests ran with a different harness or transform cache state and weren't compared:\n", len(differing))
+		for _, d := range differing {
+			fmt.Fprintf(out, "%s\t%s (%s, %s)\t%s (%s, %s)\n", d.key, ms(d.old.Median), d.old.HarnessCache, d.old.TransformCache,
+				ms(d.new.Median), d.new.HarnessCache, d.new.TransformCache)
+		}
+	}
+}
+
+// maxSlowdownFromEnv returns TC39_BENCH_MAX_SLOWDOWN, 0 if it's not set.
+func maxSlowdownFromEnv() (float64, error) {
+	v := os.Getenv("TC39_BENCH_MAX_SLOWDOWN")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || !(n > 0) || math.IsInf(n, 1) {
+		return 0, fmt.Errorf("TC39_BENCH_MAX_SLOWDOWN must be a positive percentage, got %q", v)
+	}
+	return n, nil
+}
+
+// regressions returns the deltas that got significantly slower by more than maxSlowdown percent,
+// none if it's 0.
+func regressions(deltas []tc39BenchDelta, maxSlowdown float64) []tc39BenchDelta {
+	var slower []tc39BenchDelta
+	for _, d := range deltas {
+		if maxSlowdown > 0 && d.significant() && d.percent() > maxSlowdown {
+			slower = append(slower, d)
+		}
+	}
+	return slower
+}
+
+type tc39BenchDelta struct {
+	key      string
+	old, new tc39BenchEntry
+}
+
+// significant reports whether the change is more than noise: both sides were measured at least
+// twice and the ranges between their min and max don't overlap.
+func (d tc39BenchDelta) significant() bool {
+	if d.old.Samples < 2 || d.new.Samples < 2 {
+		return false
+	}
+	return d.new.Min > d.old.Max || d.new.Max < d.old.Min
+}
+
+// percent is the change of the median in percents, positive when it got slower.
+func (d tc39BenchDelta) percent() float64 {
+	if d.old.Median == 0 {
+		return 0
+	}
+	return (float64(d.new.Median)/float64(d.old.Median) - 1) * 100
+}
+
+func (d tc39BenchDelta) deltaString() string {
+	if !d.significant() {
+		return "~"
+	}
+	return fmt.Sprintf("%+.2f%%", d.percent())
+}
+
+// compareBenchReports pairs up the entries present in both reports, the ones with the same cache
+// states are returned as deltas and the rest separately as they can't be meaningfully compared.
+func compareBenchReports(old, cur *tc39BenchReport) (deltas, differing []tc39BenchDelta) {
+	entries := make(map[string]tc39BenchEntry, len(cur.Tests))
+	for _, e := range cur.Tests {
+		entries[e.key()] = e
+	}
+	for _, e := range old.Tests {
+		newEntry, ok := entries[e.key()]
+		if !ok {
+			continue
+		}
+		d := tc39BenchDelta{key: e.key(), old: e, new: newEntry}
+		if e.HarnessCache != newEntry.HarnessCache || e.TransformCache != newEntry.TransformCache {
+			differing = append(differing, d)
+			continue
+		}
+		deltas = append(deltas, d)
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		return deltas[i].key < deltas[j].key
+	})
+	return deltas, differing
+}
+
+// benchstatValue formats an entry as its median and the spread around it, like benchstat does.
+func benchstatValue(e tc39BenchEntry) string {
+	if e.Median == 0 {
+		return ms(e.Median) + "ms"
+	}
+	spread := float64(e.Max-e.Min) / 2 / float64(e.Median) * 100
+	return fmt.Sprintf("%sms ± %.0f%%", ms(e.Median), spread)
+}
+
+// writeBenchstatTable writes the deltas as a benchstat-like table, with "~" for the changes that
+// aren't significant.
+func writeBenchstatTable(w io.Writer, deltas []tc39BenchDelta) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "name\told time/op\tnew time/op\tdelta\tsamples")
+	for _, d := range deltas {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d+%d\n", d.key, benchstatValue(d.old), benchstatValue(d.new),
+			d.deltaString(), d.old.Samples, d.new.Samples)
+	}
+	_ = tw.Flush()
+}
+
+func TestTC39BenchDeltaSignificance(t *testing.T) {
+	entry := func(samples int, min, median, max int64) tc39BenchEntry {
+		return tc39BenchEntry{Samples: samples, Min: ms2d(min), Median: ms2d(median), Max: ms2d(max)}
+	}
+	tests := []struct {
+		name     string
+		old, new tc39BenchEntry
+		delta    string
+	}{
+		{"slower", entry(5, 10, 11, 12), entry(5, 20, 22, 24), "+100.00%"},
+		{"faster", entry(5, 20, 22, 24), entry(5, 10, 11, 12), "-50.00%"},
+		{"overlapping", entry(5, 10, 11, 20), entry(5, 15, 22, 24), "~"},
+		{"single sample", entry(1, 10, 10, 10), entry(1, 20, 20, 20), "~"},
+		{"single old sample", entry(1, 10, 10, 10), entry(5, 20, 22, 24), "~"},
+		// ranges that touch overlap
+		{"touching", entry(5, 10, 11, 15), entry(5, 15, 16, 17), "~"},
+		{"barely apart", entry(5, 10, 11, 15), entry(5, 16, 22, 24), "+100.00%"},
+		{"unchanged", entry(5, 10, 11, 12), entry(5, 10, 11, 12), "~"},
+		// nothing to compare with, but it's still apart
+		{"zero median", entry(2, 0, 0, 0), entry(2, 1, 1, 1), "+0.00%"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			d := tc39BenchDelta{old: tc.old, new: tc.new}
+			assert.Equal(t, tc.delta, d.deltaString())
+		})
+	}
+}
+
+func TestCompareBenchReports(t *testing.T) {
+	entry := func(name string, strict bool, cache string) tc39BenchEntry {
+		return tc39BenchEntry{Name: name, Strict: strict, Samples: 3, Median: ms2d(1), HarnessCache: cache, TransformCache: "none"}
+	}
+	transformed := func(cache string) tc39BenchEntry {
+		e := entry("test/babel.js", false, "warm")
+		e.TransformCache = cache
+		return e
+	}
+	old := &tc39BenchReport{Tests: []tc39BenchEntry{
+		entry("test/b.js", false, "warm"), entry("test/a.js", true, "warm"), entry("test/a.js", false, "cold"),
+		entry("test/cache.js", false, "cold"), entry("test/mixed.js", false, "mixed"), entry("test/gone.js", false, "warm"),
+		transformed("cold"),
+	}}
+	cur := &tc39BenchReport{Tests: []tc39BenchEntry{
+		entry("test/a.js", false, "cold"), entry("test/a.js", true, "warm"), entry("test/b.js", false, "warm"),
+		entry("test/cache.js", false, "warm"), entry("test/mixed.js", false, "cold"), entry("test/new.js", false, "warm"),
+		transformed("warm"),
+	}}
+	deltas, differing := compareBenchReports(old, cur)
+	// only like with like, sorted, the tests in a single report aren't compared at all
+	var keys []string
+	for _, d := range deltas {
+		keys = append(keys, d.key)
+	}
+	require.Equal(t, []string{"test/a.js-strict:false", "test/a.js-strict:true", "test/b.js-strict:false"}, keys)
+	require.Len(t, differing, 3)
+	require.Equal(t, tc39BenchDelta{
+		key: "test/cache.js-strict:false", old: entry("test/cache.js", false, "cold"), new: entry("test/cache.js", false, "warm"),
+	}, differing[0])
+	require.Equal(t, "test/mixed.js-strict:false", differing[1].key)
+	// the same harness, but only one of them had the transform cached
+	require.Equal(t, tc39BenchDelta{key: "test/babel.js-strict:false", old: transformed("cold"), new: transformed("warm")}, differing[2])
+
+	deltas, differing = compareBenchReports(old, &tc39BenchReport{})
+	require.Empty(t, deltas)
+	require.Empty(t, differing)
+}
+
+func ms2d(n int64) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+func TestRegressions(t *testing.T) {
+	entry := func(min, median, max int64) tc39BenchEntry {
+		return tc39BenchEntry{Samples: 5, Min: ms2d(min), Median: ms2d(median), Max: ms2d(max)}
+	}
+	deltas := []tc39BenchDelta{
+		{key: "twice", old: entry(10, 10, 10), new: entry(20, 20, 20)},
+		{key: "by half", old: entry(10, 10, 10), new: entry(15, 15, 15)},
+		{key: "noise", old: entry(10, 10, 30), new: entry(20, 40, 60)},
+		{key: "faster", old: entry(20, 20, 20), new: entry(10, 10, 10)},
+	}
+	keys := func(deltas []tc39BenchDelta) (keys []string) {
+		for _, d := range deltas {
+			keys = append(keys, d.key)
+		}
+		return keys
+	}
+	require.Equal(t, []string{"twice", "by half"}, keys(regressions(deltas, 10)))
+	// more than the limit, not as much
+	require.Equal(t, []string{"twice"}, keys(regressions(deltas, 50)))
+	require.Empty(t, regressions(deltas, 100))
+	require.Empty(t, regressions(deltas, 0))
+	require.Empty(t, regressions(nil, 10))
+
+	defer func() { require.NoError(t, os.Unsetenv("TC39_BENCH_MAX_SLOWDOWN")) }()
+	require.NoError(t, os.Unsetenv("TC39_BENCH_MAX_SLOWDOWN"))
+	n, err := maxSlowdownFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, 0.0, n)
+	require.NoError(t, os.Setenv("TC39_BENCH_MAX_SLOWDOWN", "12.5"))
+	n, err = maxSlowdownFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, 12.5, n)
+	for _, v := range []string{"0", "-5", "10%", "NaN", "Inf", "a"} {
+		require.NoError(t, os.Setenv("TC39_BENCH_MAX_SLOWDOWN", v))
+		_, err = maxSlowdownFromEnv()
+		require.EqualError(t, err, fmt.Sprintf("TC39_BENCH_MAX_SLOWDOWN must be a positive percentage, got %q", v))
+	}
+}
+
+func TestBenchstatTable(t *testing.T) {
+	var out strings.Builder
+	writeBenchstatTable(&out, nil)
+	require.Equal(t, "name  old time/op  new time/op  delta  samples\n", out.String())
+
+	out.Reset()
+	writeBenchstatTable(&out, []tc39BenchDelta{
+		{key: "test/a.js-strict:true", old: tc39BenchEntry{Samples: 5, Min: ms2d(9), Median: ms2d(10), Max: ms2d(11)},
+			new: tc39BenchEntry{Samples: 5, Min: ms2d(18), Median: ms2d(20), Max: ms2d(22)}},
+		{key: "test/b.js-strict:false", old: tc39BenchEntry{Samples: 1}, new: tc39BenchEntry{Samples: 1}},
+	})
+	require.Equal(t, `name                    old time/op     new time/op     delta     samples
+test/a.js-strict:true   10.000ms ± 10%  20.000ms ± 10%  +100.00%  5+5
+test/b.js-strict:false  0.000ms         0.000ms         ~         1+1
+`, out.String())
+}
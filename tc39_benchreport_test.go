@@ -0,0 +1,323 @@
+package test262
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39BenchSchemaVersion is bumped whenever what the benchmark measures or how it's written
+// changes, as measurements from different versions aren't comparable. Version 1 measured the
+// whole of runTC39File, version 2 only measured running the test body, version 3 is the first
+// JSON one and version 4 has the state of the transform cache.
+const tc39BenchSchemaVersion = 4
+
+type tc39BenchReport struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	Metadata      tc39BenchMetadata `json:"metadata"`
+	// Environment is empty in the reports written before it was added.
+	Environment tc39Environment  `json:"environment"`
+	Tests       []tc39BenchEntry `json:"tests"`
+
+	Directories []tc39BenchmarkAggregate `json:"directories"`
+	Features    []tc39BenchmarkAggregate `json:"features"`
+}
+
+type tc39BenchMetadata struct {
+	GojaVersion   string    `json:"gojaVersion"`
+	Test262Commit string    `json:"test262Commit"`
+	CompatMode    string    `json:"compatMode"`
+	Workers       int       `json:"workers"`
+	GOOS          string    `json:"goos"`
+	GOARCH        string    `json:"goarch"`
+	Timestamp     time.Time `json:"timestamp"`
+	BenchOnly     bool      `json:"benchOnly"`
+}
+
+// tc39BenchEntry is a strict or sloppy variant of a test, all durations are in nanoseconds.
+type tc39BenchEntry struct {
+	Name    string        `json:"name"`
+	Strict  bool          `json:"strict"`
+	Samples int           `json:"samples"`
+	Min     time.Duration `json:"min"`
+	Median  time.Duration `json:"median"`
+	Max     time.Duration `json:"max"`
+	Harness time.Duration `json:"harness"`
+	Compile time.Duration `json:"compile"`
+
+	// PeakHeapAlloc is 0 when no sample was taken while the test ran.
+	PeakHeapAlloc        uint64 `json:"peakHeapAlloc"`
+	PeakHeapUnattributed bool   `json:"peakHeapUnattributed,omitempty"`
+
+	// HarnessCache is warm, cold or mixed depending on whether the harness program cache had the
+	// test's includes already compiled, and TransformCache likewise for the Babel cache of
+	// TC39_CACHE_DIR having the test's transform, none if it wasn't looked up. Only entries with
+	// the same states are comparable.
+	HarnessCache   string `json:"harnessCache"`
+	TransformCache string `json:"transformCache"`
+}
+
+func (e tc39BenchEntry) key() string {
+	return fmt.Sprintf("%s-strict:%v", e.Name, e.Strict)
+}
+
+func (ctx *tc39TestCtx) benchReport() tc39BenchReport {
+	rows := ctx.benchmarkRows()
+	report := tc39BenchReport{
+		SchemaVersion: tc39BenchSchemaVersion,
+		Metadata: tc39BenchMetadata{
+			GojaVersion:   moduleVersion("github.com/dop251/goja"),
+			Test262Commit: test262Commit(ctx.base),
+			CompatMode:    lib.CompatibilityModeExtended.String(),
+			Workers:       tc39Workers(),
+			GOOS:          runtime.GOOS,
+			GOARCH:        runtime.GOARCH,
+			Timestamp:     time.Now().UTC(),
+			BenchOnly:     ctx.benchOnly,
+		},
+		Environment: ctx.env,
+		Tests:       make([]tc39BenchEntry, len(rows)),
+	}
+	report.Directories, report.Features = ctx.benchmarkAggregates()
+	for i, row := range rows {
+		report.Tests[i] = tc39BenchEntry{
+			Name:    row.name,
+			Strict:  row.strict,
+			Samples: row.samples,
+			Min:     row.min,
+			Median:  row.median,
+			Max:     row.max,
+			Harness: row.harness,
+			Compile: row.compile,
+
+			PeakHeapAlloc:        row.peakHeap,
+			PeakHeapUnattributed: row.peakHeapUnattributed,
+
+			HarnessCache:   row.harnessCache,
+			TransformCache: row.transformCache,
+		}
+	}
+	return report
+}
+
+func (ctx *tc39TestCtx) writeBenchmarkFile(name string) error {
+	b, err := json.MarshalIndent(ctx.benchReport(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeArtifact(name, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// readBenchReport reads a report written by writeBenchmarkFile, refusing ones written with a
+// different schema version.
+func readBenchReport(name string) (*tc39BenchReport, error) {
+	b, err := ioutil.ReadFile(name) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	var report tc39BenchReport
+	if err = json.Unmarshal(b, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if report.SchemaVersion != tc39BenchSchemaVersion {
+		return nil, fmt.Errorf("%s has benchmark schema version %d, but only version %d can be compared",
+			name, report.SchemaVersion, tc39BenchSchemaVersion)
+	}
+	return &report, nil
+}
+
+func moduleVersion(module string) string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == module {
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
+
+// test262Commit returns the commit the checkout in base is at, which can be overridden with
+// TC39_TEST262_COMMIT for checkouts that aren't git repositories.
+func test262Commit(base string) string {
+	if commit := os.Getenv("TC39_TEST262_COMMIT"); commit != "" {
+		return commit
+	}
+	gitDir := filepath.Join(base, ".git")
+	head, err := ioutil.ReadFile(filepath.Join(gitDir, "HEAD")) //nolint:gosec
+	if err != nil {
+		return "unknown"
+	}
+	ref := strings.TrimSpace(string(head))
+	if !strings.HasPrefix(ref, "ref: ") {
+		return ref
+	}
+	ref = strings.TrimPrefix(ref, "ref: ")
+	if commit, err := ioutil.ReadFile(osPath(gitDir, ref)); err == nil { //nolint:gosec
+		return strings.TrimSpace(string(commit))
+	}
+	packed, err := ioutil.ReadFile(filepath.Join(gitDir, "packed-refs")) //nolint:gosec
+	if err != nil {
+		return "unknown"
+	}
+	for _, line := range strings.Split(string(packed), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == ref {
+			return fields[0]
+		}
+	}
+	return "unknown"
+}
+
+func TestTC39BenchReportRoundTrip(t *testing.T) {
+	report := tc39BenchReport{
+		SchemaVersion: tc39BenchSchemaVersion,
+		Metadata: tc39BenchMetadata{
+			GojaVersion:   "v0.0.0-20201022115936-e21ccf39bfce",
+			Test262Commit: "72154b17fc99a26e79b2586960f059360d4ce43d",
+			CompatMode:    "extended",
+			Workers:       4,
+			GOOS:          "linux",
+			GOARCH:        "amd64",
+			Timestamp:     time.Date(2020, 10, 22, 11, 59, 36, 0, time.UTC),
+		},
+		Environment: tc39Environment{Hash: "0123456789abcdef", Fingerprint: tc39Fingerprint{
+			GoVersion: "go1.14.10", GOOS: "linux", GOARCH: "amd64", NumCPU: 8, GOMAXPROCS: 8,
+			CompatMode: "extended", Workers: 4, Env: map[string]string{"TC39_BENCH": "1"},
+		}},
+		Tests: []tc39BenchEntry{{
+			Name:    "test/built-ins/Array/length.js",
+			Strict:  true,
+			Samples: 3,
+			Min:     1000,
+			Median:  2000,
+			Max:     3000,
+			Harness: 400,
+			Compile: 500,
+
+			PeakHeapAlloc: 1 << 20,
+
+			HarnessCache:   "warm",
+			TransformCache: "cold",
+		}},
+		Directories: []tc39BenchmarkAggregate{{Name: "built-ins/Array", Tests: 1, Total: 4000, Mean: 4000}},
+		Features:    []tc39BenchmarkAggregate{{Name: "Symbol", Tests: 1, Total: 4000, Mean: 4000}},
+	}
+	expected := `{
+  "schemaVersion": 4,
+  "metadata": {
+    "gojaVersion": "v0.0.0-20201022115936-e21ccf39bfce",
+    "test262Commit": "72154b17fc99a26e79b2586960f059360d4ce43d",
+    "compatMode": "extended",
+    "workers": 4,
+    "goos": "linux",
+    "goarch": "amd64",
+    "timestamp": "2020-10-22T11:59:36Z",
+    "benchOnly": false
+  },
+  "environment": {
+    "hash": "0123456789abcdef",
+    "fingerprint": {
+      "goVersion": "go1.14.10",
+      "goos": "linux",
+      "goarch": "amd64",
+      "numCPU": 8,
+      "gomaxprocs": 8,
+      "gogc": "",
+      "compatMode": "extended",
+      "workers": 4,
+      "env": {
+        "TC39_BENCH": "1"
+      }
+    }
+  },
+  "tests": [
+    {
+      "name": "test/built-ins/Array/length.js",
+      "strict": true,
+      "samples": 3,
+      "min": 1000,
+      "median": 2000,
+      "max": 3000,
+      "harness": 400,
+      "compile": 500,
+      "peakHeapAlloc": 1048576,
+      "harnessCache": "warm",
+      "transformCache": "cold"
+    }
+  ],
+  "directories": [
+    {
+      "name": "built-ins/Array",
+      "tests": 1,
+      "total": 4000,
+      "mean": 4000
+    }
+  ],
+  "features": [
+    {
+      "name": "Symbol",
+      "tests": 1,
+      "total": 4000,
+      "mean": 4000
+    }
+  ]
+}`
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	require.NoError(t, err)
+	require.Equal(t, expected, string(b))
+
+	var decoded tc39BenchReport
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, report, decoded)
+}
+
+func TestReadBenchReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-benchreport")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	write := func(name string, report interface{}) string {
+		b, err := json.Marshal(report)
+		require.NoError(t, err)
+		file := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(file, b, 0o600))
+		return file
+	}
+
+	file := write("current.json", tc39BenchReport{
+		SchemaVersion: tc39BenchSchemaVersion, Tests: []tc39BenchEntry{{Name: "test/a.js", Samples: 1, Median: 10}},
+	})
+	report, err := readBenchReport(file)
+	require.NoError(t, err)
+	require.Equal(t, []tc39BenchEntry{{Name: "test/a.js", Samples: 1, Median: 10}}, report.Tests)
+
+	// the measurements of another schema aren't comparable
+	for _, version := range []int{tc39BenchSchemaVersion + 1, tc39BenchSchemaVersion - 1, 0} {
+		file = write(fmt.Sprintf("v%d.json", version), tc39BenchReport{SchemaVersion: version})
+		_, err = readBenchReport(file)
+		require.EqualError(t, err, fmt.Sprintf("%s has benchmark schema version %d, but only version %d can be compared",
+			file, version, tc39BenchSchemaVersion))
+	}
+	// the tab separated output of the first version isn't JSON at all
+	file = filepath.Join(dir, "v1.tsv")
+	require.NoError(t, ioutil.WriteFile(file, []byte("name\tmode\tduration(ms)\n"), 0o600))
+	_, err = readBenchReport(file)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), file+": "), err)
+	_, err = readBenchReport(filepath.Join(dir, "missing.json"))
+	require.True(t, os.IsNotExist(err), err)
+}
@@ -0,0 +1,141 @@
+package test262
+
+import (
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBootstrap pins down what the runtime every test runs in provides before the harness, the
+// $262 host hooks, independently of the results of whole suites.
+func TestBootstrap(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	newVM := func() *goja.Runtime {
+		vm, _, _ := ctx.newRuntime("test/bootstrap.js", &tc39Output{})
+		return vm
+	}
+
+	t.Run("$262", func(t *testing.T) {
+		vm := newVM()
+		v, err := vm.RunString(`Object.getOwnPropertyNames($262).sort().join()`)
+		require.NoError(t, err)
+		require.Equal(t, "createRealm,detachArrayBuffer,evalScript,global", v.String())
+	})
+
+	t.Run("global", func(t *testing.T) {
+		ctx.runTC39Test(t, "test/global.js", `assert.sameValue($262.global, this);
+var other = $262.createRealm();
+assert.sameValue(other.global, other.evalScript("this"));
+assert(other.global !== this);`, &tc39Meta{}, false)
+		require.Equal(t, CategoryPass, ctx.results.resultsCopy()["test/global.js-strict:false"].Category)
+	})
+
+	t.Run("createRealm", func(t *testing.T) {
+		vm := newVM()
+		v, err := vm.RunString(`
+			var other = $262.createRealm();
+			other.evalScript("var x = 1;");
+			var nested = other.createRealm();
+			[typeof x, other.global.x, other.global.Array !== Array, other.global !== this,
+				typeof other.global.assert, typeof other.global.Test262Error,
+				nested.global !== other.global, Object.getOwnPropertyNames(other).sort()].join()`)
+		require.NoError(t, err)
+		require.Equal(t, "undefined,1,true,true,function,function,true,"+
+			"createRealm,detachArrayBuffer,evalScript,global", v.String())
+
+		ctx.runTC39Test(t, "test/realm.js", `var other = $262.createRealm();
+assert.sameValue(other.evalScript("1 + 1"), 2);`, &tc39Meta{}, false)
+		require.Equal(t, CategoryPass, ctx.results.resultsCopy()["test/realm.js-strict:false"].Category)
+		require.Equal(t, "3 tests ran using $262.createRealm, which skipped them before it was implemented, "+
+			"2 of them passed", ctx.results.realmSummary())
+	})
+
+	t.Run("detachArrayBuffer", func(t *testing.T) {
+		vm := newVM()
+		v, err := vm.RunString(`
+			var buffer = new ArrayBuffer(8), view = new Uint8Array(buffer);
+			var before = buffer.byteLength;
+			$262.detachArrayBuffer(buffer);
+			var threw = false;
+			try {
+				new Uint8Array(buffer);
+			} catch (e) {
+				threw = e instanceof TypeError;
+			}
+			[before, threw].join()`)
+		require.NoError(t, err)
+		require.Equal(t, "8,true", v.String())
+		// an undefined key is the one every buffer has
+		_, err = vm.RunString(`$262.detachArrayBuffer(new ArrayBuffer(1), undefined)`)
+		require.NoError(t, err)
+		for src, message := range map[string]string{
+			`$262.detachArrayBuffer()`:                        "takes the buffer and optionally its key, got 0 arguments",
+			`$262.detachArrayBuffer(buffer, undefined, 1)`:    "takes the buffer and optionally its key, got 3 arguments",
+			`$262.detachArrayBuffer({})`:                      "is called with incompatible argument",
+			`$262.detachArrayBuffer(new ArrayBuffer(1), "k")`: "is called with a key, the buffer has none",
+		} {
+			// the TypeError is of the test's runtime, so assert.throws recognizes it
+			v, err = vm.RunString(`(function() {
+				try {
+					` + src + `;
+				} catch (e) {
+					return [e instanceof TypeError, e.constructor === TypeError, e.message].join();
+				}
+			})()`)
+			require.NoError(t, err, src)
+			require.Equal(t, "true,true,detachArrayBuffer() "+message, v.String(), src)
+		}
+	})
+
+	t.Run("evalScript", func(t *testing.T) {
+		vm := newVM()
+		v, err := vm.RunString(`
+			var result = $262.evalScript("var evaluated = 1; let lexical = 2; evaluated + lexical");
+			[result, evaluated, typeof lexical, this.hasOwnProperty("evaluated")].join()`)
+		require.NoError(t, err)
+		require.Equal(t, "3,1,number,true", v.String())
+
+		// what the script throws and the compiler's SyntaxError are errors of the realm
+		v, err = vm.RunString(`
+			function thrown(src) {
+				try {
+					$262.evalScript(src);
+				} catch (e) {
+					return [e.constructor === TypeError, e.constructor === SyntaxError, e instanceof Error].join();
+				}
+				return "nothing thrown";
+			}
+			[thrown("null.x"), thrown("var 1;")].join(" ")`)
+		require.NoError(t, err)
+		require.Equal(t, "true,false,true false,true,true", v.String())
+		_, err = vm.RunString(`$262.evalScript("throw new RangeError('uncaught')")`)
+		require.Error(t, err)
+		exc, ok := err.(*goja.Exception)
+		require.True(t, ok, "%v", err)
+		require.Equal(t, "RangeError: uncaught", exc.Value().String())
+	})
+
+	t.Run("print", func(t *testing.T) {
+		out := &tc39Output{}
+		vm, _, _ := ctx.newRuntime("test/bootstrap.js", out)
+		_, err := vm.RunString(`print("a", 1, {}); print()`)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a 1 [object Object]", ""}, out.captured())
+	})
+
+	// there's no $262.IsHTMLDDA, whatever the esid of a test needing it
+	t.Run("test/is-html-dda.js", func(t *testing.T) {
+		defer func() {
+			require.True(t, t.Skipped())
+			for _, strict := range []bool{false, true} {
+				result := ctx.results.resultsCopy()[variantKey("test/is-html-dda.js", strict)]
+				require.Equal(t, CategorySkippedFeature, result.Category)
+				require.Equal(t, "Host feature IsHTMLDDA needs $262.IsHTMLDDA, an object with the [[IsHTMLDDA]] slot of document.all",
+					result.Message)
+			}
+			require.Equal(t, []string{"1 tests skipped needing the host feature IsHTMLDDA"}, ctx.results.hostFeatureSummary())
+		}()
+		ctx.runTC39File("test/is-html-dda.js", "test/is-html-dda.js", t)
+	})
+}
@@ -0,0 +1,136 @@
+package test262
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ResultCategory is how the result of a test variant compares to what was expected of it.
+type ResultCategory int
+
+const (
+	// CategoryPass is a test variant that completed as the test expects.
+	CategoryPass ResultCategory = iota
+	// CategoryExpectedFailure is a failure with exactly the error in the expected errors.
+	CategoryExpectedFailure
+	// CategoryNewFailure is a failure of a test variant expected to pass.
+	CategoryNewFailure
+	// CategoryChangedFailure is a failure with another error than the expected one.
+	CategoryChangedFailure
+	// CategoryTimeout is a test variant that was interrupted for taking longer than TC39_TEST_TIMEOUT,
+	// or an async one TC39_ASYNC_TIMEOUT.
+	CategoryTimeout
+	// CategoryPanic is a test variant that panicked, unless that was the expected error.
+	CategoryPanic
+	// CategoryPendingHostWork is a test variant that completed with callbacks the host scheduled
+	// for it still pending, unless that was the expected error.
+	CategoryPendingHostWork
+	// CategoryHarness is a test variant that couldn't run as a harness file it needs failed,
+	// unless that was the expected error.
+	CategoryHarness
+	// CategoryInfrastructure is a test variant the runner couldn't run at all.
+	CategoryInfrastructure
+	// CategorySkippedExcluded is a test in the skip list.
+	CategorySkippedExcluded
+	// CategorySkippedFeature is a test using a blacklisted feature.
+	CategorySkippedFeature
+	// CategorySkippedEsid is a test of a part of the spec goja doesn't claim to implement.
+	CategorySkippedEsid
+	// CategorySkippedDeadline is a test of a directory that exceeded its deadline, or one not run
+	// as the deadline of the whole suite passed.
+	CategorySkippedDeadline
+	// CategorySkippedIgnorable is a test that threw IgnorableTestError, using a host hook that
+	// isn't available.
+	CategorySkippedIgnorable
+	// CategorySkippedQuarantined is a test in quarantine.yaml.
+	CategorySkippedQuarantined
+)
+
+//nolint:gochecknoglobals
+var resultCategoryNames = [...]string{
+	CategoryPass:               "pass",
+	CategoryExpectedFailure:    "expected-failure",
+	CategoryNewFailure:         "new-failure",
+	CategoryChangedFailure:     "changed-failure",
+	CategoryTimeout:            "timeout",
+	CategoryPanic:              "panic",
+	CategoryPendingHostWork:    "pending-host-work",
+	CategoryHarness:            "harness-failure",
+	CategoryInfrastructure:     "infrastructure",
+	CategorySkippedExcluded:    "skipped-excluded",
+	CategorySkippedFeature:     "skipped-feature",
+	CategorySkippedEsid:        "skipped-esid",
+	CategorySkippedDeadline:    "skipped-deadline",
+	CategorySkippedIgnorable:   "skipped-ignorable",
+	CategorySkippedQuarantined: "skipped-quarantined",
+}
+
+func (c ResultCategory) String() string {
+	if c < 0 || int(c) >= len(resultCategoryNames) {
+		return "unknown"
+	}
+	return resultCategoryNames[c]
+}
+
+// unexpected tells if the category is a result the run should fail for.
+func (c ResultCategory) unexpected() bool {
+	return c == CategoryNewFailure || c == CategoryChangedFailure || c == CategoryTimeout || c == CategoryPanic ||
+		c == CategoryPendingHostWork || c == CategoryHarness
+}
+
+// skipped tells if the category is a test variant that didn't run, all the categories after
+// CategoryInfrastructure.
+func (c ResultCategory) skipped() bool {
+	return c > CategoryInfrastructure
+}
+
+// variantKey is how a test variant is named in breaking_test_errors.json.
+func variantKey(name string, strict bool) string {
+	return fmt.Sprintf("%s-strict:%v", slashPath(name), strict)
+}
+
+// legacyErrors returns the messages of the unexpected results by variant key, what the runner
+// prints for breaking_test_errors.json.
+func legacyErrors(results map[string]TestResult) map[string]string {
+	errs := make(map[string]string)
+	for key, result := range results {
+		if result.Category.unexpected() {
+			errs[key] = result.Message
+		}
+	}
+	return errs
+}
+
+func TestResultCategories(t *testing.T) {
+	for c := CategoryPass; c <= CategorySkippedQuarantined; c++ {
+		require.NotEqual(t, "unknown", c.String(), int(c))
+	}
+	require.Equal(t, "unknown", (CategorySkippedQuarantined + 1).String())
+
+	results := map[string]TestResult{
+		"test/pass.js-strict:true":       {Category: CategoryPass},
+		"test/expected.js-strict:false":  {Category: CategoryExpectedFailure, Message: "test/expected.js: Test262Error: a"},
+		"test/new.js-strict:true":        {Category: CategoryNewFailure, Message: "test/new.js: Test262Error: b"},
+		"test/changed.js-strict:false":   {Category: CategoryChangedFailure, Message: "test/changed.js: TypeError: c"},
+		"test/timeout.js-strict:true":    {Category: CategoryTimeout, Message: "test/timeout.js: the test didn't finish in TC39_TEST_TIMEOUT (1s)"},
+		"test/panic.js-strict:false":     {Category: CategoryPanic, Message: "panic: test/panic.js: d"},
+		"test/pending.js-strict:true":    {Category: CategoryPendingHostWork, Message: "test/pending.js: pending host work: e"},
+		"test/harness.js-strict:true":    {Category: CategoryHarness, Message: "test/harness.js: harness include f.js failed: g"},
+		"test/infra.js-strict:false":     {Category: CategoryInfrastructure, Message: "file too large"},
+		"test/excluded.js-strict:false":  {Category: CategorySkippedExcluded, Message: "Excluded"},
+		"test/ignorable.js-strict:false": {Category: CategorySkippedIgnorable, Message: "Test threw IgnorableTestError"},
+	}
+	b, err := json.MarshalIndent(legacyErrors(results), "", "  ")
+	require.NoError(t, err)
+	require.Equal(t, `{
+  "test/changed.js-strict:false": "test/changed.js: TypeError: c",
+  "test/harness.js-strict:true": "test/harness.js: harness include f.js failed: g",
+  "test/new.js-strict:true": "test/new.js: Test262Error: b",
+  "test/panic.js-strict:false": "panic: test/panic.js: d",
+  "test/pending.js-strict:true": "test/pending.js: pending host work: e",
+  "test/timeout.js-strict:true": "test/timeout.js: the test didn't finish in TC39_TEST_TIMEOUT (1s)"
+}`, string(b))
+}
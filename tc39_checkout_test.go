@@ -0,0 +1,79 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Test262Commit is the test262 commit the expected errors were generated against.
+const tc39Test262Commit = "72154b17fc99a26e79b2586960f059360d4ce43d"
+
+var errFoundTest = errors.New("found a test")
+
+// checkCheckout verifies that base looks like a complete test262 checkout, so that a half
+// extracted one fails once instead of failing every single test with the same error.
+func checkCheckout(base string) error {
+	var problems []string
+	for _, name := range []string{"harness/assert.js", "harness/sta.js"} {
+		f, err := os.Open(osPath(base, name)) //nolint:gosec
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s can't be read: %v", name, err))
+			continue
+		}
+		_ = f.Close()
+	}
+
+	err := filepath.Walk(filepath.Join(base, "test"), func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(p, ".js") {
+			return errFoundTest
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		problems = append(problems, "test/ doesn't contain a single .js file")
+	case !errors.Is(err, errFoundTest):
+		problems = append(problems, fmt.Sprintf("test/ can't be read: %v", err))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s looks like an incomplete test262 checkout:\n\t%s\n"+
+		"re-fetch it with:\n\tgit -C %s fetch --depth=1 origin %s && git -C %s reset --hard FETCH_HEAD",
+		slashPath(base), strings.Join(problems, "\n\t"), base, tc39Test262Commit, base)
+}
+
+func TestCheckCheckout(t *testing.T) {
+	base, err := ioutil.TempDir("", "tc39checkout")
+	require.NoError(t, err)
+	defer os.RemoveAll(base) //nolint:errcheck
+
+	write := func(name string) {
+		name = osPath(base, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+		require.NoError(t, ioutil.WriteFile(name, nil, 0o644))
+	}
+
+	write("test/built-ins/README.md")
+	err = checkCheckout(base)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "harness/assert.js can't be read")
+	require.Contains(t, err.Error(), "harness/sta.js can't be read")
+	require.Contains(t, err.Error(), "test/ doesn't contain a single .js file")
+
+	write("harness/assert.js")
+	write("harness/sta.js")
+	write("test/built-ins/Array/length.js")
+	require.NoError(t, checkCheckout(base))
+}
@@ -0,0 +1,74 @@
+package test262
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var (
+	// realTimeTests need the clock to advance while they run, so they always get the real one.
+	realTimeTests = map[string]bool{}
+)
+
+// tc39ClockHook freezes the clock of every test at the same instant, so the tests observing
+// Date.now() advancing or a DST transition don't depend on when they run.
+type tc39ClockHook struct {
+	instant time.Time
+}
+
+// clockFromEnv returns the clock hook for TC39_FROZEN_TIME, nil if the real clock is to be used.
+func clockFromEnv() (*tc39ClockHook, error) {
+	v := os.Getenv("TC39_FROZEN_TIME")
+	if v == "" {
+		return nil, nil
+	}
+	instant, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("TC39_FROZEN_TIME: %w", err)
+	}
+	return &tc39ClockHook{instant: instant}, nil
+}
+
+func (h *tc39ClockHook) Before(tc *TestInfo, vm *goja.Runtime) error {
+	if !realTimeTests[tc.Name] {
+		vm.SetTimeSource(func() time.Time { return h.instant })
+	}
+	return nil
+}
+
+func (h *tc39ClockHook) After(*TestInfo, *TestResult) {}
+
+// clockSummary tells which clock the tests ran with.
+func clockSummary(h *tc39ClockHook) string {
+	if h == nil {
+		return "deterministic clock: off, the tests used the real time"
+	}
+	return fmt.Sprintf("deterministic clock: on, frozen at %s, except for %d tests needing the real time",
+		h.instant.Format(time.RFC3339), len(realTimeTests))
+}
+
+func TestClockHook(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	instant := time.Date(2020, time.March, 29, 1, 30, 0, 0, time.UTC)
+	ctx.opts.Hooks = []TestHook{&tc39ClockHook{instant: instant}}
+
+	frozen := fmt.Sprintf(`
+		var now = Date.now();
+		assert.sameValue(now, %d);
+		for (var i = 0; i < 1000; i++) {
+			assert.sameValue(Date.now(), now);
+		}
+		assert.sameValue(new Date().getTime(), now);`, instant.UnixNano()/int64(time.Millisecond))
+	ctx.runTC39Test(t, "test/clock.js", frozen, &tc39Meta{}, false)
+
+	realTimeTests["test/real-clock.js"] = true
+	defer delete(realTimeTests, "test/real-clock.js")
+	ctx.runTC39Test(t, "test/real-clock.js", `assert(Date.now() > 1600000000000);`, &tc39Meta{}, false)
+	require.Empty(t, ctx.results.errorsCopy())
+}
@@ -0,0 +1,191 @@
+package test262
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// compatModes are the compatibility modes every test runs in, TC39_COMPAT, the extended one by
+// default.
+var compatModes = []lib.CompatibilityMode{lib.CompatibilityModeExtended} //nolint:gochecknoglobals
+
+// transpiledFeatures are the features of the syntax goja only parses once Babel transformed it, the
+// base mode skips their tests instead of failing them all with a SyntaxError.
+//nolint:gochecknoglobals
+var transpiledFeatures = map[string]bool{
+	"arrow-function":           true,
+	"async-functions":          true,
+	"class":                    true,
+	"computed-property-names":  true,
+	"const":                    true,
+	"default-parameters":       true,
+	"destructuring-assignment": true,
+	"destructuring-binding":    true,
+	"exponentiation":           true,
+	"generators":               true,
+	"let":                      true,
+	"object-rest":              true,
+	"object-spread":            true,
+	"super":                    true,
+	"template":                 true,
+}
+
+func compatModesFromEnv() ([]lib.CompatibilityMode, error) {
+	v := os.Getenv("TC39_COMPAT")
+	if v == "" {
+		return []lib.CompatibilityMode{lib.CompatibilityModeExtended}, nil
+	}
+	var modes []lib.CompatibilityMode
+	seen := make(map[lib.CompatibilityMode]bool)
+	for _, s := range strings.Split(v, ",") {
+		mode, err := lib.CompatibilityModeString(strings.TrimSpace(s))
+		if err != nil || seen[mode] {
+			return nil, fmt.Errorf("TC39_COMPAT must be a list of distinct modes out of base and extended, got %q", v)
+		}
+		seen[mode] = true
+		modes = append(modes, mode)
+	}
+	return modes, nil
+}
+
+// modeKey is the key of a variant run in the given mode, the one of variantKey for the default
+// mode and with a -mode:<mode> suffix for the others.
+func modeKey(name string, strict bool, mode lib.CompatibilityMode) string {
+	if mode.String() == tc39DefaultMode {
+		return variantKey(name, strict)
+	}
+	return variantKey(name, strict) + "-mode:" + mode.String()
+}
+
+// babelOnly returns why the mode can't run the test, empty if it can. The base mode has no Babel
+// for the modules and the syntax of transpiledFeatures.
+func babelOnly(meta *tc39Meta, mode lib.CompatibilityMode) string {
+	if mode != lib.CompatibilityModeBase {
+		return ""
+	}
+	if meta.hasFlag("module") {
+		return "Modules need Babel, which the base mode doesn't have"
+	}
+	for _, feature := range meta.Features {
+		if transpiledFeatures[feature] {
+			return fmt.Sprintf("Feature %s needs Babel, which the base mode doesn't have", feature)
+		}
+	}
+	return ""
+}
+
+// compatSummary has a line for every mode with how many of the variants it ran passed, none if
+// only the default mode ran.
+func (r *tc39Results) compatSummary(modes []lib.CompatibilityMode) []string {
+	if len(modes) == 1 && modes[0].String() == tc39DefaultMode {
+		return nil
+	}
+	type counts struct{ ran, passed, skipped int }
+	byMode := make(map[string]*counts, len(modes))
+	for _, mode := range modes {
+		byMode[mode.String()] = &counts{}
+	}
+	r.mu.Lock()
+	for nameKey, result := range r.results {
+		k, err := parseLegacyKey(nameKey, nil)
+		if err != nil || byMode[k.Mode] == nil {
+			continue
+		}
+		c := byMode[k.Mode]
+		switch {
+		case result.Category.skipped():
+			c.skipped++
+		case result.Category == CategoryPass:
+			c.passed++
+			c.ran++
+		default:
+			c.ran++
+		}
+	}
+	r.mu.Unlock()
+	lines := make([]string, 0, len(modes))
+	for mode, c := range byMode {
+		lines = append(lines, fmt.Sprintf("%s mode (TC39_COMPAT): %d of the %d test variants it ran passed, %d skipped",
+			mode, c.passed, c.ran, c.skipped))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestCompatModes(t *testing.T) {
+	for v, expected := range map[string][]lib.CompatibilityMode{
+		"":               {lib.CompatibilityModeExtended},
+		"base":           {lib.CompatibilityModeBase},
+		"base,extended":  {lib.CompatibilityModeBase, lib.CompatibilityModeExtended},
+		"extended, base": {lib.CompatibilityModeExtended, lib.CompatibilityModeBase},
+	} {
+		require.NoError(t, os.Setenv("TC39_COMPAT", v))
+		modes, err := compatModesFromEnv()
+		require.NoError(t, err, v)
+		require.Equal(t, expected, modes, v)
+	}
+	for _, v := range []string{"es6", "base,base", "base,", ","} {
+		require.NoError(t, os.Setenv("TC39_COMPAT", v))
+		_, err := compatModesFromEnv()
+		require.EqualError(t, err, fmt.Sprintf("TC39_COMPAT must be a list of distinct modes out of base and extended, got %q", v))
+	}
+	require.NoError(t, os.Unsetenv("TC39_COMPAT"))
+
+	defer func(old []lib.CompatibilityMode) { compatModes = old }(compatModes)
+	compatModes = []lib.CompatibilityMode{lib.CompatibilityModeBase, lib.CompatibilityModeExtended}
+	ctx := newFixtureCtx(t)
+	ctx.expectedErrors = map[string]string{}
+	tb := &tc39CountingTB{TB: t}
+	for _, name := range []string{"test/compat/es5.js", "test/compat/es6.js", "test/compat/es6-untagged.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, tb)
+		})
+	}
+
+	results := ctx.results.resultsCopy()
+	for _, strict := range []bool{false, true} {
+		// plain ES5 runs the same in both
+		require.Equal(t, CategoryPass, results[modeKey("test/compat/es5.js", strict, lib.CompatibilityModeBase)].Category)
+		require.Equal(t, CategoryPass, results[modeKey("test/compat/es5.js", strict, lib.CompatibilityModeExtended)].Category)
+
+		// what needs Babel and says so is skipped in the base mode
+		base := results[modeKey("test/compat/es6.js", strict, lib.CompatibilityModeBase)]
+		require.Equal(t, CategorySkippedFeature, base.Category)
+		require.Equal(t, "Feature arrow-function needs Babel, which the base mode doesn't have", base.Message)
+		require.Equal(t, CategoryPass, results[modeKey("test/compat/es6.js", strict, lib.CompatibilityModeExtended)].Category)
+
+		// and what doesn't say so fails in it
+		base = results[modeKey("test/compat/es6-untagged.js", strict, lib.CompatibilityModeBase)]
+		require.Equal(t, CategoryNewFailure, base.Category)
+		require.Contains(t, base.Message, "test/compat/es6-untagged.js: Line 6:18 Unexpected token ILLEGAL")
+		require.Equal(t, CategoryPass, results[modeKey("test/compat/es6-untagged.js", strict, lib.CompatibilityModeExtended)].Category)
+	}
+	require.Equal(t, 2, tb.errors)
+	errs := ctx.results.errorsCopy()
+	require.Len(t, errs, 2)
+	require.Contains(t, errs, "test/compat/es6-untagged.js-strict:true-mode:base")
+
+	require.Equal(t, []string{
+		"base mode (TC39_COMPAT): 2 of the 4 test variants it ran passed, 2 skipped",
+		"extended mode (TC39_COMPAT): 6 of the 6 test variants it ran passed, 0 skipped",
+	}, ctx.results.compatSummary(compatModes))
+	require.Empty(t, ctx.results.compatSummary([]lib.CompatibilityMode{lib.CompatibilityModeExtended}))
+
+	// a failure of the base mode is expected by its own key
+	ctx = newFixtureCtx(t)
+	ctx.expectedErrors = errs
+	t.Run("test/compat/es6-untagged.js", func(t *testing.T) {
+		ctx.runTC39File("test/compat/es6-untagged.js", "test/compat/es6-untagged.js", t)
+	})
+	for _, strict := range []bool{false, true} {
+		result := ctx.results.resultsCopy()[modeKey("test/compat/es6-untagged.js", strict, lib.CompatibilityModeBase)]
+		require.Equal(t, CategoryExpectedFailure, result.Category)
+	}
+}
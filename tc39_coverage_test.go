@@ -0,0 +1,114 @@
+package test262
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39CoverageFile is the artifact listing the esids none of whose tests ran, it's written after
+// every full run.
+const tc39CoverageFile = "esid_coverage.json"
+
+// tc39CoverageGap is an esid of which all tests were skipped.
+type tc39CoverageGap struct {
+	Esid  string `json:"esid"`
+	Tests int    `json:"tests"`
+	// Reason is why most of the tests were skipped, and ReasonTests how many of them were.
+	Reason      string `json:"reason"`
+	ReasonTests int    `json:"reasonTests"`
+}
+
+// coverageGaps returns the esids without a single executed test, sorted, from the number of
+// tests of every esid by their skip reason, with an empty reason for the executed ones.
+func coverageGaps(coverage map[string]map[string]int) []tc39CoverageGap {
+	gaps := make([]tc39CoverageGap, 0)
+	for esid, reasons := range coverage {
+		if reasons[""] > 0 {
+			continue
+		}
+		gap := tc39CoverageGap{Esid: esid}
+		for reason, n := range reasons {
+			gap.Tests += n
+			// the smallest reason wins ties, so the result doesn't depend on the map order
+			if n > gap.ReasonTests || n == gap.ReasonTests && reason < gap.Reason {
+				gap.Reason, gap.ReasonTests = reason, n
+			}
+		}
+		gaps = append(gaps, gap)
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].Esid < gaps[j].Esid })
+	return gaps
+}
+
+func writeCoverageGaps(name string, gaps []tc39CoverageGap) error {
+	b, err := json.MarshalIndent(gaps, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeArtifact(name, func(w io.Writer) error {
+		_, err := w.Write(append(b, '\n'))
+		return err
+	})
+}
+
+func TestCoverageGaps(t *testing.T) {
+	coverage := map[string]map[string]int{
+		"sec-array.prototype.map": {"": 3, "Blacklisted feature BigInt": 2},
+		"sec-proxy-object-internal-methods-and-internal-slots-get-p-receiver": {
+			"Not ES6 or ES5 esid: sec-proxy-object-internal-methods-and-internal-slots-get-p-receiver": 5,
+			"Blacklisted feature BigInt": 1,
+		},
+		"sec-bigint": {"Blacklisted feature BigInt": 2, "skipped while running": 2},
+	}
+	require.Equal(t, []tc39CoverageGap{
+		{Esid: "sec-bigint", Tests: 4, Reason: "Blacklisted feature BigInt", ReasonTests: 2},
+		{
+			Esid: "sec-proxy-object-internal-methods-and-internal-slots-get-p-receiver", Tests: 6,
+			Reason:      "Not ES6 or ES5 esid: sec-proxy-object-internal-methods-and-internal-slots-get-p-receiver",
+			ReasonTests: 5,
+		},
+	}, coverageGaps(coverage))
+
+	// every test of it ran, or it has none at all
+	require.Empty(t, coverageGaps(map[string]map[string]int{"sec-array.prototype.map": {"": 1}}))
+	require.NotNil(t, coverageGaps(nil))
+	require.Empty(t, coverageGaps(nil))
+	// ties go to the smallest reason
+	require.Equal(t, []tc39CoverageGap{{Esid: "sec-x", Tests: 4, Reason: "a", ReasonTests: 2}},
+		coverageGaps(map[string]map[string]int{"sec-x": {"b": 2, "a": 2}}))
+
+	dir, err := ioutil.TempDir("", "tc39-coverage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, tc39CoverageFile)
+	require.NoError(t, writeCoverageGaps(file, coverageGaps(nil)))
+	b, err := ioutil.ReadFile(file) //nolint:gosec
+	require.NoError(t, err)
+	require.Equal(t, "[]\n", string(b))
+
+	// a test is counted by its esid once it ran or was skipped, the ones without an esid aren't
+	ctx := newFixtureCtx(t)
+	for _, name := range []string{"test/skip-features/esid.js", "test/skip-features/esid-array.js", "test/compat/es5.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, t)
+		})
+	}
+	coverage = ctx.results.coverageByEsid()
+	require.Equal(t, map[string]map[string]int{
+		"sec-arraybuffer.prototype.slice": {"": 1},
+		"sec-array.prototype.map":         {"Not ES6 or ES5 esid: sec-array.prototype.map": 1},
+	}, coverage)
+	// it's a copy
+	coverage["sec-array.prototype.map"][""] = 1
+	require.Equal(t, []tc39CoverageGap{{
+		Esid: "sec-array.prototype.map", Tests: 1, Reason: "Not ES6 or ES5 esid: sec-array.prototype.map", ReasonTests: 1,
+	}}, coverageGaps(ctx.results.coverageByEsid()))
+}
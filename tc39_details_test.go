@@ -0,0 +1,185 @@
+package test262
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+const tc39DefaultMaxDetails = 4096
+
+// maxDetails is the most bytes the details of a failure can have, a test throwing an object with
+// a huge property shouldn't blow up the results.
+var maxDetails = tc39DefaultMaxDetails //nolint:gochecknoglobals
+
+func maxDetailsFromEnv() (int, error) {
+	v := os.Getenv("TC39_MAX_DETAILS")
+	if v == "" {
+		return tc39DefaultMaxDetails, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("TC39_MAX_DETAILS must be a positive number of bytes, got %q", v)
+	}
+	return n, nil
+}
+
+// errorDetails describes what a test threw beyond its message, the JS stack and the own
+// properties of the thrown value, so a failure can be triaged from the results alone.
+func errorDetails(vm *goja.Runtime, exc *goja.Exception) string {
+	var b strings.Builder
+	// the first line is the value, which the message already has
+	if i := strings.IndexByte(exc.String(), '\n'); i >= 0 && i+1 < len(exc.String()) {
+		b.WriteString("stack:\n")
+		b.WriteString(exc.String()[i+1:])
+	}
+	if o, ok := exc.Value().(*goja.Object); ok {
+		b.WriteString("value: ")
+		b.WriteString(exportOwnProperties(vm, o))
+	}
+	details := strings.TrimSuffix(b.String(), "\n")
+	if len(details) > maxDetails {
+		details = details[:maxDetails] + "... (truncated, TC39_MAX_DETAILS)"
+	}
+	return details
+}
+
+// exportOwnProperties returns the own string keyed properties of o as JSON, shallowly: the
+// primitives as they are and the objects as their class. The test's code can run through a
+// getter, so anything throwing is replaced by what it threw.
+func exportOwnProperties(vm *goja.Runtime, o *goja.Object) string {
+	var keys []string
+	if err := tryJS(func() {
+		names := vm.Get("Object").ToObject(vm).Get("getOwnPropertyNames")
+		getOwnPropertyNames, ok := goja.AssertFunction(names)
+		if !ok {
+			keys = o.Keys()
+			return
+		}
+		v, err := getOwnPropertyNames(goja.Undefined(), o)
+		if err != nil {
+			panic(err)
+		}
+		if err = vm.ExportTo(v, &keys); err != nil {
+			panic(err)
+		}
+	}); err != nil {
+		return fmt.Sprintf("<listing the properties threw: %v>", err)
+	}
+	props := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		var v goja.Value
+		if err := tryJS(func() { v = o.Get(key) }); err != nil {
+			props[key] = fmt.Sprintf("<the getter threw: %v>", err)
+			continue
+		}
+		props[key] = shallowExport(v)
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(props); err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func shallowExport(v goja.Value) interface{} {
+	if v == nil {
+		return nil
+	}
+	if o, ok := v.(*goja.Object); ok {
+		return "[object " + o.ClassName() + "]"
+	}
+	switch exported := v.Export().(type) {
+	case float64:
+		if math.IsNaN(exported) || math.IsInf(exported, 0) {
+			return v.String()
+		}
+		return exported
+	case nil, bool, int64, string:
+		return exported
+	default:
+		// symbols and whatever else JSON has nothing for
+		return v.String()
+	}
+}
+
+// tryJS calls fn, returning what JS threw as an error. Outside of a call from JS goja panics
+// with the thrown value itself.
+func tryJS(fn func()) (err error) {
+	defer func() {
+		switch x := recover().(type) {
+		case nil:
+		case *goja.Exception:
+			err = x
+		case goja.Value:
+			err = thrownValue{x}
+		default:
+			panic(x)
+		}
+	}()
+	fn()
+	return nil
+}
+
+type thrownValue struct {
+	v goja.Value
+}
+
+func (e thrownValue) Error() (s string) {
+	// converting it to a string can throw too
+	if err := tryJS(func() { s = e.v.String() }); err != nil {
+		if o, ok := e.v.(*goja.Object); ok {
+			return "[object " + o.ClassName() + "]"
+		}
+		return "<unprintable>"
+	}
+	return s
+}
+
+func TestErrorDetails(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	tb := &tc39CountingTB{TB: t}
+	ctx.runTC39Test(tb, "test/details.js", `function thrower() {
+  var e = new Test262Error("boom");
+  e.code = 42;
+  e.nested = {a: 1};
+  e.list = [1, 2];
+  e.nan = NaN;
+  Object.defineProperty(e, "bad", {get: function() { throw new TypeError("no"); }, enumerable: true});
+  throw e;
+}
+thrower();`, &tc39Meta{}, false)
+	result := ctx.results.resultsCopy()["test/details.js-strict:false"]
+	require.Equal(t, CategoryNewFailure, result.Category)
+	require.NotContains(t, result.Message, "stack:", "the details aren't part of what's compared")
+	require.Equal(t, "stack:\n"+
+		"\tat thrower (test/details.js:8:9(40))\n"+
+		"\tat test/details.js:10:8(6)\n"+
+		`value: {"bad":"<the getter threw: TypeError: no>",`+
+		`"code":42,"list":"[object Array]","message":"boom","nan":"NaN","nested":"[object Object]"}`, result.Details)
+	require.Equal(t, 1, tb.errors)
+
+	// a thrown primitive has no properties
+	ctx.runTC39Test(tb, "test/primitive.js", `throw 1;`, &tc39Meta{}, false)
+	require.Equal(t, "stack:\n\tat test/primitive.js:1:7(1)",
+		ctx.results.resultsCopy()["test/primitive.js-strict:false"].Details)
+
+	// only failures have them
+	ctx.runTC39Test(tb, "test/negative.js", `null.x;`, &tc39Meta{Negative: TC39MetaNegative{Phase: "runtime", Type: "TypeError"}}, false)
+	require.Empty(t, ctx.results.resultsCopy()["test/negative.js-strict:false"].Details)
+
+	defer func(old int) { maxDetails = old }(maxDetails)
+	maxDetails = 10
+	ctx.runTC39Test(tb, "test/long.js", `throw new Error("x")`, &tc39Meta{}, false)
+	require.Equal(t, "stack:\n\tat... (truncated, TC39_MAX_DETAILS)",
+		ctx.results.resultsCopy()["test/long.js-strict:false"].Details)
+}
@@ -0,0 +1,156 @@
+package test262
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// tc39DirConfigFile configures directories of the test tree, keyed by their slash separated
+// path, the same one the names of their tests start with.
+const tc39DirConfigFile = "./directories.yml"
+
+type tc39DirConfig struct {
+	// Deadline is how long all the tests below the directory may take together, the ones that
+	// would start after it was exceeded are skipped.
+	Deadline time.Duration `yaml:"deadline"`
+	// Select is include or exclude, to run the tests below the directory or not, see
+	// tc39DirSelection.
+	Select string `yaml:"select"`
+}
+
+func loadDirConfigs(name string) (map[string]tc39DirConfig, error) {
+	b, err := ioutil.ReadFile(name) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	var configs map[string]tc39DirConfig
+	if err = yaml.Unmarshal(b, &configs); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return configs, nil
+}
+
+// tc39Deadlines tracks the time spent below every directory with a deadline. Tests running in
+// parallel all count, so it's the time spent by all workers together.
+type tc39Deadlines struct {
+	deadlines map[string]time.Duration
+
+	mu      sync.Mutex
+	spent   map[string]time.Duration
+	skipped map[string]int
+}
+
+func newDeadlines(configs map[string]tc39DirConfig) *tc39Deadlines {
+	d := &tc39Deadlines{
+		deadlines: make(map[string]time.Duration),
+		spent:     make(map[string]time.Duration),
+		skipped:   make(map[string]int),
+	}
+	for dir, config := range configs {
+		if config.Deadline > 0 {
+			d.deadlines[slashPath(dir)] = config.Deadline
+		}
+	}
+	return d
+}
+
+// dir returns the closest directory with a deadline the test is in, if there is one.
+func (d *tc39Deadlines) dir(name string) (string, bool) {
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if _, ok := d.deadlines[dir]; ok {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// start checks if the test may still run. If it may, the returned function has to be called
+// once it's done to account for the time it took.
+func (d *tc39Deadlines) start(name string) (done func(), err error) {
+	dir, ok := d.dir(name)
+	if !ok {
+		return func() {}, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.spent[dir] >= d.deadlines[dir] {
+		d.skipped[dir]++
+		return nil, fmt.Errorf("directory deadline exceeded: %s took more than %s", dir, d.deadlines[dir])
+	}
+	startTime := time.Now()
+	return func() {
+		d.mu.Lock()
+		d.spent[dir] += time.Since(startTime)
+		d.mu.Unlock()
+	}, nil
+}
+
+// summary returns a line for every directory whose deadline made tests be skipped.
+func (d *tc39Deadlines) summary() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lines := make([]string, 0, len(d.skipped))
+	for dir, n := range d.skipped {
+		lines = append(lines, fmt.Sprintf("directory deadline exceeded (%s): %s, %d tests skipped", d.deadlines[dir], dir, n))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestDirectoryDeadlines(t *testing.T) {
+	d := newDeadlines(map[string]tc39DirConfig{
+		"test/built-ins/RegExp":                  {Deadline: time.Hour},
+		"test/built-ins/RegExp/property-escapes": {Deadline: time.Nanosecond},
+		"test/language":                          {},
+	})
+
+	dir, ok := d.dir("test/built-ins/RegExp/property-escapes/generated/Any.js")
+	require.True(t, ok)
+	require.Equal(t, "test/built-ins/RegExp/property-escapes", dir)
+	dir, ok = d.dir("test/built-ins/RegExp/lastIndex.js")
+	require.True(t, ok)
+	require.Equal(t, "test/built-ins/RegExp", dir)
+	_, ok = d.dir("test/language/asi/S7.9_A1.js")
+	require.False(t, ok)
+
+	done, err := d.start("test/built-ins/RegExp/property-escapes/a.js")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	done()
+	for _, name := range []string{"b.js", "c.js"} {
+		_, err = d.start("test/built-ins/RegExp/property-escapes/" + name)
+		require.Error(t, err)
+	}
+	// the deadline of the parent is separate
+	done, err = d.start("test/built-ins/RegExp/lastIndex.js")
+	require.NoError(t, err)
+	done()
+
+	require.Equal(t, []string{
+		"directory deadline exceeded (1ns): test/built-ins/RegExp/property-escapes, 2 tests skipped",
+	}, d.summary())
+}
+
+func TestLoadDirConfigs(t *testing.T) {
+	_, err := loadDirConfigs(tc39DirConfigFile)
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "directories.yml")
+	require.NoError(t, err)
+	defer os.Remove(f.Name()) //nolint:errcheck
+	_, err = f.WriteString("test/built-ins/RegExp/property-escapes:\n  deadline: 3m\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	configs, err := loadDirConfigs(f.Name())
+	require.NoError(t, err)
+	require.Equal(t, 3*time.Minute, configs["test/built-ins/RegExp/property-escapes"].Deadline)
+}
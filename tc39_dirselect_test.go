@@ -0,0 +1,242 @@
+package test262
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	tc39SelectInclude = "include"
+	tc39SelectExclude = "exclude"
+)
+
+// tc39DirSelection has the directories of the main suite whose tests run and the ones whose tests
+// don't, the select of directories.yml unless TC39_INCLUDE_DIRS or TC39_EXCLUDE_DIRS replace
+// them. The closest selected ancestor of a test decides, and a test without one only runs if no
+// directory is included at all. A nil selection runs every test.
+type tc39DirSelection struct {
+	selects map[string]string
+	// source is where each list came from, for the summary
+	source map[string]string
+	// excluded counts the tests not run by the directory that excluded them, empty for the ones
+	// outside of all the included directories. Only the walking goroutine touches it.
+	excluded map[string]int
+}
+
+func dirSelectionFromEnv(configs map[string]tc39DirConfig) (*tc39DirSelection, error) {
+	return newDirSelection(configs, os.Getenv("TC39_INCLUDE_DIRS"), os.Getenv("TC39_EXCLUDE_DIRS"))
+}
+
+// newDirSelection selects the directories of the configs, with include and exclude, comma
+// separated lists of directories, replacing their included and excluded directories if set.
+func newDirSelection(configs map[string]tc39DirConfig, include, exclude string) (*tc39DirSelection, error) {
+	d := &tc39DirSelection{
+		selects:  make(map[string]string),
+		source:   map[string]string{tc39SelectInclude: tc39DirConfigFile, tc39SelectExclude: tc39DirConfigFile},
+		excluded: make(map[string]int),
+	}
+	lists := map[string][]string{}
+	for dir, config := range configs {
+		switch config.Select {
+		case "":
+		case tc39SelectInclude, tc39SelectExclude:
+			lists[config.Select] = append(lists[config.Select], dir)
+		default:
+			return nil, fmt.Errorf("%s: %s has the select %q, it has to be include or exclude", tc39DirConfigFile, dir, config.Select)
+		}
+	}
+	for selection, env := range map[string]struct{ name, value string }{
+		tc39SelectInclude: {"TC39_INCLUDE_DIRS", include},
+		tc39SelectExclude: {"TC39_EXCLUDE_DIRS", exclude},
+	} {
+		if env.value == "" {
+			continue
+		}
+		lists[selection], d.source[selection] = nil, env.name
+		for _, dir := range strings.Split(env.value, ",") {
+			if dir = strings.TrimSpace(dir); dir == "" {
+				return nil, fmt.Errorf("%s must be a comma separated list of directories, got %q", env.name, env.value)
+			}
+			lists[selection] = append(lists[selection], dir)
+		}
+	}
+	for selection, dirs := range lists {
+		for _, dir := range dirs {
+			dir = path.Clean(slashPath(dir))
+			if other, ok := d.selects[dir]; ok && other != selection {
+				return nil, fmt.Errorf("%s is both included and excluded", dir)
+			}
+			d.selects[dir] = selection
+		}
+	}
+	return d, nil
+}
+
+// selection returns the closest selected ancestor of the test and its select, empty if it has none.
+func (d *tc39DirSelection) selection(name string) (string, string) {
+	for dir := path.Dir(slashPath(name)); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if selection, ok := d.selects[dir]; ok {
+			return dir, selection
+		}
+	}
+	return "", ""
+}
+
+// has tells if the test runs.
+func (d *tc39DirSelection) has(name string) bool {
+	if d == nil {
+		return true
+	}
+	_, selection := d.selection(name)
+	if selection == "" {
+		for _, other := range d.selects {
+			if other == tc39SelectInclude {
+				return false
+			}
+		}
+		return true
+	}
+	return selection == tc39SelectInclude
+}
+
+// take tells if the test runs, counting it by the directory that excluded it if it doesn't.
+func (d *tc39DirSelection) take(name string) bool {
+	if d.has(name) {
+		return true
+	}
+	dir, _ := d.selection(name)
+	d.excluded[dir]++
+	return false
+}
+
+// expected returns the expected errors of the variants of the tests that run, the others aren't
+// compared with anything.
+func (d *tc39DirSelection) expected(errs map[string]string) map[string]string {
+	if d == nil {
+		return errs
+	}
+	own := make(map[string]string)
+	for nameKey, errStr := range errs {
+		if k, err := parseLegacyKey(nameKey, nil); err == nil && d.has(k.Test) {
+			own[nameKey] = errStr
+		}
+	}
+	return own
+}
+
+// summary has a line for every excluded directory, and one for the tests outside of the included
+// ones, with how many tests they didn't run.
+func (d *tc39DirSelection) summary() []string {
+	if d == nil {
+		return nil
+	}
+	lines := make([]string, 0, len(d.excluded))
+	for dir, n := range d.excluded {
+		if dir == "" {
+			lines = append(lines, fmt.Sprintf("%d tests not run, outside of the directories included in %s", n, d.source[tc39SelectInclude]))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d tests not run, below %s excluded in %s", n, dir, d.source[tc39SelectExclude]))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestDirSelection(t *testing.T) {
+	configs, err := loadDirConfigs(tc39DirConfigFile)
+	require.NoError(t, err)
+	d, err := newDirSelection(configs, "", "")
+	require.NoError(t, err)
+	for name, ok := range map[string]bool{
+		"test/language/asi/S7.9_A1.js":                          true,
+		"test/built-ins/Array/length.js":                        true,
+		"test/annexB/built-ins/escape/name.js":                  true,
+		"test/annexB/built-ins/Date/prototype/getYear/B.2.4.js": false,
+		"test/annexB/language/comments/a.js":                    false,
+		"test/intl402/Collator/builtin.js":                      false,
+		"test/staging/a.js":                                     false,
+		"test/harness/compare-array.js":                         false,
+	} {
+		require.Equal(t, ok, d.has(name), name)
+	}
+
+	// the closest selected ancestor decides
+	d, err = newDirSelection(map[string]tc39DirConfig{
+		"test/a":     {Select: tc39SelectInclude},
+		"test/a/b":   {Select: tc39SelectExclude},
+		"test/a/b/c": {Select: tc39SelectInclude},
+		"test/d":     {Deadline: 1},
+	}, "", "")
+	require.NoError(t, err)
+	for name, ok := range map[string]bool{
+		"test/a/x.js": true, "test/a/b/x.js": false, "test/a/b/e/x.js": false, "test/a/b/c/x.js": true,
+		"test/d/x.js": false, "test/x.js": false,
+	} {
+		require.Equal(t, ok, d.has(name), name)
+	}
+	// the environment replaces a list
+	d, err = newDirSelection(map[string]tc39DirConfig{
+		"test/a": {Select: tc39SelectInclude}, "test/b": {Select: tc39SelectExclude},
+	}, "", `test\c/, test/c/d`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"test/a": tc39SelectInclude, "test/c": tc39SelectExclude, "test/c/d": tc39SelectExclude}, d.selects)
+	// and without any included directory all the others run
+	d, err = newDirSelection(map[string]tc39DirConfig{"test/b": {Select: tc39SelectExclude}}, "", "")
+	require.NoError(t, err)
+	require.True(t, d.has("test/a/x.js"))
+	require.False(t, d.has("test/b/x.js"))
+	require.True(t, (*tc39DirSelection)(nil).has("test/b/x.js"))
+
+	for _, c := range []struct {
+		configs               map[string]tc39DirConfig
+		include, exclude, err string
+	}{
+		{map[string]tc39DirConfig{"test/a": {Select: "skip"}}, "", "", `test/a has the select "skip", it has to be include or exclude`},
+		{map[string]tc39DirConfig{"test/a": {Select: tc39SelectInclude}}, "", "test/a", "test/a is both included and excluded"},
+		{nil, "test/a,", "", `TC39_INCLUDE_DIRS must be a comma separated list of directories, got "test/a,"`},
+	} {
+		_, err = newDirSelection(c.configs, c.include, c.exclude)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), c.err)
+	}
+
+	// a synthetic tree, whose excluded tests are counted
+	dir, err := ioutil.TempDir("", "tc39-dirs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	for _, name := range []string{
+		"test/language/a.js", "test/language/b.js", "test/intl402/a.js", "test/intl402/b/c.js",
+		"test/harness/a.js", "test/annexB/built-ins/escape/a.js", "test/annexB/language/a.js",
+	} {
+		file := osPath(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		require.NoError(t, ioutil.WriteFile(file, nil, 0o644))
+	}
+	var out bytes.Buffer
+	ctx := newFixtureCtx(t)
+	ctx.base, ctx.opts.Out, ctx.dryRun = dir, &out, true
+	ctx.dirs, err = newDirSelection(map[string]tc39DirConfig{
+		"test/language":                {Select: tc39SelectInclude},
+		"test/annexB/built-ins/escape": {Select: tc39SelectInclude},
+		"test/intl402":                 {Select: tc39SelectExclude},
+	}, "", "")
+	require.NoError(t, err)
+	ctx.runTC39Tests("test")
+	require.Equal(t, "test/annexB/built-ins/escape/a.js\ntest/language/a.js\ntest/language/b.js\n", out.String())
+	require.Equal(t, []string{
+		"2 tests not run, below test/intl402 excluded in " + tc39DirConfigFile,
+		"2 tests not run, outside of the directories included in " + tc39DirConfigFile,
+	}, ctx.dirs.summary())
+	require.Equal(t, map[string]string{"test/language/a.js-strict:false": "boom"}, ctx.dirs.expected(map[string]string{
+		"test/language/a.js-strict:false": "boom", "test/intl402/a.js-strict:false": "boom",
+	}))
+}
@@ -0,0 +1,189 @@
+package test262
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Dump writes what's needed to look into every unexpected failure to TC39_DUMP_DIR, the source
+// of the test, the code the k6 compiler turned it into and the error, in files named after the test
+// and its variant. A nil dump writes nothing.
+type tc39Dump struct {
+	dir string
+
+	mu     sync.Mutex
+	dumped int
+	// errs has why the failures that couldn't be dumped weren't, by variant key
+	errs map[string]error
+}
+
+func dumpFromEnv() (*tc39Dump, error) {
+	dir := os.Getenv("TC39_DUMP_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	return newDump(dir)
+}
+
+func newDump(dir string) (*tc39Dump, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("TC39_DUMP_DIR can't be created: %w", err)
+	}
+	if err := checkWritableDir(dir); err != nil {
+		return nil, fmt.Errorf("TC39_DUMP_DIR is not writable: %w", err)
+	}
+	return &tc39Dump{dir: dir, errs: make(map[string]error)}, nil
+}
+
+// prefix returns the path of the files of the variant without their extensions, an error if the
+// name of the test would put them outside of the dump directory.
+func (d *tc39Dump) prefix(name string, strict bool, mode lib.CompatibilityMode) (string, error) {
+	rel := slashPath(name)
+	if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") || filepath.VolumeName(filepath.FromSlash(rel)) != "" {
+		return "", fmt.Errorf("%s would be dumped outside of TC39_DUMP_DIR", name)
+	}
+	variant := "sloppy"
+	if strict {
+		variant = "strict"
+	}
+	if mode.String() != tc39DefaultMode {
+		variant += "-" + mode.String()
+	}
+	return osPath(d.dir, rel+"."+variant), nil
+}
+
+// write dumps the failure of a variant, code being empty if it didn't compile.
+func (d *tc39Dump) write(name string, strict bool, mode lib.CompatibilityMode, src, code, message string) {
+	if d == nil {
+		return
+	}
+	err := d.writeFiles(name, strict, mode, map[string]string{
+		".source.js": src, ".transformed.js": code, ".error.txt": message + "\n",
+	})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		d.errs[modeKey(name, strict, mode)] = err
+		return
+	}
+	d.dumped++
+}
+
+func (d *tc39Dump) writeFiles(name string, strict bool, mode lib.CompatibilityMode, files map[string]string) error {
+	prefix, err := d.prefix(name, strict, mode)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(prefix), 0o755); err != nil {
+		return err
+	}
+	for ext, content := range files {
+		if content == "" {
+			continue
+		}
+		err = writeArtifact(prefix+ext, func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *tc39Dump) summary() string {
+	if d == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	line := fmt.Sprintf("dumped %d unexpected failures to TC39_DUMP_DIR %s", d.dumped, d.dir)
+	if len(d.errs) > 0 {
+		keys := make([]string, 0, len(d.errs))
+		for key := range d.errs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		line += fmt.Sprintf(", %d couldn't be dumped:", len(keys))
+		for _, key := range keys {
+			line += fmt.Sprintf("\n%s: %v", key, d.errs[key])
+		}
+	}
+	return line
+}
+
+func TestDumpFailures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-dump")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	ctx := newFixtureCtx(t)
+	ctx.dump, err = newDump(dir)
+	require.NoError(t, err)
+	tb := &tc39CountingTB{TB: t}
+	for _, name := range []string{"test/dump/fail.js", "test/compat/es5.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, tb)
+		})
+	}
+	require.Equal(t, 2, tb.errors)
+
+	src, err := readTC39Source(osPath(tc39FixturesBase, "test/dump/fail.js"))
+	require.NoError(t, err)
+	for _, variant := range []string{"sloppy", "strict"} {
+		prefix := filepath.Join(dir, "test", "dump", "fail.js."+variant)
+		b, err := ioutil.ReadFile(prefix + ".source.js") //nolint:gosec
+		require.NoError(t, err)
+		require.Equal(t, src, string(b))
+		b, err = ioutil.ReadFile(prefix + ".transformed.js") //nolint:gosec
+		require.NoError(t, err)
+		require.NotContains(t, string(b), "`", "Babel transformed the template literal")
+		b, err = ioutil.ReadFile(prefix + ".error.txt") //nolint:gosec
+		require.NoError(t, err)
+		require.Contains(t, string(b), "Expected SameValue(«a1», «b») to be true")
+	}
+	// the passing test isn't dumped
+	files, err := filepath.Glob(filepath.Join(dir, "test", "*", "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 6)
+	require.Equal(t, "dumped 2 unexpected failures to TC39_DUMP_DIR "+dir, ctx.dump.summary())
+
+	// nor is an expected failure
+	expected := ctx.results.errorsCopy()
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "test")))
+	ctx = newFixtureCtx(t)
+	ctx.expectedErrors = expected
+	ctx.dump, err = newDump(dir)
+	require.NoError(t, err)
+	t.Run("test/dump/fail.js", func(t *testing.T) {
+		ctx.runTC39File("test/dump/fail.js", "test/dump/fail.js", t)
+	})
+	files, err = filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	// and a name can't write outside of the directory
+	for _, name := range []string{"../escape.js", "test/../../other.js", "/tmp/escape.js"} {
+		ctx.dump.write(name, false, lib.CompatibilityModeExtended, "src", "", "boom")
+		_, err := os.Stat(osPath(filepath.Dir(dir), path.Base(name)+".sloppy.source.js"))
+		require.True(t, os.IsNotExist(err), name)
+	}
+	require.Equal(t, "dumped 0 unexpected failures to TC39_DUMP_DIR "+dir+", 3 couldn't be dumped:\n"+
+		"../escape.js-strict:false: ../escape.js would be dumped outside of TC39_DUMP_DIR\n"+
+		"../other.js-strict:false: test/../../other.js would be dumped outside of TC39_DUMP_DIR\n"+
+		"/tmp/escape.js-strict:false: /tmp/escape.js would be dumped outside of TC39_DUMP_DIR",
+		ctx.dump.summary())
+}
@@ -0,0 +1,331 @@
+package test262
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The kinds of failure an expected error can have as its category.
+const (
+	// kindCompile is an error before the test body started running, compiling, transforming or
+	// resolving it.
+	kindCompile = "compile"
+	kindRuntime = "runtime"
+	kindPanic   = "panic"
+	kindTimeout = "timeout"
+)
+
+// tc39SinceLayout is the format of the day an expected error was first seen.
+const tc39SinceLayout = "2006-01-02"
+
+// tc39ExpectedEntry is an expected error with what's known about it. Only Error is compared, the
+// rest rides along. An entry without anything else is written as the error by itself, the flat
+// format of the baselines from before the entries, which is read as well.
+type tc39ExpectedEntry struct {
+	Error string `json:"error"`
+	// Category is the kind of the failure, one of kindCompile, kindRuntime, kindPanic and kindTimeout.
+	Category string `json:"category,omitempty"`
+	// Since is the day it was added, in tc39SinceLayout.
+	Since string `json:"since,omitempty"`
+	// Issue is the link to the issue of goja, Babel or core-js about it.
+	Issue string `json:"issue,omitempty"`
+}
+
+type tc39ExpectedEntryObject tc39ExpectedEntry
+
+func (e tc39ExpectedEntry) flat() bool {
+	return e.Category == "" && e.Since == "" && e.Issue == ""
+}
+
+// MarshalJSON writes an entry with nothing more than its error as the error.
+func (e tc39ExpectedEntry) MarshalJSON() ([]byte, error) {
+	if e.flat() {
+		return json.Marshal(e.Error)
+	}
+	return json.Marshal(tc39ExpectedEntryObject(e))
+}
+
+// UnmarshalJSON reads both an error by itself and an object.
+func (e *tc39ExpectedEntry) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case len(b) > 0 && b[0] == '"':
+		*e = tc39ExpectedEntry{}
+		return json.Unmarshal(b, &e.Error)
+	case len(b) > 0 && b[0] == '{':
+		return json.Unmarshal(b, (*tc39ExpectedEntryObject)(e))
+	default:
+		// the offsets of the errors in here would be the ones in b, not in the file
+		return fmt.Errorf("an expected error is either a string or an object, not %s", b)
+	}
+}
+
+// check returns what's wrong with the fields of the entry of the variant.
+func (e tc39ExpectedEntry) check(nameKey string) error {
+	switch e.Category {
+	case "", kindCompile, kindRuntime, kindPanic, kindTimeout:
+	default:
+		return fmt.Errorf("the category of %s is %q, not one of %s, %s, %s and %s",
+			nameKey, e.Category, kindCompile, kindRuntime, kindPanic, kindTimeout)
+	}
+	if _, err := time.Parse(tc39SinceLayout, e.Since); e.Since != "" && err != nil {
+		return fmt.Errorf("the since of %s isn't a day like %s: %w", nameKey, tc39SinceLayout, err)
+	}
+	return nil
+}
+
+// failureKind returns the category of a failure for its entry.
+func failureKind(result TestResult) string {
+	switch {
+	case result.Category == CategoryPanic:
+		return kindPanic
+	case result.Category == CategoryTimeout:
+		return kindTimeout
+	case result.Early:
+		return kindCompile
+	default:
+		return kindRuntime
+	}
+}
+
+// tc39JSONContext is how many bytes around the offset of an invalid JSON its error quotes.
+const tc39JSONContext = 30
+
+// jsonError tells where in b the JSON is invalid, with the text around it, as the offsets of the
+// decoder alone are hard to find in a file of thousands of lines.
+func jsonError(b []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	offset := syntaxErr.Offset
+	from, to := int(offset)-tc39JSONContext, int(offset)+tc39JSONContext
+	if from < 0 {
+		from = 0
+	}
+	if to > len(b) {
+		to = len(b)
+	}
+	return fmt.Errorf("invalid JSON at byte %d, near %q: %w", offset, b[from:to], err)
+}
+
+// readExpectedEntries reads a baseline in either format, a map of the entries or errors by
+// themselves, or a versioned document, whose records only have the errors. An empty one, like a
+// file just created, has none.
+func readExpectedEntries(b []byte) (map[string]tc39ExpectedEntry, error) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return map[string]tc39ExpectedEntry{}, nil
+	}
+	var probe struct {
+		SchemaVersion json.RawMessage `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, jsonError(b, err)
+	}
+	if probe.SchemaVersion != nil {
+		errs, err := readVersionedErrors(b)
+		return entriesOf(errs), err
+	}
+	entries := make(map[string]tc39ExpectedEntry, 1000)
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, jsonError(b, err)
+	}
+	keys := make([]string, 0, len(entries))
+	for nameKey := range entries {
+		keys = append(keys, nameKey)
+	}
+	sort.Strings(keys)
+	for _, nameKey := range keys {
+		if err := entries[nameKey].check(nameKey); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// entriesOf returns the entries of errors without anything more known about them.
+func entriesOf(errs map[string]string) map[string]tc39ExpectedEntry {
+	if errs == nil {
+		return nil
+	}
+	entries := make(map[string]tc39ExpectedEntry, len(errs))
+	for nameKey, errStr := range errs {
+		entries[nameKey] = tc39ExpectedEntry{Error: errStr}
+	}
+	return entries
+}
+
+// errorsOf returns the errors of the entries, what's compared.
+func errorsOf(entries map[string]tc39ExpectedEntry) map[string]string {
+	errs := make(map[string]string, len(entries))
+	for nameKey, e := range entries {
+		errs[nameKey] = e.Error
+	}
+	return errs
+}
+
+func writeExpectedEntries(name string, entries map[string]tc39ExpectedEntry) error {
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeArtifact(name, func(w io.Writer) error {
+		_, err := w.Write(append(b, '\n'))
+		return err
+	})
+}
+
+func writeExpectedErrors(name string, errs map[string]string) error {
+	return writeExpectedEntries(name, entriesOf(errs))
+}
+
+// expectedSummary breaks the expected errors the run compared with down by category, if any of
+// them has one, and lists the first max of the ones older than maxAge, none with a maxAge of 0.
+func (ctx *tc39TestCtx) expectedSummary(now time.Time, maxAge time.Duration, max int) []string {
+	counts := make(map[string]int)
+	var old []string
+	for nameKey := range ctx.expectedErrors {
+		e := ctx.expectedEntries[nameKey]
+		counts[e.Category]++
+		since, err := time.Parse(tc39SinceLayout, e.Since)
+		if maxAge > 0 && err == nil && now.Sub(since) > maxAge {
+			line := fmt.Sprintf("since %s: %s", e.Since, nameKey)
+			if e.Issue != "" {
+				line += " " + e.Issue
+			}
+			old = append(old, line)
+		}
+	}
+	var lines []string
+	if counts[""] < len(ctx.expectedErrors) {
+		lines = append(lines, fmt.Sprintf("%d expected errors, %d at %s, %d at %s, %d %ss, %d %ss and %d without a category",
+			len(ctx.expectedErrors), counts[kindCompile], kindCompile, counts[kindRuntime], kindRuntime,
+			counts[kindPanic], kindPanic, counts[kindTimeout], kindTimeout, counts[""]))
+	}
+	if len(old) > 0 {
+		sort.Strings(old)
+		lines = append(lines, fmt.Sprintf("%d expected errors are older than %d days (TC39_EXPECTED_MAX_AGE)",
+			len(old), int(maxAge/(24*time.Hour))))
+		if len(old) > max {
+			old = append(old[:max], fmt.Sprintf("and %d more", len(old)-max))
+		}
+		lines = append(lines, old...)
+	}
+	return lines
+}
+
+func expectedMaxAgeFromEnv() (time.Duration, error) {
+	v := os.Getenv("TC39_EXPECTED_MAX_AGE")
+	if v == "" {
+		return 0, nil
+	}
+	days, err := strconv.Atoi(v)
+	if err != nil || days <= 0 {
+		return 0, fmt.Errorf("TC39_EXPECTED_MAX_AGE must be a positive number of days, got %q", v)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+func TestExpectedEntries(t *testing.T) {
+	src := `{
+  "test/a.js-strict:false": "test/a.js: Test262Error: flat",
+  "test/b.js-strict:true": {
+    "error": "test/b.js: SyntaxError: b",
+    "category": "compile",
+    "since": "2020-01-02",
+    "issue": "https://github.com/dop251/goja/issues/1"
+  },
+  "test/c.js-strict:true": {
+    "error": "test/c.js: c"
+  }
+}
+`
+	entries, err := readExpectedEntries([]byte(src))
+	require.NoError(t, err)
+	require.Equal(t, map[string]tc39ExpectedEntry{
+		"test/a.js-strict:false": {Error: "test/a.js: Test262Error: flat"},
+		"test/b.js-strict:true": {
+			Error: "test/b.js: SyntaxError: b", Category: kindCompile, Since: "2020-01-02",
+			Issue: "https://github.com/dop251/goja/issues/1",
+		},
+		"test/c.js-strict:true": {Error: "test/c.js: c"},
+	}, entries)
+	errs, err := readExpectedErrors([]byte(src))
+	require.NoError(t, err)
+	require.Equal(t, errorsOf(entries), errs)
+
+	// written, an entry without more than its error is flat and the others keep their fields
+	dir, err := ioutil.TempDir("", "tc39-entries")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, "entries.json")
+	require.NoError(t, writeExpectedEntries(file, entries))
+	b, err := ioutil.ReadFile(file) //nolint:gosec
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(src, "{\n    \"error\": \"test/c.js: c\"\n  }", "\"test/c.js: c\"", 1), string(b))
+	read, err := readExpectedEntries(b)
+	require.NoError(t, err)
+	require.Equal(t, entries, read)
+
+	for entry, msg := range map[string]string{
+		`{"error": "x", "category": "crash"}`: `the category of test/a.js-strict:false is "crash", not one of compile, runtime, panic and timeout`,
+		`{"error": "x", "since": "yesterday"}`: `the since of test/a.js-strict:false isn't a day like 2006-01-02: ` +
+			`parsing time "yesterday" as "2006-01-02": cannot parse "yesterday" as "2006"`,
+	} {
+		_, err = readExpectedEntries([]byte(`{"test/a.js-strict:false": ` + entry + `}`))
+		require.EqualError(t, err, msg)
+	}
+
+	for kind, result := range map[string]TestResult{
+		kindCompile: {Category: CategoryNewFailure, Early: true},
+		kindRuntime: {Category: CategoryChangedFailure},
+		kindPanic:   {Category: CategoryPanic, Early: true},
+		kindTimeout: {Category: CategoryTimeout},
+	} {
+		require.Equal(t, kind, failureKind(result))
+	}
+}
+
+func TestExpectedSummary(t *testing.T) {
+	ctx := &tc39TestCtx{expectedEntries: map[string]tc39ExpectedEntry{
+		"test/a.js-strict:false": {Error: "a"},
+		"test/b.js-strict:false": {Error: "b", Category: kindCompile, Since: "2020-01-01", Issue: "https://github.com/babel/babel/issues/2"},
+		"test/c.js-strict:false": {Error: "c", Category: kindRuntime, Since: "2020-03-01"},
+		"test/d.js-strict:false": {Error: "d", Category: kindRuntime, Since: "2020-03-30"},
+		// not compared with, as out of the shard
+		"test/e.js-strict:false": {Error: "e", Category: kindPanic, Since: "2019-01-01"},
+	}}
+	ctx.expectedErrors = errorsOf(ctx.expectedEntries)
+	delete(ctx.expectedErrors, "test/e.js-strict:false")
+	now := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, []string{
+		"4 expected errors, 1 at compile, 2 at runtime, 0 panics, 0 timeouts and 1 without a category",
+		"2 expected errors are older than 30 days (TC39_EXPECTED_MAX_AGE)",
+		"since 2020-01-01: test/b.js-strict:false https://github.com/babel/babel/issues/2",
+		"since 2020-03-01: test/c.js-strict:false",
+	}, ctx.expectedSummary(now, 30*24*time.Hour, 5))
+	require.Equal(t, []string{
+		"4 expected errors, 1 at compile, 2 at runtime, 0 panics, 0 timeouts and 1 without a category",
+		"3 expected errors are older than 2 days (TC39_EXPECTED_MAX_AGE)",
+		"since 2020-01-01: test/b.js-strict:false https://github.com/babel/babel/issues/2",
+		"and 2 more",
+	}, ctx.expectedSummary(now, 2*24*time.Hour, 1))
+
+	// without any category nor a maximum age there is nothing to tell
+	ctx = &tc39TestCtx{expectedErrors: map[string]string{"test/a.js-strict:false": "a"}}
+	ctx.expectedEntries = entriesOf(ctx.expectedErrors)
+	require.Empty(t, ctx.expectedSummary(now, 0, 5))
+}
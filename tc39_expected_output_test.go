@@ -0,0 +1,98 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39ExpectedOutputExt is the extension of the file next to a test with what it has to print,
+// foo.expected for foo.js.
+const tc39ExpectedOutputExt = ".expected"
+
+// expectedOutput reads the expected output of the test in file, nil if it has none.
+func expectedOutput(file string) (*string, error) {
+	b, err := readFile(strings.TrimSuffix(file, ".js") + tc39ExpectedOutputExt)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	return &s, nil
+}
+
+// outputLines splits the expected output in the lines print is expected to have been called
+// with, the newline at the end of the file not being one.
+func outputLines(output string) []string {
+	output = strings.TrimSuffix(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// outputDiff compares what a test printed with what it was expected to, line by line, empty
+// when they're the same.
+func outputDiff(expected string, got []string) string {
+	want := outputLines(expected)
+	var diffs []string
+	for i := 0; i < len(want) || i < len(got); i++ {
+		switch {
+		case i >= len(got):
+			diffs = append(diffs, fmt.Sprintf("line %d: expected %q, got nothing", i+1, want[i]))
+		case i >= len(want):
+			diffs = append(diffs, fmt.Sprintf("line %d: expected nothing, got %q", i+1, got[i]))
+		case want[i] != got[i]:
+			diffs = append(diffs, fmt.Sprintf("line %d: expected %q, got %q", i+1, want[i], got[i]))
+		}
+	}
+	return strings.Join(diffs, "\n")
+}
+
+func TestExpectedOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-output")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	root := filepath.Join(dir, "golden")
+	require.NoError(t, os.Mkdir(root, 0o755))
+	for name, content := range map[string]string{
+		"sidecar.js":        "/*---\ndescription: d\n---*/\nprint('a', 1); print('b');\n",
+		"sidecar.expected":  "a 1\r\nb\n",
+		"frontmatter.js":    "/*---\noutput: |\n  a\n  b\n---*/\nprint('a'); print('c'); print('d');\n",
+		"both.js":           "/*---\noutput: from the frontmatter\n---*/\nprint('from the sidecar');\n",
+		"both.expected":     "from the sidecar\n",
+		"unchecked.js":      "/*---\ndescription: d\n---*/\nprint('anything');\n",
+		"prints-nothing.js": "/*---\noutput: ''\n---*/\nprint('something');\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0o644))
+	}
+	ctx := newFixtureCtx(t)
+	ctx.extraSuites, err = extraSuites(ctx.base, root)
+	require.NoError(t, err)
+	tb := &tc39CountingTB{TB: t}
+	for _, name := range []string{"sidecar.js", "frontmatter.js", "both.js", "unchecked.js", "prints-nothing.js"} {
+		ctx.runTC39File("golden/"+name, name, tb)
+	}
+
+	errs := ctx.results.errorsCopy()
+	require.Len(t, errs, 4)
+	require.Contains(t, errs["golden/frontmatter.js-strict:false"], "unexpected output:\n"+
+		"line 2: expected \"b\", got \"c\"\nline 3: expected nothing, got \"d\"")
+	require.Contains(t, errs["golden/prints-nothing.js-strict:true"],
+		"line 1: expected nothing, got \"something\"")
+	require.Equal(t, 4, tb.errors)
+
+	meta, _, err := parseTC39File(filepath.Join(root, "frontmatter.js"), root)
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\n", *meta.Output)
+	require.Empty(t, outputDiff("a\nb\n", []string{"a", "b"}))
+	require.Equal(t, "line 2: expected \"b\", got nothing", outputDiff("a\nb", []string{"a"}))
+}
@@ -0,0 +1,198 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tc39DefaultMaxFileSize = 16 << 20
+
+// slashPath canonicalizes a path to forward slashes. Every test name, cache key, baseline key and
+// path in a report goes through it, so they are the same regardless of the OS the suite ran on.
+// Backslashes are always treated as separators as no test262 file has one in its name.
+func slashPath(p string) string {
+	return path.Clean(strings.ReplaceAll(filepath.ToSlash(p), "\\", "/"))
+}
+
+// osPath returns the path to access the file with the slash separated name below base.
+func osPath(base, name string) string {
+	return filepath.Join(base, filepath.FromSlash(name))
+}
+
+// maxFileSize is the most a single test or harness file is allowed to have, so a corrupted
+// checkout doesn't exhaust the memory before running a single test.
+var maxFileSize int64 = tc39DefaultMaxFileSize //nolint:gochecknoglobals
+
+// fileTooLargeError is an infrastructure error, no legitimate test262 file comes close to the limit.
+type fileTooLargeError struct {
+	name        string
+	size, limit int64
+}
+
+func (e *fileTooLargeError) Error() string {
+	return fmt.Sprintf("%s is %d bytes, more than the limit of %d bytes (TC39_MAX_FILE_SIZE)",
+		slashPath(e.name), e.size, e.limit)
+}
+
+func maxFileSizeFromEnv() (int64, error) {
+	v := os.Getenv("TC39_MAX_FILE_SIZE")
+	if v == "" {
+		return tc39DefaultMaxFileSize, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("TC39_MAX_FILE_SIZE must be a positive number of bytes, got %q", v)
+	}
+	return n, nil
+}
+
+// readFile reads the whole file, but not more than maxFileSize bytes of it.
+func readFile(name string) ([]byte, error) {
+	release := openFiles.acquire()
+	defer release()
+	f, err := os.Open(name) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck,gosec
+
+	b, err := ioutil.ReadAll(io.LimitReader(f, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > maxFileSize {
+		size := int64(len(b))
+		if fi, err := f.Stat(); err == nil {
+			size = fi.Size()
+		}
+		return nil, &fileTooLargeError{name: name, size: size, limit: maxFileSize}
+	}
+	return b, nil
+}
+
+// tc39SourceCache holds the content of the harness files, which are needed by most tests. Nothing
+// changes them during a run, so every file is read from disk only once and never invalidated.
+type tc39SourceCache struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	reads int
+}
+
+func newSourceCache() *tc39SourceCache {
+	return &tc39SourceCache{files: make(map[string][]byte)}
+}
+
+// read returns the content of the file with the slash separated name below base.
+func (c *tc39SourceCache) read(base, name string) ([]byte, error) {
+	file := osPath(base, slashPath(name))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.files[file]; ok {
+		return b, nil
+	}
+	b, err := readFile(file)
+	if err != nil {
+		return nil, err
+	}
+	c.reads++
+	c.files[file] = b
+	return b, nil
+}
+
+// diskReads returns how many files were actually read from disk.
+func (c *tc39SourceCache) diskReads() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reads
+}
+
+func TestSourceCache(t *testing.T) {
+	c := newSourceCache()
+	for _, name := range []string{"harness/assert.js", `harness\assert.js`, "harness/./assert.js", "harness/sta.js"} {
+		b, err := c.read(tc39FixturesBase, name)
+		require.NoError(t, err)
+		require.NotEmpty(t, b)
+	}
+	require.Equal(t, 2, c.diskReads())
+
+	_, err := c.read(tc39FixturesBase, "harness/missing.js")
+	require.Error(t, err)
+	require.Equal(t, 2, c.diskReads())
+}
+
+func TestSlashPath(t *testing.T) {
+	tests := map[string]string{
+		filepath.Join("test", "built-ins", "Array", "length.js"): "test/built-ins/Array/length.js",
+		`test\built-ins\Array\length.js`:                         "test/built-ins/Array/length.js",
+		`harness\assert.js`:                                      "harness/assert.js",
+		"test/language/../built-ins/./Math/abs.js":               "test/built-ins/Math/abs.js",
+	}
+	for input, expected := range tests {
+		assert.Equal(t, expected, slashPath(input), input)
+	}
+	assert.Equal(t, "harness/sta.js", slashPath(osPath("", path.Join("harness", "sta.js"))))
+}
+
+func TestMaxFileSize(t *testing.T) {
+	require.NoError(t, os.Unsetenv("TC39_MAX_FILE_SIZE"))
+	n, err := maxFileSizeFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, int64(tc39DefaultMaxFileSize), n)
+	require.NoError(t, os.Setenv("TC39_MAX_FILE_SIZE", "1024"))
+	n, err = maxFileSizeFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, int64(1024), n)
+	for _, v := range []string{"0", "-1", "16M", "1.5", " 1", "0x10"} {
+		require.NoError(t, os.Setenv("TC39_MAX_FILE_SIZE", v))
+		_, err = maxFileSizeFromEnv()
+		require.EqualError(t, err, fmt.Sprintf("TC39_MAX_FILE_SIZE must be a positive number of bytes, got %q", v))
+	}
+	require.NoError(t, os.Unsetenv("TC39_MAX_FILE_SIZE"))
+
+	dir, err := ioutil.TempDir("", "tc39-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, "large.js")
+	require.NoError(t, ioutil.WriteFile(file, []byte("var a = 1;\n"), 0o644))
+	defer func(old int64) { maxFileSize = old }(maxFileSize)
+	maxFileSize = 11
+	b, err := readFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "var a = 1;\n", string(b))
+	maxFileSize = 10
+	_, err = readFile(file)
+	var tooLarge *fileTooLargeError
+	require.True(t, errors.As(err, &tooLarge), err)
+	require.Equal(t, &fileTooLargeError{name: file, size: 11, limit: 10}, tooLarge)
+	require.EqualError(t, err, slashPath(file)+" is 11 bytes, more than the limit of 10 bytes (TC39_MAX_FILE_SIZE)")
+
+	// a test or a harness file that is too large is an infrastructure error, not a failure of the test
+	maxFileSize = 100
+	ctx := newFixtureCtx(t)
+	tb := &tc39CapturingTB{TB: t}
+	ctx.runTC39File("test/compat/es5.js", "test/compat/es5.js", tb)
+	require.Equal(t, []string{
+		"infrastructure error: " + slashPath(osPath(tc39FixturesBase, "test/compat/es5.js")) +
+			" is 165 bytes, more than the limit of 100 bytes (TC39_MAX_FILE_SIZE)",
+	}, tb.errors)
+	tb = &tc39CapturingTB{TB: t}
+	ctx.runTC39Test(tb, "test/harness.js", "var a = 1;", &tc39Meta{}, false)
+	require.Equal(t, []string{
+		"infrastructure error: harness include assert.js failed: harness/assert.js isn't one of the 0 harness files " +
+			"precompiled at init, and it can't be read: " + slashPath(osPath(tc39FixturesBase, "harness/assert.js")) +
+			" is 1494 bytes, more than the limit of 100 bytes (TC39_MAX_FILE_SIZE)",
+	}, tb.errors)
+	require.Equal(t, CategoryInfrastructure, ctx.results.resultsCopy()["test/harness.js-strict:false"].Category)
+}
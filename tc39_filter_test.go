@@ -0,0 +1,211 @@
+package test262
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Filter has the tests of TC39_FILTER, a comma separated list of glob patterns matched against
+// the whole slash separated path of a test. A * matches within a directory and a ** any number of
+// them, none too. A nil filter has all the tests.
+type tc39Filter struct {
+	patterns [][]string
+	// matched counts the tests the filter let through, only the walking goroutine touches it
+	matched int
+}
+
+func filterFromEnv() (*tc39Filter, error) {
+	v := os.Getenv("TC39_FILTER")
+	if v == "" {
+		return nil, nil
+	}
+	return newFilter(v)
+}
+
+func newFilter(v string) (*tc39Filter, error) {
+	f := &tc39Filter{}
+	for _, pattern := range strings.Split(v, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("TC39_FILTER must be a comma separated list of glob patterns, got %q", v)
+		}
+		segments := strings.Split(path.Clean(pattern), "/")
+		for _, segment := range segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("TC39_FILTER has the malformed pattern %q", pattern)
+			}
+		}
+		f.patterns = append(f.patterns, segments)
+	}
+	return f, nil
+}
+
+// matchSegments tells if the path matches the pattern, or with below the path of a directory some
+// test below which could.
+func matchSegments(pattern, name []string, below bool) bool {
+	switch {
+	case len(name) == 0 && below:
+		return len(pattern) > 0
+	case len(name) == 0:
+		for _, segment := range pattern {
+			if segment != "**" {
+				return false
+			}
+		}
+		return true
+	case len(pattern) == 0:
+		return false
+	case pattern[0] == "**":
+		return matchSegments(pattern[1:], name, below) || matchSegments(pattern, name[1:], below)
+	}
+	ok, _ := path.Match(pattern[0], name[0])
+	return ok && matchSegments(pattern[1:], name[1:], below)
+}
+
+func (f *tc39Filter) match(name string, below bool) bool {
+	if f == nil {
+		return true
+	}
+	segments := strings.Split(slashPath(name), "/")
+	for _, pattern := range f.patterns {
+		if matchSegments(pattern, segments, below) {
+			return true
+		}
+	}
+	return false
+}
+
+// has tells if the test with the given name matches the filter.
+func (f *tc39Filter) has(name string) bool {
+	return f.match(name, false)
+}
+
+// mayMatchBelow tells if some test below the directory could match the filter, the walk doesn't
+// descend into the others.
+func (f *tc39Filter) mayMatchBelow(dir string) bool {
+	return f.match(dir, true)
+}
+
+// take counts the test and tells if it matches the filter.
+func (f *tc39Filter) take(name string) bool {
+	if f == nil {
+		return true
+	}
+	if !f.has(name) {
+		return false
+	}
+	f.matched++
+	return true
+}
+
+// expected returns the expected errors of the variants of the tests matching the filter, so the
+// ones of the others aren't compared with anything.
+func (f *tc39Filter) expected(errs map[string]string) map[string]string {
+	if f == nil {
+		return errs
+	}
+	own := make(map[string]string)
+	for nameKey, errStr := range errs {
+		if k, err := parseLegacyKey(nameKey, nil); err == nil && f.has(k.Test) {
+			own[nameKey] = errStr
+		}
+	}
+	return own
+}
+
+func (f *tc39Filter) summary() string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d tests matched TC39_FILTER", f.matched)
+}
+
+func TestFilter(t *testing.T) {
+	require.NoError(t, os.Unsetenv("TC39_FILTER"))
+	f, err := filterFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, f)
+	require.True(t, f.has("test/a.js"))
+	require.True(t, f.mayMatchBelow("test"))
+	require.Empty(t, f.summary())
+	for _, v := range []string{"test/a.js,", ",", "test/[a.js"} {
+		require.NoError(t, os.Setenv("TC39_FILTER", v))
+		_, err = filterFromEnv()
+		require.Error(t, err, v)
+	}
+	require.NoError(t, os.Unsetenv("TC39_FILTER"))
+
+	for pattern, cases := range map[string]map[string]bool{
+		// a prefix of the name
+		"test/built-ins/Array/prototype/splice/S15.4.4.12_*": {
+			"test/built-ins/Array/prototype/splice/S15.4.4.12_A1_T1.js": true,
+			"test/built-ins/Array/prototype/splice/length.js":           false,
+			"test/built-ins/Array/prototype/splice/S15.4.4.12_A1/x.js":  false,
+		},
+		// a suffix anywhere
+		"**/*_A1_T1.js": {
+			"test/built-ins/Array/prototype/splice/S15.4.4.12_A1_T1.js": true,
+			"test/language/S7.2_A1_T1.js":                               true,
+			"test/language/S7.2_A1_T10.js":                              false,
+		},
+		// everything below a directory
+		"test/built-ins/RegExp/**": {
+			"test/built-ins/RegExp/S15.10.1_A1_T1.js":               true,
+			"test/built-ins/RegExp/property-escapes/generated/a.js": true,
+			"test/built-ins/RegExpX/a.js":                           false,
+			"test/built-ins/Array/a.js":                             false,
+		},
+		// ** matches no directory too
+		"test/**/for-of/*.js": {
+			"test/for-of/a.js":                          true,
+			"test/language/statements/for-of/a.js":      true,
+			"test/language/statements/for-of/dstr/a.js": false,
+		},
+	} {
+		f, err := newFilter(pattern)
+		require.NoError(t, err)
+		for name, ok := range cases {
+			require.Equal(t, ok, f.has(name), "%s %s", pattern, name)
+		}
+	}
+
+	f, err = newFilter("test/built-ins/RegExp/**, test/language/statements/for-of/*")
+	require.NoError(t, err)
+	for dir, ok := range map[string]bool{
+		"test":                               true,
+		"test/built-ins":                     true,
+		"test/built-ins/RegExp/named-groups": true,
+		"test/built-ins/Array":               false,
+		"test/language/statements/for-of":    true,
+		// the single * doesn't go deeper
+		"test/language/statements/for-of/dstr": false,
+		"test/annexB":                          false,
+	} {
+		require.Equal(t, ok, f.mayMatchBelow(dir), dir)
+	}
+
+	// only the expected errors of the filtered tests are compared
+	errs := map[string]string{
+		"test/built-ins/RegExp/a.js-strict:false": "boom",
+		"test/built-ins/Array/a.js-strict:false":  "boom",
+	}
+	require.Equal(t, map[string]string{"test/built-ins/RegExp/a.js-strict:false": "boom"}, f.expected(errs))
+
+	// the walk only descends into the directories that could have a match
+	var out bytes.Buffer
+	ctx := newFixtureCtx(t)
+	ctx.opts.Out = &out
+	ctx.dryRun = true
+	ctx.filter, err = newFilter("test/compat/es5*,test/pool/pass-[12].js")
+	require.NoError(t, err)
+	ctx.runTC39Tests("test")
+	require.Equal(t, "test/compat/es5.js\ntest/pool/pass-1.js\ntest/pool/pass-2.js\n", strings.Split(out.String(), "ignored")[0])
+	require.Equal(t, "3 tests matched TC39_FILTER", ctx.filter.summary())
+	require.Contains(t, out.String(), "ignored pruned directory: ")
+}
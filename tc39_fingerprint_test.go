@@ -0,0 +1,170 @@
+package test262
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39FingerprintIgnoredEnv are the TC39_* variables that only say where to write or what to
+// compare, so they don't change the results.
+//nolint:gochecknoglobals
+var tc39FingerprintIgnoredEnv = map[string]bool{
+	"TC39_BENCH_OUT":     true,
+	"TC39_BENCH_COMPARE": true,
+	"TC39_TZ_PASS_OUT":   true,
+	"TC39_REPORT":        true,
+	"TC39_JUNIT":         true,
+	"TC39_TAP":           true,
+}
+
+// tc39Fingerprint is what about the environment of a run can change its results or timings
+// without a single line of code changing.
+type tc39Fingerprint struct {
+	GoVersion  string `json:"goVersion"`
+	GOOS       string `json:"goos"`
+	GOARCH     string `json:"goarch"`
+	NumCPU     int    `json:"numCPU"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	// GOGC is empty when it isn't set, the default of 100.
+	GOGC       string            `json:"gogc"`
+	CompatMode string            `json:"compatMode"`
+	Workers    int               `json:"workers"`
+	Env        map[string]string `json:"env"`
+}
+
+// tc39Environment is the fingerprint of a run and its hash, which is the same for two runs
+// exactly when their fingerprints are.
+type tc39Environment struct {
+	Hash        string          `json:"hash"`
+	Fingerprint tc39Fingerprint `json:"fingerprint"`
+}
+
+func newFingerprint(environ []string) tc39Fingerprint {
+	f := tc39Fingerprint{
+		GoVersion:  runtime.Version(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		CompatMode: lib.CompatibilityModeExtended.String(),
+		Workers:    tc39Workers(),
+		Env:        make(map[string]string),
+	}
+	for _, kv := range environ {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			continue
+		}
+		switch key := kv[:i]; {
+		case key == "GOGC":
+			f.GOGC = kv[i+1:]
+		case strings.HasPrefix(key, "TC39_") && !tc39FingerprintIgnoredEnv[key]:
+			f.Env[key] = kv[i+1:]
+		}
+	}
+	return f
+}
+
+func newEnvironment(environ []string) tc39Environment {
+	f := newFingerprint(environ)
+	// the keys of the map are marshaled sorted, so equal fingerprints always hash the same
+	b, _ := json.Marshal(f)
+	sum := sha256.Sum256(b)
+	return tc39Environment{Hash: hex.EncodeToString(sum[:8]), Fingerprint: f}
+}
+
+// fingerprintDiff describes how two fingerprints differ, a line for every field.
+func fingerprintDiff(old, cur tc39Fingerprint) []string {
+	var diffs []string
+	field := func(name string, a, b interface{}) {
+		if a != b {
+			diffs = append(diffs, fmt.Sprintf("%s: %#v -> %#v", name, a, b))
+		}
+	}
+	field("Go version", old.GoVersion, cur.GoVersion)
+	field("GOOS", old.GOOS, cur.GOOS)
+	field("GOARCH", old.GOARCH, cur.GOARCH)
+	field("CPUs", old.NumCPU, cur.NumCPU)
+	field("GOMAXPROCS", old.GOMAXPROCS, cur.GOMAXPROCS)
+	field("GOGC", old.GOGC, cur.GOGC)
+	field("compat mode", old.CompatMode, cur.CompatMode)
+	field("workers", old.Workers, cur.Workers)
+	keys := make(map[string]bool)
+	for key := range old.Env {
+		keys[key] = true
+	}
+	for key := range cur.Env {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		a, inOld := old.Env[key]
+		b, inCur := cur.Env[key]
+		switch {
+		case !inOld:
+			diffs = append(diffs, fmt.Sprintf("%s: unset -> %q", key, b))
+		case !inCur:
+			diffs = append(diffs, fmt.Sprintf("%s: %q -> unset", key, a))
+		case a != b:
+			diffs = append(diffs, fmt.Sprintf("%s: %q -> %q", key, a, b))
+		}
+	}
+	return diffs
+}
+
+// environmentWarning is what the comparison of two runs warns with when they ran in different
+// environments, empty when they didn't.
+func environmentWarning(old, cur tc39Environment) string {
+	if old.Hash == cur.Hash {
+		return ""
+	}
+	if old.Hash == "" || cur.Hash == "" {
+		return "WARNING: a report has no environment fingerprint, the differences may be environmental\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "WARNING: the runs had different environments (%s and %s), "+
+		"the differences may not be regressions:\n", old.Hash, cur.Hash)
+	for _, diff := range fingerprintDiff(old.Fingerprint, cur.Fingerprint) {
+		fmt.Fprintf(&b, "\t%s\n", diff)
+	}
+	return b.String()
+}
+
+func TestEnvironmentFingerprint(t *testing.T) {
+	env := newEnvironment([]string{"TC39_SEED=1", "TC39_BENCH=1", "TC39_BENCH_OUT=a.json", "HOME=/root", "NOEQUALS"})
+	require.Equal(t, map[string]string{"TC39_SEED": "1", "TC39_BENCH": "1"}, env.Fingerprint.Env)
+	require.Len(t, env.Hash, 16)
+	// neither the order of the variables nor the ignored ones change the hash
+	same := newEnvironment([]string{"TC39_BENCH=1", "TC39_BENCH_OUT=b.json", "TC39_SEED=1"})
+	require.Equal(t, env, same)
+	require.Empty(t, environmentWarning(env, same))
+
+	other := newEnvironment([]string{"TC39_BENCH=1", "GOGC=off", "TC39_TRACE_SLOW=1s"})
+	require.NotEqual(t, env.Hash, other.Hash)
+	require.Equal(t, []string{
+		`GOGC: "" -> "off"`,
+		`TC39_SEED: "1" -> unset`,
+		`TC39_TRACE_SLOW: unset -> "1s"`,
+	}, fingerprintDiff(env.Fingerprint, other.Fingerprint))
+	require.Equal(t, "WARNING: the runs had different environments ("+env.Hash+" and "+other.Hash+
+		"), the differences may not be regressions:\n"+
+		"\tGOGC: \"\" -> \"off\"\n\tTC39_SEED: \"1\" -> unset\n\tTC39_TRACE_SLOW: unset -> \"1s\"\n",
+		environmentWarning(env, other))
+	require.Contains(t, environmentWarning(tc39Environment{}, env), "no environment fingerprint")
+
+	require.Equal(t, os.Getenv("GOGC"), newFingerprint(os.Environ()).GOGC)
+}
@@ -0,0 +1,155 @@
+package test262
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// tc39ExcerptContext is how many lines around the failing one the excerpt of the source shows, or
+// how many from the top when the error has no line.
+const tc39ExcerptContext = 5
+
+// tc39NewFailure is everything about an unexpected failure needed to look into it.
+type tc39NewFailure struct {
+	name     string
+	strict   bool
+	mode     lib.CompatibilityMode
+	category ResultCategory
+	message  string
+	meta     *tc39Meta
+	// src is what ran, the source of the file as it is for both variants
+	src string
+}
+
+// tc39FirstNew stops a run at the first unexpected failure, for TC39_STOP_ON_FIRST_NEW. The tests
+// running in parallel when it happens can fail too, the failure reported is the one with the
+// lowest variant key of all of them, so repeated runs stop at the same test.
+type tc39FirstNew struct {
+	mu    sync.Mutex
+	found []tc39NewFailure
+}
+
+// add records an unexpected failure, which stops the run.
+func (f *tc39FirstNew) add(failure tc39NewFailure) {
+	f.mu.Lock()
+	f.found = append(f.found, failure)
+	f.mu.Unlock()
+}
+
+// stopped tells if the run has to stop, it never does without TC39_STOP_ON_FIRST_NEW.
+func (f *tc39FirstNew) stopped() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.found) > 0
+}
+
+// first returns the failure to report and how many others were found.
+func (f *tc39FirstNew) first() (tc39NewFailure, int, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.found) == 0 {
+		return tc39NewFailure{}, 0, false
+	}
+	sort.Slice(f.found, func(i, j int) bool {
+		a, b := f.found[i], f.found[j]
+		return modeKey(a.name, a.strict, a.mode) < modeKey(b.name, b.strict, b.mode)
+	})
+	return f.found[0], len(f.found) - 1, true
+}
+
+// excerpt returns the lines of src around the line of the file the message points at, numbered.
+func excerpt(src, name, message string) string {
+	lines := strings.Split(src, "\n")
+	from, to := 0, 2*tc39ExcerptContext+1
+	if m := regexp.MustCompile(regexp.QuoteMeta(slashPath(name)) + `:(\d+):\d+`).FindStringSubmatch(message); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		from, to = line-1-tc39ExcerptContext, line+tc39ExcerptContext
+	}
+	if from < 0 {
+		from = 0
+	}
+	if to > len(lines) {
+		to = len(lines)
+	}
+	var b strings.Builder
+	for i := from; i < to; i++ {
+		fmt.Fprintf(&b, "%5d | %s\n", i+1, lines[i])
+	}
+	return b.String()
+}
+
+// writeFirstNew writes everything about the failure the run stopped at, nothing if it didn't.
+func (ctx *tc39TestCtx) writeFirstNew(w io.Writer) {
+	failure, others, ok := ctx.firstNew.first()
+	if !ok {
+		fmt.Fprintln(w, "TC39_STOP_ON_FIRST_NEW: no new failure")
+		return
+	}
+	fmt.Fprintf(w, "stopped at the first new failure, %s (%s)\n", modeKey(failure.name, failure.strict, failure.mode), failure.category)
+	if others > 0 {
+		fmt.Fprintf(w, "%d more new failures were found before the run stopped\n", others)
+	}
+	fmt.Fprintf(w, "error: %s\n", failure.message)
+	fmt.Fprintf(w, "rerun: %s\n", rerunCommand(failure.name))
+	if b, err := yaml.Marshal(failure.meta); err == nil {
+		fmt.Fprintf(w, "metadata:\n%s", b)
+	}
+	fmt.Fprintf(w, "source:\n%s", excerpt(failure.src, failure.name, failure.message))
+	_, code, err := ctx.compileJS(failure.src, failure.name, "", "", failure.strict)
+	switch {
+	case failure.mode == lib.CompatibilityModeBase:
+		fmt.Fprintln(w, "transformed source: none, the base mode doesn't transform it")
+	case err != nil:
+		fmt.Fprintf(w, "transformed source: it doesn't compile: %v\n", err)
+	case code == failure.src:
+		fmt.Fprintln(w, "transformed source: the same, it wasn't transformed")
+	default:
+		fmt.Fprintf(w, "transformed source:\n%s", excerpt(code, failure.name, failure.message))
+	}
+}
+
+func TestStopOnFirstNew(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.firstNew = &tc39FirstNew{}
+	tb := &tc39CountingTB{TB: t}
+	ctx.runTC39Test(tb, "test/passing.js", "var a = 1;", &tc39Meta{}, false)
+	require.False(t, ctx.firstNew.stopped())
+
+	// found by tests running in parallel, in whatever order they fail
+	for _, name := range []string{"test/c.js", "test/b.js"} {
+		ctx.runTC39Test(tb, name, "var a = 1;\nvar b = 2;\nthrow new Error('boom');", &tc39Meta{Es5id: "1"}, false)
+	}
+	require.True(t, ctx.firstNew.stopped())
+	t.Run("test/after.js", func(t *testing.T) {
+		defer func() { require.True(t, t.Skipped()) }()
+		ctx.runTC39File("test/after.js", "test/after.js", t)
+	})
+
+	var b strings.Builder
+	ctx.writeFirstNew(&b)
+	out := b.String()
+	require.Contains(t, out, "stopped at the first new failure, test/b.js-strict:false (new-failure)\n"+
+		"1 more new failures were found before the run stopped\n")
+	require.Contains(t, out, "rerun: "+rerunCommand("test/b.js"))
+	require.Contains(t, out, "metadata:\n")
+	require.Contains(t, out, "es5id: \"1\"")
+	require.Contains(t, out, "    3 | throw new Error('boom');\n")
+
+	long := strings.Repeat("x\n", 30)
+	require.Equal(t, 2*tc39ExcerptContext+1, strings.Count(excerpt(long, "x.js", "no line"), "\n"))
+	require.True(t, strings.HasPrefix(excerpt(long, "x.js", "at x.js:20:3"), "   15 | x\n"))
+	require.Equal(t, "    1 | a\n    2 | b\n    3 | c\n", excerpt("a\nb\nc", "x.js", "at x.js:2:1"))
+}
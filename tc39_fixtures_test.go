@@ -0,0 +1,22 @@
+package test262
+
+import (
+	"testing"
+
+	"github.com/loadimpact/k6/js/compiler"
+	"github.com/loadimpact/k6/lib/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39FixturesBase is a minimal test262 layout used to test the runner itself.
+const tc39FixturesBase = "testdata/fixtures"
+
+func newFixtureCtx(t *testing.T) *tc39TestCtx {
+	ctx := &tc39TestCtx{
+		base:     tc39FixturesBase,
+		compiler: compiler.New(testutils.NewLogger(t)),
+		t:        t,
+	}
+	require.NoError(t, ctx.init())
+	return ctx
+}
@@ -0,0 +1,235 @@
+package test262
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39DefaultGitHubAnnotations is how many error annotations GitHub shows for a step.
+const tc39DefaultGitHubAnnotations = 10
+
+const (
+	// tc39BaselineDir is where the expected errors are kept, in a file for every top directory of
+	// the tests, see baselineArea.
+	tc39BaselineDir = "breaking_test_errors"
+	// tc39Baseline is the single file the expected errors were kept in before, still read while it
+	// exists.
+	tc39Baseline = "breaking_test_errors.json"
+)
+
+// tc39Annotation is a GitHub Actions workflow command, shown inline in the pull request.
+type tc39Annotation struct {
+	level string
+	file  string
+	line  int
+	title string
+	msg   string
+}
+
+func (a tc39Annotation) String() string {
+	var props []string
+	if a.file != "" {
+		props = append(props, "file="+escapeAnnotationProperty(a.file))
+	}
+	if a.line > 0 {
+		props = append(props, "line="+strconv.Itoa(a.line))
+	}
+	if a.title != "" {
+		props = append(props, "title="+escapeAnnotationProperty(a.title))
+	}
+	cmd := "::" + a.level
+	if len(props) > 0 {
+		cmd += " " + strings.Join(props, ",")
+	}
+	return cmd + "::" + escapeAnnotationData(a.msg)
+}
+
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// githubAnnotationsFromEnv returns the most error annotations to emit, 0 unless running on
+// GitHub Actions.
+func githubAnnotationsFromEnv() (int, error) {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return 0, nil
+	}
+	v := os.Getenv("TC39_GITHUB_ANNOTATIONS")
+	if v == "" {
+		return tc39DefaultGitHubAnnotations, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("TC39_GITHUB_ANNOTATIONS must be a positive number, got %q", v)
+	}
+	return n, nil
+}
+
+// baselineLine returns the line of the baseline the variant is on, 0 if it isn't there.
+func baselineLine(baseline []byte, nameKey string) int {
+	i := bytes.Index(baseline, []byte(strconv.Quote(nameKey)+":"))
+	if i < 0 {
+		return 0
+	}
+	return bytes.Count(baseline[:i], []byte("\n")) + 1
+}
+
+// variantName returns the name of the test of a variant key.
+func variantName(nameKey string) string {
+	if i := strings.LastIndex(nameKey, "-strict:"); i >= 0 {
+		return nameKey[:i]
+	}
+	return nameKey
+}
+
+// repoPath returns the path to the file relative to the working directory, the root of the
+// repository when running the tests, as the annotations want it. A checkout symlinked into
+// testdata is named by the symlink, as the base is resolved.
+func repoPath(file string) string {
+	if base, err := filepath.EvalSymlinks(tc39BASE); err == nil && base != tc39BASE {
+		if rel, err := filepath.Rel(base, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(filepath.Join(tc39BASE, rel))
+		}
+	}
+	if wd, err := os.Getwd(); err == nil && filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(wd, file); err == nil {
+			file = rel
+		}
+	}
+	return filepath.ToSlash(file)
+}
+
+// baselineLocation returns the file of the baselines, by name, the variant is in and its line, the
+// file of its area in tc39BaselineDir and 0 if it isn't in any of them.
+func baselineLocation(baselines map[string][]byte, nameKey string) (string, int) {
+	names := make([]string, 0, len(baselines))
+	for name := range baselines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if line := baselineLine(baselines[name], nameKey); line > 0 {
+			return filepath.ToSlash(name), line
+		}
+	}
+	return tc39BaselineDir + "/" + baselineArea(nameKey), 0
+}
+
+// annotations returns an error annotation for each of the first max unexpected results, on the
+// baseline if the test failed differently than it expects and on the test otherwise, and a
+// notice with the counts of all of them.
+func (ctx *tc39TestCtx) annotations(results map[string]TestResult, baselines map[string][]byte, max int) []tc39Annotation {
+	keys := make([]string, 0, len(results))
+	counts := make(map[ResultCategory]int)
+	var skipped int
+	for key, result := range results {
+		counts[result.Category]++
+		if strings.HasPrefix(result.Category.String(), "skipped-") {
+			skipped++
+		}
+		if result.Category.unexpected() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var annotations []tc39Annotation
+	for i, key := range keys {
+		if i == max {
+			break
+		}
+		result := results[key]
+		a := tc39Annotation{level: "error", title: key + " " + result.Category.String(), msg: result.Message}
+		if result.Category == CategoryChangedFailure {
+			a.file, a.line = baselineLocation(baselines, key)
+		} else {
+			s, rel := ctx.suite(variantName(key))
+			a.file = repoPath(osPath(s.root, rel))
+		}
+		annotations = append(annotations, a)
+	}
+	msg := fmt.Sprintf("tc39: %d passed, %d expected failures, %d new failures, %d changed failures, "+
+		"%d panics, %d with pending host work, %d harness failures, %d infrastructure errors, %d skipped",
+		counts[CategoryPass], counts[CategoryExpectedFailure], counts[CategoryNewFailure], counts[CategoryChangedFailure],
+		counts[CategoryPanic], counts[CategoryPendingHostWork], counts[CategoryHarness], counts[CategoryInfrastructure], skipped)
+	if len(keys) > max {
+		msg += fmt.Sprintf(", only the first %d unexpected results are annotated (TC39_GITHUB_ANNOTATIONS)", max)
+	}
+	return append(annotations, tc39Annotation{level: "notice", title: "tc39", msg: msg})
+}
+
+// writeAnnotations writes the annotations of the run to w, which has to be the step's stdout
+// itself, as the workflow commands are only recognized at the start of a line.
+func (ctx *tc39TestCtx) writeAnnotations(w io.Writer, max int) error {
+	files, err := baselineFiles(ctx.baselineLegacy, ctx.baselineDir)
+	if err != nil {
+		return err
+	}
+	baselines := make(map[string][]byte, len(files))
+	for _, name := range files {
+		if baselines[name], err = ioutil.ReadFile(name); err != nil { //nolint:gosec
+			return err
+		}
+	}
+	for _, a := range ctx.annotations(ctx.results.resultsCopy(), baselines, max) {
+		if _, err = fmt.Fprintln(w, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGitHubAnnotations(t *testing.T) {
+	require.Equal(t, "::error file=a%2Cb.js,line=3,title=x%3Ay::50%25%0Adone",
+		tc39Annotation{level: "error", file: "a,b.js", line: 3, title: "x:y", msg: "50%\ndone"}.String())
+
+	baseline := []byte("{\n  \"test/a.js-strict:false\": \"old\",\n  \"test/b.js-strict:true\": \"old\"\n}\n")
+	require.Equal(t, 3, baselineLine(baseline, "test/b.js-strict:true"))
+	require.Equal(t, 0, baselineLine(baseline, "test/c.js-strict:true"))
+	baselines := map[string][]byte{tc39Baseline: baseline, tc39BaselineDir + "/test.json": []byte("{}\n")}
+	file, line := baselineLocation(baselines, "test/b.js-strict:true")
+	require.Equal(t, tc39Baseline, file)
+	require.Equal(t, 3, line)
+	file, line = baselineLocation(baselines, "test/built-ins/x.js-strict:true")
+	require.Equal(t, tc39BaselineDir+"/built-ins.json", file)
+	require.Equal(t, 0, line)
+
+	ctx := newFixtureCtx(t)
+	results := map[string]TestResult{
+		"test/a.js-strict:false": {Category: CategoryExpectedFailure, Message: "old"},
+		"test/b.js-strict:true":  {Category: CategoryChangedFailure, Message: "test/b.js: new"},
+		"test/c.js-strict:true":  {Category: CategoryNewFailure, Message: "test/c.js: boom"},
+		"test/d.js-strict:false": {Category: CategoryPanic, Message: "panic: test/d.js: boom"},
+		"test/e.js-strict:false": {Category: CategoryPass},
+		"test/f.js-strict:false": {Category: CategorySkippedFeature, Message: "Blacklisted feature BigInt"},
+	}
+	require.Equal(t, []string{
+		"::error file=breaking_test_errors.json,line=3,title=test/b.js-strict%3Atrue changed-failure::test/b.js: new",
+		"::error file=testdata/fixtures/test/c.js,title=test/c.js-strict%3Atrue new-failure::test/c.js: boom",
+		"::notice title=tc39::tc39: 1 passed, 1 expected failures, 1 new failures, 1 changed failures, " +
+			"1 panics, 0 with pending host work, 0 harness failures, 0 infrastructure errors, 1 skipped, " +
+			"only the first 2 unexpected results are annotated (TC39_GITHUB_ANNOTATIONS)",
+	}, annotationStrings(ctx.annotations(results, baselines, 2)))
+}
+
+func annotationStrings(annotations []tc39Annotation) []string {
+	lines := make([]string, len(annotations))
+	for i, a := range annotations {
+		lines[i] = a.String()
+	}
+	return lines
+}
@@ -0,0 +1,260 @@
+package test262
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/compiler"
+	"github.com/loadimpact/k6/lib/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// harnessError is a harness file failing to load before a test, which says nothing about the test
+// itself. One broken include fails every test using it, so they're counted by the include.
+type harnessError struct {
+	include string
+	err     error
+}
+
+func (e *harnessError) Error() string {
+	return fmt.Sprintf("harness include %s failed: %v", e.include, e.err)
+}
+
+func (e *harnessError) Unwrap() error {
+	return e.err
+}
+
+type tc39HarnessCause struct {
+	variants int
+	// first is the error of the first variant it failed for, they're usually all the same.
+	first string
+}
+
+func (r *tc39Results) recordHarnessFailure(e *harnessError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cause, ok := r.harness[e.include]
+	if !ok {
+		cause = &tc39HarnessCause{first: e.err.Error()}
+		r.harness[e.include] = cause
+	}
+	cause.variants++
+}
+
+// countCachedFailure counts a load of a harness file that got the error it failed to compile with
+// before instead of being compiled again.
+func (r *tc39Results) countCachedFailure() {
+	r.mu.Lock()
+	r.cachedFailures++
+	r.mu.Unlock()
+}
+
+// cachedFailureSummary returns the line saying how many loads of harness files got their cached
+// compile error, if any did.
+func (r *tc39Results) cachedFailureSummary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cachedFailures == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d harness loads failed with the cached compile error of their file", r.cachedFailures)
+}
+
+// harnessSummary returns a line for every include that failed, the most failed variants first.
+func (r *tc39Results) harnessSummary() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	includes := make([]string, 0, len(r.harness))
+	for include := range r.harness {
+		includes = append(includes, include)
+	}
+	sort.Slice(includes, func(i, j int) bool {
+		a, b := r.harness[includes[i]], r.harness[includes[j]]
+		if a.variants != b.variants {
+			return a.variants > b.variants
+		}
+		return includes[i] < includes[j]
+	})
+	lines := make([]string, len(includes))
+	for i, include := range includes {
+		cause := r.harness[include]
+		lines[i] = fmt.Sprintf("harness include %s failed for %d test variants, first with: %s", include, cause.variants, cause.first)
+	}
+	return lines
+}
+
+// tc39HarnessInit is how many harness files init precompiled and how long that took.
+type tc39HarnessInit struct {
+	files int
+	took  time.Duration
+}
+
+func (h tc39HarnessInit) summary() string {
+	return fmt.Sprintf("precompiled %d harness files at init in %s", h.files, h.took.Round(time.Millisecond))
+}
+
+// precompileHarness compiles every file of the harness of every suite up front, so the tests don't
+// wait for each other on prgCacheLock to compile their includes. A file that can't be read is left
+// to the tests including it, one that doesn't compile fails them with its cached error.
+func (ctx *tc39TestCtx) precompileHarness() {
+	start := time.Now()
+	ctx.harnessPrgs = make(map[string]*goja.Program)
+	bases := []string{ctx.base}
+	for _, s := range ctx.extraSuites {
+		bases = append(bases, s.harness)
+	}
+	for _, base := range bases {
+		release := openFiles.acquire()
+		files, err := ioutil.ReadDir(osPath(base, "harness"))
+		release()
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name := path.Join("harness", f.Name())
+			file := osPath(base, name)
+			if f.IsDir() || path.Ext(name) != ".js" || ctx.harnessPrgs[file] != nil {
+				continue
+			}
+			b, err := ctx.sources.read(base, name)
+			if err != nil {
+				continue
+			}
+			prg, _, err := ctx.compileJS(string(b), name, "", "", false)
+			if err != nil {
+				ctx.prgCache[file] = tc39CachedProgram{err: err}
+				continue
+			}
+			ctx.harnessPrgs[file] = prg
+		}
+	}
+	ctx.harnessInit = tc39HarnessInit{files: len(ctx.harnessPrgs), took: time.Since(start)}
+}
+
+func TestPrecompiledHarness(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	require.Equal(t, 5, ctx.harnessInit.files)
+	require.Regexp(t, `^precompiled 5 harness files at init in \S+$`, ctx.harnessInit.summary())
+	prg, cached, err := ctx.compile(ctx.base, "harness/assert.js")
+	require.NoError(t, err)
+	require.True(t, cached)
+	require.True(t, prg == ctx.harnessPrgs[osPath(ctx.base, "harness/assert.js")])
+	require.Empty(t, ctx.prgCache)
+
+	dir, err := ioutil.TempDir("", "tc39-harness")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "harness"), 0o755))
+	for name, src := range map[string]string{"one.js": "var one = 1;", "broken.js": "var 1;", "notes.txt": "not js"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "harness", name), []byte(src), 0o644))
+	}
+	ctx = &tc39TestCtx{base: dir, compiler: compiler.New(testutils.NewLogger(t))}
+	require.NoError(t, ctx.init())
+	require.Equal(t, 1, ctx.harnessInit.files)
+
+	// a file added after init is compiled once it's needed
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "harness", "two.js"), []byte("var two = 2;"), 0o644))
+	for _, wantCached := range []bool{false, true} {
+		_, cached, err = ctx.compile(dir, "harness/two.js")
+		require.NoError(t, err)
+		require.Equal(t, wantCached, cached)
+	}
+	// the broken one fails the tests including it
+	_, _, err = ctx.compile(dir, "harness/broken.js")
+	require.Error(t, err)
+	require.Contains(t, ctx.prgCache, osPath(dir, "harness/broken.js"))
+	_, _, err = ctx.compile(dir, "harness/missing.js")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "harness/missing.js isn't one of the 1 harness files precompiled at init, and it can't be read: ")
+}
+
+func TestCachedCompileFailures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-harness")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "harness"), 0o755))
+	for _, name := range []string{"assert.js", "sta.js"} {
+		b, err := ioutil.ReadFile(filepath.Join(tc39FixturesBase, "harness", name))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "harness", name), b, 0o644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "harness", "broken.js"), []byte("var 1;"), 0o644))
+	ctx := &tc39TestCtx{base: dir, compiler: compiler.New(testutils.NewLogger(t))}
+	require.NoError(t, ctx.init())
+	require.Equal(t, "", ctx.results.cachedFailureSummary())
+
+	tb := &tc39CountingTB{TB: t}
+	run := func(include string) {
+		for i := 0; i < 3; i++ {
+			name := fmt.Sprintf("test/%s-%d.js", include, i)
+			ctx.runTC39Test(tb, name, `var a = 1;`, &tc39Meta{Includes: []string{include}}, false)
+			result := ctx.results.resultsCopy()[variantKey(name, false)]
+			require.Equal(t, CategoryHarness, result.Category)
+			require.Contains(t, result.Message, "harness include "+include+" failed: ")
+		}
+	}
+	// init compiled it, none of the tests did
+	run("broken.js")
+	require.Equal(t, "3 harness loads failed with the cached compile error of their file", ctx.results.cachedFailureSummary())
+
+	// one added since is compiled by the first test including it only
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "harness", "later.js"), []byte("var 2;"), 0o644))
+	run("later.js")
+	require.Equal(t, "5 harness loads failed with the cached compile error of their file", ctx.results.cachedFailureSummary())
+	require.Equal(t, 6, tb.errors)
+}
+
+func TestHarnessFailures(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	tb := &tc39CountingTB{TB: t}
+	runtimeNegative := &tc39Meta{Negative: TC39MetaNegative{Phase: "runtime", Type: "Test262Error"}, Includes: []string{"missing.js"}}
+	ctx.runTC39Test(tb, "test/negative.js", `throw new Test262Error();`, runtimeNegative, false)
+	ctx.runTC39Test(tb, "test/negative.js", `throw new Test262Error();`, runtimeNegative, true)
+	ctx.runTC39Test(tb, "test/positive.js", `var a = 1;`, &tc39Meta{Includes: []string{"compareArray.js", "missing.js"}}, false)
+	ctx.runTC39Test(tb, "test/included.js", `assert(compareArray([1], [1]));`, &tc39Meta{Includes: []string{"compareArray.js"}}, false)
+
+	results := ctx.results.resultsCopy()
+	result := results["test/negative.js-strict:false"]
+	require.Equal(t, CategoryHarness, result.Category)
+	require.Regexp(t, "^test/negative.js: harness include missing.js failed: .*missing.js", result.Message)
+	require.NotContains(t, result.Message, "phase")
+	require.Equal(t, CategoryHarness, results["test/positive.js-strict:false"].Category)
+	require.Equal(t, CategoryPass, results["test/included.js-strict:false"].Category)
+	require.Equal(t, 3, tb.errors)
+
+	summary := ctx.results.harnessSummary()
+	require.Len(t, summary, 1)
+	require.Regexp(t, "^harness include missing.js failed for 3 test variants, first with: .*missing.js", summary[0])
+	require.Contains(t, ctx.results.errorsCopy(), "test/positive.js-strict:false")
+
+	// an expected harness failure stays expected
+	ctx.expectedErrors = map[string]string{"test/positive.js-strict:false": results["test/positive.js-strict:false"].Message}
+	ctx.runTC39Test(tb, "test/positive.js", `var a = 1;`, &tc39Meta{Includes: []string{"compareArray.js", "missing.js"}}, false)
+	require.Equal(t, CategoryExpectedFailure, ctx.results.resultsCopy()["test/positive.js-strict:false"].Category)
+}
+
+func TestRawTests(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	t.Run("test/raw.js", func(t *testing.T) {
+		ctx.runTC39File("test/raw.js", "test/raw.js", t)
+	})
+	results := ctx.results.resultsCopy()
+	require.Equal(t, CategoryPass, results["test/raw.js-strict:false"].Category)
+	require.NotContains(t, results, "test/raw.js-strict:true", "a raw test only runs as it is")
+
+	// the same source fails with the harness
+	tb := &tc39CountingTB{TB: t}
+	meta, src, err := parseTC39File(osPath(tc39FixturesBase, "test/raw.js"), tc39FixturesBase)
+	require.NoError(t, err)
+	meta.Flags = nil
+	ctx.runTC39Test(tb, "test/not-raw.js", src, meta, false)
+	require.Equal(t, CategoryNewFailure, ctx.results.resultsCopy()["test/not-raw.js-strict:false"].Category)
+	require.Equal(t, 1, tb.errors)
+}
@@ -0,0 +1,230 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// Options configures the runner beyond the environment variables.
+type Options struct {
+	// Hooks run around every variant of every test. Their Before methods are called in order
+	// and their After methods in the reverse one, like nested middleware.
+	Hooks []TestHook
+	// Out gets all the human-readable output of a run, the progress, summaries and tables,
+	// os.Stdout if it's nil.
+	Out io.Writer
+}
+
+func (ctx *tc39TestCtx) out() io.Writer {
+	if ctx.opts.Out == nil {
+		return os.Stdout
+	}
+	return ctx.opts.Out
+}
+
+// TestHook instruments the tests. Before is called once the runtime is set up and before any
+// harness file runs, an error from it is an infrastructure failure and the test isn't run. After
+// is only called for the hooks whose Before succeeded.
+type TestHook interface {
+	Before(tc *TestInfo, vm *goja.Runtime) error
+	After(tc *TestInfo, result *TestResult)
+}
+
+// TestInfo is the test variant a hook is called for.
+type TestInfo struct {
+	Name   string
+	Strict bool
+	// Mode is the compatibility mode the variant runs in.
+	Mode lib.CompatibilityMode
+	Meta *tc39Meta
+	// HostWork is where the hooks register the callbacks they schedule for the test, which
+	// fails if any of them is still pending when it completes.
+	HostWork *tc39HostWork
+}
+
+// TestResult is what running a test variant produced and how that compares to the
+// expectations of the test.
+type TestResult struct {
+	// Err is what the test threw, nil if it completed.
+	Err error
+	// Early is set if Err happened before the test body started running.
+	Early   bool
+	Timings tc39Timings
+
+	Category ResultCategory
+	// Message is the error as it's kept in breaking_test_errors.json, or why the test was
+	// skipped, empty for a pass.
+	Message string
+	// Expected is the expected error of a variant that failed with another one, empty if it had
+	// none or failed with it.
+	Expected string
+	// Details has the stack and the properties of the value the test threw, if it did, for
+	// triaging. Unlike Message it changes with the harness, so it isn't compared.
+	Details string
+	// Native is how a failure compares to the run of the variant on plain goja, empty unless
+	// TC39_NATIVE_COMPARE compared it.
+	Native string
+	// Duration is how long the whole variant took, the runtime setup included.
+	Duration time.Duration
+	Meta     *tc39Meta
+}
+
+// runHooks calls Before of every hook and returns the function calling After on the ones that
+// succeeded.
+func (ctx *tc39TestCtx) runHooks(tc *TestInfo, vm *goja.Runtime) (after func(*TestResult), err error) {
+	var ran []TestHook
+	after = func(result *TestResult) {
+		for i := len(ran) - 1; i >= 0; i-- {
+			ran[i].After(tc, result)
+		}
+	}
+	for _, hook := range ctx.opts.Hooks {
+		if err = hook.Before(tc, vm); err != nil {
+			return after, fmt.Errorf("before hook of %s: %w", tc.Name, err)
+		}
+		ran = append(ran, hook)
+	}
+	return after, nil
+}
+
+// tc39BenchHook collects the timings of the tests that are being measured.
+type tc39BenchHook struct {
+	mu      sync.Mutex
+	samples map[tc39TestPrgKey][]tc39Timings
+}
+
+func newBenchHook() *tc39BenchHook {
+	return &tc39BenchHook{samples: make(map[tc39TestPrgKey][]tc39Timings)}
+}
+
+// start begins collecting the samples of a test variant, the runs of the ones that weren't
+// started aren't recorded.
+func (h *tc39BenchHook) start(key tc39TestPrgKey) {
+	h.mu.Lock()
+	h.samples[key] = make([]tc39Timings, 0)
+	h.mu.Unlock()
+}
+
+// take returns the samples of a test variant and stops collecting them.
+func (h *tc39BenchHook) take(key tc39TestPrgKey) []tc39Timings {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	samples := h.samples[key]
+	delete(h.samples, key)
+	return samples
+}
+
+func (h *tc39BenchHook) Before(*TestInfo, *goja.Runtime) error {
+	return nil
+}
+
+func (h *tc39BenchHook) After(tc *TestInfo, result *TestResult) {
+	key := tc39TestPrgKey{name: tc.Name, strict: tc.Strict}
+	h.mu.Lock()
+	if samples, ok := h.samples[key]; ok {
+		h.samples[key] = append(samples, result.Timings)
+	}
+	h.mu.Unlock()
+}
+
+// tc39RecordingHook records the calls to it, optionally failing Before.
+type tc39RecordingHook struct {
+	name   string
+	calls  *[]string
+	err    error
+	result *TestResult
+}
+
+func (h *tc39RecordingHook) Before(tc *TestInfo, vm *goja.Runtime) error {
+	*h.calls = append(*h.calls, h.name+".before")
+	return h.err
+}
+
+func (h *tc39RecordingHook) After(tc *TestInfo, result *TestResult) {
+	*h.calls = append(*h.calls, h.name+".after")
+	h.result = result
+}
+
+func TestHooksOrder(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	var calls []string
+	a := &tc39RecordingHook{name: "a", calls: &calls}
+	b := &tc39RecordingHook{name: "b", calls: &calls}
+	ctx.opts.Hooks = []TestHook{a, b}
+
+	ctx.runTC39Test(t, "test/hooks.js", `throw new Error("thrown")`, &tc39Meta{
+		Negative: TC39MetaNegative{Phase: "runtime", Type: "Error"},
+	}, false)
+	require.Equal(t, []string{"a.before", "b.before", "b.after", "a.after"}, calls)
+	require.True(t, a.result == b.result, "the hooks got different results")
+	require.False(t, a.result.Early)
+	require.Error(t, a.result.Err)
+	require.Contains(t, a.result.Err.Error(), "thrown")
+}
+
+func TestHooksBeforeError(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	var calls []string
+	errBroken := errors.New("broken")
+	ctx.opts.Hooks = []TestHook{
+		&tc39RecordingHook{name: "a", calls: &calls},
+		&tc39RecordingHook{name: "b", calls: &calls, err: errBroken},
+		&tc39RecordingHook{name: "c", calls: &calls},
+	}
+
+	tb := &tc39CountingTB{TB: t}
+	ctx.runTC39Test(tb, "test/hooks.js", `$ERROR("must not run")`, &tc39Meta{}, false)
+	require.Equal(t, []string{"a.before", "b.before", "a.after"}, calls)
+	require.Equal(t, 1, tb.errors)
+	require.Empty(t, ctx.results.errorsCopy())
+}
+
+func TestBenchHook(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.enableBench = true
+	ctx.benchIterations = 3
+	ctx.benchHook = newBenchHook()
+	ctx.opts.Hooks = []TestHook{ctx.benchHook}
+
+	v := ctx.benchTC39Test(t, "test/bench.js", `var a = [1, 2, 3].join();`, &tc39Meta{}, true)
+	require.Equal(t, 3, v.samples)
+	require.True(t, v.strict)
+	// the harness was precompiled at init
+	require.Equal(t, "warm", v.harnessCache)
+	// without it the first iteration compiles the harness, and only the first one is labeled
+	ctx.harnessPrgs = make(map[string]*goja.Program)
+	v = ctx.benchTC39Test(t, "test/cold.js", `var a = 1;`, &tc39Meta{}, false)
+	require.Equal(t, "cold", v.harnessCache)
+	require.Equal(t, 3, v.samples)
+	v = ctx.benchTC39Test(t, "test/cold.js", `var a = 1;`, &tc39Meta{}, true)
+	require.Equal(t, "warm", v.harnessCache)
+	require.Equal(t, "none", v.transformCache, "there's no TC39_CACHE_DIR")
+
+	// only what Babel has to transform looks up the transform cache, the first time in vain
+	dir, err := ioutil.TempDir("", "tc39-bench-hook")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	ctx.babelCache, err = newBabelCache(dir)
+	require.NoError(t, err)
+	class := `class Point { constructor(x) { this.x = x; } }`
+	v = ctx.benchTC39Test(t, "test/class.js", class, &tc39Meta{}, false)
+	require.Equal(t, "cold", v.transformCache)
+	v = ctx.benchTC39Test(t, "test/class.js", class, &tc39Meta{}, true)
+	require.Equal(t, "warm", v.transformCache)
+	v = ctx.benchTC39Test(t, "test/cold.js", `var a = 1;`, &tc39Meta{}, false)
+	require.Equal(t, "none", v.transformCache)
+
+	// the runs of tests that aren't measured aren't kept
+	ctx.runTC39Test(t, "test/bench.js", `var a = 1;`, &tc39Meta{}, true)
+	require.Empty(t, ctx.benchHook.samples)
+}
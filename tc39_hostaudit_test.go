@@ -0,0 +1,215 @@
+package test262
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39HostAuditMaxCandidates is how many of the tests that could have changed the host are named.
+const tc39HostAuditMaxCandidates = 20
+
+// tc39HostSentinel describes one thing about the host no test should be able to change.
+type tc39HostSentinel struct {
+	name  string
+	state func() string
+}
+
+// tc39HostHook is a tripwire for tests reaching the host, which only a bug in what the runner
+// exposes to them could allow. Every so many tests it checks a few sentinels and, if one of them
+// changed, names the tests that ran since the last check as the candidates. It's approximate: it
+// only notices what the sentinels cover, and only which window of tests did it.
+type tc39HostHook struct {
+	every     int
+	sentinels []tc39HostSentinel
+	canary    string
+	spawn     func(func())
+
+	mu       sync.Mutex
+	finished int
+	running  map[*TestInfo]bool
+	window   map[string]bool
+
+	// checkMu serializes the checks, which run in the background
+	checkMu  sync.Mutex
+	states   []string
+	problems []string
+}
+
+// hostAuditFromEnv returns how many tests run between two checks of the host, 0 if it isn't
+// audited.
+func hostAuditFromEnv() (int, error) {
+	v := os.Getenv("TC39_HOST_AUDIT")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("TC39_HOST_AUDIT must be the number of tests between the checks, got %q", v)
+	}
+	return n, nil
+}
+
+// newHostHook creates the canary file and snapshots the sentinels, which include the mtime of
+// the directory of the tests. The checks run through spawn.
+func newHostHook(every int, base string, spawn func(func())) (*tc39HostHook, error) {
+	f, err := ioutil.TempFile("", "tc39-canary")
+	if err != nil {
+		return nil, err
+	}
+	if err = f.Close(); err != nil {
+		return nil, err
+	}
+	h := &tc39HostHook{
+		every:   every,
+		canary:  f.Name(),
+		spawn:   spawn,
+		running: make(map[*TestInfo]bool),
+		window:  make(map[string]bool),
+	}
+	h.sentinels = []tc39HostSentinel{
+		{"the environment", func() string {
+			env := os.Environ()
+			sort.Strings(env)
+			return strings.Join(env, "\x00")
+		}},
+		{"the working directory", func() string {
+			wd, err := os.Getwd()
+			return wd + errString(err)
+		}},
+		{"the canary file " + h.canary, fileState(h.canary)},
+		{"the directory " + base, fileState(base)},
+	}
+	h.states = h.snapshot()
+	return h, nil
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return " " + err.Error()
+}
+
+// fileState describes a file by its size, mode and mtime, or by why it can't be described.
+func fileState(name string) func() string {
+	return func() string {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("%d %s %s", fi.Size(), fi.Mode(), fi.ModTime().Format(time.RFC3339Nano))
+	}
+}
+
+func (h *tc39HostHook) snapshot() []string {
+	states := make([]string, len(h.sentinels))
+	for i, s := range h.sentinels {
+		states[i] = s.state()
+	}
+	return states
+}
+
+func (h *tc39HostHook) Before(tc *TestInfo, _ *goja.Runtime) error {
+	h.mu.Lock()
+	h.running[tc] = true
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *tc39HostHook) After(tc *TestInfo, _ *TestResult) {
+	h.mu.Lock()
+	delete(h.running, tc)
+	h.window[fmt.Sprintf("%s-strict:%v", tc.Name, tc.Strict)] = true
+	h.finished++
+	due := h.finished%h.every == 0
+	h.mu.Unlock()
+	if due {
+		h.spawn(h.check)
+	}
+}
+
+// check compares the sentinels to the previous check. The candidates are taken before the
+// sentinels are, they are the tests that finished since the previous check took them and the
+// ones still running, which covers every test that could have changed a sentinel since the
+// previous check took its snapshot.
+func (h *tc39HostHook) check() {
+	h.checkMu.Lock()
+	defer h.checkMu.Unlock()
+	h.mu.Lock()
+	candidates := make([]string, 0, len(h.window)+len(h.running))
+	for name := range h.window {
+		candidates = append(candidates, name)
+	}
+	for tc := range h.running {
+		candidates = append(candidates, fmt.Sprintf("%s-strict:%v (still running)", tc.Name, tc.Strict))
+	}
+	h.window = make(map[string]bool)
+	h.mu.Unlock()
+
+	states := h.snapshot()
+	var changed []string
+	for i, s := range h.sentinels {
+		if states[i] != h.states[i] {
+			changed = append(changed, s.name)
+		}
+	}
+	h.states = states
+	if len(changed) == 0 {
+		return
+	}
+	sort.Strings(candidates)
+	if more := len(candidates) - tc39HostAuditMaxCandidates; more > 0 {
+		candidates = append(candidates[:tc39HostAuditMaxCandidates], fmt.Sprintf("%d more", more))
+	}
+	h.problems = append(h.problems, fmt.Sprintf("%s changed while running one of %s",
+		strings.Join(changed, " and "), strings.Join(candidates, ", ")))
+}
+
+// report checks the tests run since the last check, removes the canary and returns what changed.
+// The spawned checks have to be finished.
+func (h *tc39HostHook) report() []string {
+	h.mu.Lock()
+	pending := len(h.window) > 0
+	h.mu.Unlock()
+	if pending {
+		h.check()
+	}
+	h.checkMu.Lock()
+	defer h.checkMu.Unlock()
+	_ = os.Remove(h.canary)
+	return append([]string(nil), h.problems...)
+}
+
+func TestHostAudit(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	audit, err := newHostHook(2, ctx.base, ctx.spawn)
+	require.NoError(t, err)
+	ctx.opts.Hooks = []TestHook{audit}
+
+	ctx.runTC39Test(t, "test/a.js", `var x = 1;`, &tc39Meta{}, false)
+	ctx.runTC39Test(t, "test/b.js", `var y = 2;`, &tc39Meta{}, false)
+	require.Empty(t, audit.problems)
+
+	// the tests can't reach the host, so a sentinel is changed behind the back of the next one
+	audit.sentinels = append(audit.sentinels, tc39HostSentinel{"the fake", func() string { return "" }})
+	audit.states = append(audit.states, "before")
+	ctx.runTC39Test(t, "test/c.js", `var z = 3;`, &tc39Meta{}, false)
+	ctx.runTC39Test(t, "test/d.js", `var w = 4;`, &tc39Meta{}, true)
+	ctx.runTC39Test(t, "test/e.js", `var v = 5;`, &tc39Meta{}, false)
+	require.Equal(t, []string{
+		"the fake changed while running one of test/c.js-strict:false, test/d.js-strict:true",
+	}, audit.report())
+	_, err = os.Stat(audit.canary)
+	require.True(t, os.IsNotExist(err), err)
+	require.Empty(t, ctx.results.errorsCopy())
+}
@@ -0,0 +1,96 @@
+package test262
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39IntlStubMessage is what all the constructors of the Intl stub throw.
+const tc39IntlStubMessage = "is not supported (Intl stub)"
+
+// tc39IntlBaseline holds the expected errors of the intl402 smoke tests, separately from
+// breaking_test_errors.json as they only run against the stub.
+const tc39IntlBaseline = "./intl402_smoke_errors.json"
+
+//nolint:gochecknoglobals
+var (
+	intlStub = goja.MustCompile("intlStub.js", `
+		(function(global) {
+			function unsupported(name) {
+				var ctor = function() {
+					throw new TypeError("Intl." + name + " `+tc39IntlStubMessage+`");
+				};
+				Object.defineProperty(ctor, "name", {value: name, configurable: true});
+				return ctor;
+			}
+			var Intl = {};
+			["Collator", "NumberFormat", "DateTimeFormat"].forEach(function(name) {
+				Object.defineProperty(Intl, name, {value: unsupported(name), writable: true, configurable: true});
+			});
+			Object.defineProperty(global, "Intl", {value: Intl, writable: true, configurable: true});
+		})(this);`,
+		false)
+
+	// intl402SmokeList are the intl402 tests that only need the Intl constructors to exist.
+	intl402SmokeList = map[string]bool{
+		"test/intl402/Collator/builtin.js":         true,
+		"test/intl402/Collator/length.js":          true,
+		"test/intl402/Collator/name.js":            true,
+		"test/intl402/Collator/prop-desc.js":       true,
+		"test/intl402/NumberFormat/builtin.js":     true,
+		"test/intl402/NumberFormat/length.js":      true,
+		"test/intl402/NumberFormat/name.js":        true,
+		"test/intl402/NumberFormat/prop-desc.js":   true,
+		"test/intl402/DateTimeFormat/builtin.js":   true,
+		"test/intl402/DateTimeFormat/length.js":    true,
+		"test/intl402/DateTimeFormat/name.js":      true,
+		"test/intl402/DateTimeFormat/prop-desc.js": true,
+	}
+)
+
+// intlSmoke reports whether name runs against the Intl stub.
+func (ctx *tc39TestCtx) intlSmoke(name string) bool {
+	return ctx.intlStub && intl402SmokeList[name]
+}
+
+func (ctx *tc39TestCtx) loadIntlBaseline() error {
+	b, err := ioutil.ReadFile(tc39IntlBaseline)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, &ctx.intlExpectedErrors); err != nil {
+		return fmt.Errorf("%s: %w", tc39IntlBaseline, jsonError(b, err))
+	}
+	return nil
+}
+
+func TestIntlStub(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.intlStub = true
+	name := "test/intl402/Collator/builtin.js"
+	require.True(t, ctx.intlSmoke(name))
+
+	src := `
+		["Collator", "NumberFormat", "DateTimeFormat"].forEach(function(name) {
+			assert.sameValue(typeof Intl[name], "function", name);
+			assert.sameValue(Intl[name].name, name);
+			assert.sameValue(Object.getPrototypeOf(Intl[name]), Function.prototype);
+			try {
+				new Intl[name]();
+			} catch (e) {
+				assert.sameValue(e.constructor, TypeError);
+				assert.sameValue(e.message, "Intl." + name + " ` + tc39IntlStubMessage + `");
+				return;
+			}
+			$ERROR("Intl." + name + " didn't throw");
+		});`
+	for _, strict := range []bool{false, true} {
+		ctx.runTC39Test(t, name, src, &tc39Meta{}, strict)
+	}
+	require.Empty(t, ctx.results.errorsCopy())
+}
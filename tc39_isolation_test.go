@@ -0,0 +1,227 @@
+package test262
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39IsolationProbe returns a function describing the own properties of the intrinsics a test
+// could pollute. It's run before the test, so a test replacing Object.getOwnPropertyNames or the
+// like doesn't fool it.
+//nolint:gochecknoglobals
+var tc39IsolationProbe = goja.MustCompile("isolationProbe.js", `
+	(function(global) {
+		var names = Object.getOwnPropertyNames, describe = Object.getOwnPropertyDescriptor;
+		var join = Array.prototype.join, push = Array.prototype.push, sort = Array.prototype.sort;
+		var targets = [Object.prototype, Array.prototype, String.prototype, global];
+		function snapshot(o) {
+			var props = names(o), out = [];
+			sort.call(props);
+			for (var i = 0; i < props.length; i++) {
+				var d = describe(o, props[i]);
+				push.call(out, props[i] + ":" + (d.writable ? "w" : "-") + (d.enumerable ? "e" : "-") +
+					(d.configurable ? "c" : "-"));
+			}
+			return join.call(out, " ");
+		}
+		return function() {
+			var out = [];
+			for (var i = 0; i < targets.length; i++) {
+				push.call(out, snapshot(targets[i]));
+			}
+			return out;
+		};
+	})(this)`, false)
+
+// tc39IsolationTargets names what the probe describes, in its order.
+//nolint:gochecknoglobals
+var tc39IsolationTargets = []string{"Object.prototype", "Array.prototype", "String.prototype", "the global object"}
+
+// tc39IsolationHook audits that the tests leave the intrinsics as the harness left them, as a
+// test that doesn't would affect the next one if runtimes were ever reused. The prototypes have
+// to be exactly the same, the global object may get new properties, which every included harness
+// file and var of a test adds, but must keep the ones it had.
+type tc39IsolationHook struct {
+	ctx *tc39TestCtx
+
+	pristineOnce sync.Once
+	pristine     []string
+	pristineErr  error
+
+	mu        sync.Mutex
+	probes    map[*TestInfo]goja.Callable
+	polluters []string
+}
+
+func newIsolationHook(ctx *tc39TestCtx) *tc39IsolationHook {
+	return &tc39IsolationHook{ctx: ctx, probes: make(map[*TestInfo]goja.Callable)}
+}
+
+// snapshot is the probe's description of the intrinsics.
+func snapshot(probe goja.Callable) ([]string, error) {
+	v, err := probe(goja.Undefined())
+	if err != nil {
+		return nil, err
+	}
+	exported, ok := v.Export().([]interface{})
+	if !ok || len(exported) != len(tc39IsolationTargets) {
+		return nil, fmt.Errorf("the probe returned %s", v)
+	}
+	out := make([]string, len(exported))
+	for i, e := range exported {
+		out[i], _ = e.(string)
+	}
+	return out, nil
+}
+
+func (h *tc39IsolationHook) Before(tc *TestInfo, vm *goja.Runtime) error {
+	h.pristineOnce.Do(func() {
+		h.pristine, h.pristineErr = h.pristineSnapshot()
+	})
+	if h.pristineErr != nil {
+		return fmt.Errorf("isolation audit: %w", h.pristineErr)
+	}
+	probe, err := newProbe(vm)
+	if err != nil {
+		return fmt.Errorf("isolation audit: %w", err)
+	}
+	h.mu.Lock()
+	h.probes[tc] = probe
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *tc39IsolationHook) After(tc *TestInfo, result *TestResult) {
+	h.mu.Lock()
+	probe := h.probes[tc]
+	delete(h.probes, tc)
+	h.mu.Unlock()
+
+	var problem string
+	if after, err := snapshot(probe); err != nil {
+		problem = fmt.Sprintf("the probe: it failed with %v", err)
+	} else {
+		problem = pollution(h.pristine, after)
+	}
+	if problem != "" {
+		h.mu.Lock()
+		h.polluters = append(h.polluters, fmt.Sprintf("%s-strict:%v polluted %s", tc.Name, tc.Strict, problem))
+		h.mu.Unlock()
+	}
+}
+
+// pristineSnapshot describes the intrinsics right after the harness ran.
+func (h *tc39IsolationHook) pristineSnapshot() ([]string, error) {
+	vm, _, _ := h.ctx.newRuntime("", &tc39Output{})
+	probe, err := newProbe(vm)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range []string{"assert.js", "sta.js"} {
+		if err = h.ctx.runFile(h.ctx.base, path.Join("harness", file), vm, &tc39Timings{}); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot(probe)
+}
+
+func newProbe(vm *goja.Runtime) (goja.Callable, error) {
+	v, err := vm.RunProgram(tc39IsolationProbe)
+	if err != nil {
+		return nil, err
+	}
+	probe, ok := goja.AssertFunction(v)
+	if !ok {
+		return nil, fmt.Errorf("the probe is a %s instead of a function", v)
+	}
+	return probe, nil
+}
+
+// pollution describes how the intrinsics changed, empty if they didn't in a way that matters.
+func pollution(pristine, after []string) string {
+	var problems []string
+	for i, target := range tc39IsolationTargets {
+		before, now := strings.Fields(pristine[i]), strings.Fields(after[i])
+		if i < len(tc39IsolationTargets)-1 && pristine[i] == after[i] {
+			continue
+		}
+		added, removed := diffFields(before, now)
+		if i == len(tc39IsolationTargets)-1 {
+			// new globals are expected, only changed or removed ones pollute
+			added = nil
+		}
+		var changes []string
+		if len(removed) > 0 {
+			changes = append(changes, "removed "+strings.Join(removed, " "))
+		}
+		if len(added) > 0 {
+			changes = append(changes, "added "+strings.Join(added, " "))
+		}
+		if len(changes) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %s", target, strings.Join(changes, ", ")))
+		}
+	}
+	return strings.Join(problems, "; ")
+}
+
+// diffFields returns the fields only in b and the ones only in a.
+func diffFields(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, f := range a {
+		inA[f] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, f := range b {
+		inB[f] = true
+		if !inA[f] {
+			added = append(added, f)
+		}
+	}
+	for _, f := range a {
+		if !inB[f] {
+			removed = append(removed, f)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// report returns the polluting test variants, sorted.
+func (h *tc39IsolationHook) report() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	polluters := append([]string(nil), h.polluters...)
+	sort.Strings(polluters)
+	return polluters
+}
+
+func TestIsolationAudit(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	audit := newIsolationHook(ctx)
+	ctx.opts.Hooks = []TestHook{audit}
+
+	ctx.runTC39Test(t, "test/clean.js", `var x = [1, 2].map(function(v) { return v * 2; }); var y = "a".repeat(2);`,
+		&tc39Meta{}, false)
+	require.Empty(t, audit.report())
+
+	ctx.runTC39Test(t, "test/pollutes.js", `
+		Array.prototype.extra = 1;
+		Object.defineProperty(String.prototype, "trim", {writable: false});
+		delete this.$262;
+		Object.getOwnPropertyNames = function() { return []; };`, &tc39Meta{}, false)
+	require.Equal(t, []string{"test/pollutes.js-strict:false polluted " +
+		"Array.prototype: added extra:wec; " +
+		"String.prototype: removed trim:w-c, added trim:--c; " +
+		"the global object: removed $262:wec",
+	}, audit.report())
+	require.Empty(t, audit.probes)
+	require.Empty(t, ctx.results.errorsCopy())
+}
@@ -0,0 +1,213 @@
+package test262
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39JUnitGolden is the JUnit XML of the fixture run in TestJUnit, rewritten with
+// TC39_UPDATE_REPORT_GOLDEN=1 like the one of TC39_REPORT.
+const tc39JUnitGolden = "testdata/junit.golden.xml"
+
+// The JUnit XML TC39_JUNIT writes, as CI systems render it: a testsuite for every top directory of
+// the tests with a testcase for every variant. encoding/xml escapes the messages, the characters
+// XML can't have at all, like the escape sequences of colored output, become U+FFFD.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+
+	duration time.Duration
+}
+
+type junitTestCase struct {
+	Name      string `xml:"name,attr"`
+	ClassName string `xml:"classname,attr"`
+	Time      string `xml:"time,attr"`
+	// Failure is an unexpected result or an expected failure, Error a panic, which is never
+	// what a test checks.
+	Failure *junitProblem `xml:"failure"`
+	Error   *junitProblem `xml:"error"`
+	Skipped *junitSkipped `xml:"skipped"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// newJUnit returns the JUnit XML of the results, the suites sorted by name and their cases by
+// variant.
+func newJUnit(results map[string]TestResult) *junitTestSuites {
+	keys := make([]string, 0, len(results))
+	for nameKey := range results {
+		keys = append(keys, nameKey)
+	}
+	sort.Strings(keys)
+	suites := make(map[string]*junitTestSuite)
+	var names []string
+	for _, nameKey := range keys {
+		result := results[nameKey]
+		name := variantName(nameKey)
+		area := strings.TrimSuffix(baselineArea(nameKey), ".json")
+		s := suites[area]
+		if s == nil {
+			s = &junitTestSuite{Name: area}
+			suites[area] = s
+			names = append(names, area)
+		}
+		c := junitTestCase{
+			Name: strings.TrimPrefix(nameKey, name+"-"), ClassName: name, Time: junitTime(result.Duration),
+		}
+		problem := &junitProblem{Message: result.Message, Type: result.Category.String(), Text: result.Message}
+		if result.Details != "" {
+			problem.Text += "\n" + result.Details
+		}
+		switch status := reportStatus(result.Category); {
+		case status == "pass":
+		case status == "skip":
+			c.Skipped = &junitSkipped{Message: result.Message}
+			s.Skipped++
+		case status == "panic":
+			c.Error = problem
+			s.Errors++
+		default:
+			c.Failure = problem
+			s.Failures++
+		}
+		s.Tests++
+		s.duration += result.Duration
+		s.Cases = append(s.Cases, c)
+	}
+	sort.Strings(names)
+	root := &junitTestSuites{Name: "tc39"}
+	var total time.Duration
+	for _, name := range names {
+		s := suites[name]
+		s.Time = junitTime(s.duration)
+		root.Tests, root.Failures, root.Errors, root.Skipped = root.Tests+s.Tests, root.Failures+s.Failures,
+			root.Errors+s.Errors, root.Skipped+s.Skipped
+		total += s.duration
+		root.Suites = append(root.Suites, *s)
+	}
+	root.Time = junitTime(total)
+	return root
+}
+
+func writeJUnit(file string, suites *junitTestSuites) error {
+	return writeArtifact(file, func(w io.Writer) error {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(suites); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	})
+}
+
+func TestJUnit(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.expectedErrors = map[string]string{
+		"test/dump/fail.js-strict:true": `~Test262Error: Expected SameValue\(«a\d», «b»\) to be true`,
+	}
+	t.Run("tc39", func(t *testing.T) {
+		for _, name := range []string{"test/compat/es5.js", "test/dump/fail.js"} {
+			name := name
+			t.Run(name, func(t *testing.T) {
+				ctx.runTC39File(name, name, &tc39CountingTB{TB: t})
+			})
+		}
+	})
+	results := ctx.results.resultsCopy()
+	for nameKey, result := range results {
+		// the stacks have the positions in the harness, the durations differ from run to run
+		result.Details, result.Duration = "", 1500*time.Microsecond
+		results[nameKey] = result
+	}
+	weird := "test/language/weird.js: SyntaxError: \x1b[31m<unexpected> & \"]]>\" '\x00'\x1b[0m\n  > 1 | a\tb"
+	results["test/language/weird.js-strict:false"] = TestResult{
+		Category: CategoryChangedFailure, Message: weird, Details: "stack:\n\tat test/language/weird.js", Duration: 2 * time.Millisecond,
+	}
+	results["test/language/panic.js-strict:true-mode:base"] = TestResult{Category: CategoryPanic, Message: "panic: test/language/panic.js: x"}
+	results["test/built-ins/bigint.js-strict:false"] = TestResult{Category: CategorySkippedFeature, Message: "Blacklisted feature BigInt"}
+	results["own/a.js-strict:true"] = TestResult{Category: CategoryTimeout, Message: "own/a.js: the test didn't finish in TC39_TEST_TIMEOUT (1s)", Duration: time.Second}
+	suites := newJUnit(results)
+
+	dir, err := ioutil.TempDir("", "tc39-junit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, "junit.xml")
+	require.NoError(t, writeJUnit(file, suites))
+	b, err := ioutil.ReadFile(file) //nolint:gosec
+	require.NoError(t, err)
+	if os.Getenv("TC39_UPDATE_REPORT_GOLDEN") != "" {
+		require.NoError(t, ioutil.WriteFile(tc39JUnitGolden, b, 0o644))
+	}
+	golden, err := ioutil.ReadFile(tc39JUnitGolden)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(b))
+
+	// it parses back, with the messages as they were but for the characters XML can't have
+	var parsed junitTestSuites
+	require.NoError(t, xml.Unmarshal(b, &parsed))
+	require.Equal(t, 8, parsed.Tests)
+	require.Equal(t, 4, parsed.Failures)
+	require.Equal(t, 1, parsed.Errors)
+	require.Equal(t, 1, parsed.Skipped)
+	require.Equal(t, "1.008", parsed.Time)
+	var names []string
+	for _, s := range parsed.Suites {
+		names = append(names, s.Name)
+	}
+	require.Equal(t, []string{"built-ins", "compat", "dump", "language", "own"}, names)
+	language := parsed.Suites[3]
+	require.Equal(t, "0.002", language.Time)
+	require.Equal(t, "test/language/weird.js", language.Cases[1].ClassName)
+	require.Equal(t, "strict:false", language.Cases[1].Name)
+	want := strings.NewReplacer("\x1b", "�", "\x00", "�").Replace(weird)
+	require.Equal(t, want, language.Cases[1].Failure.Message)
+	require.Equal(t, want+"\nstack:\n\tat test/language/weird.js", language.Cases[1].Failure.Text)
+	require.Equal(t, "changed-failure", language.Cases[1].Failure.Type)
+	require.Equal(t, "strict:true-mode:base", language.Cases[0].Name)
+	require.NotNil(t, language.Cases[0].Error)
+	require.Equal(t, "test/compat/es5.js", parsed.Suites[1].Cases[0].ClassName)
+	require.Nil(t, parsed.Suites[1].Cases[0].Failure)
+}
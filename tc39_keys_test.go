@@ -0,0 +1,332 @@
+package test262
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39ResultsSchemaVersion is the version of the files with structured result keys, the
+// manifest and results.json. It's bumped whenever a field is added to the key or changes
+// meaning, so a consumer can tell which dimensions a file has.
+const tc39ResultsSchemaVersion = 1
+
+const tc39ResultsFile = "results.json"
+
+// tc39ResultKey is a test variant with every dimension that tells it apart. The flat
+// name-strict:bool keys of breaking_test_errors.json only have the name and strictness for the
+// default mode, those of the other modes have a -mode:<mode> suffix.
+type tc39ResultKey struct {
+	// Suite is the name of the extra suite of the test, empty for test262.
+	Suite string `json:"suite,omitempty"`
+	// Test is the slash separated path of the test relative to the root of its suite.
+	Test   string `json:"test"`
+	Strict bool   `json:"strict"`
+	// Mode is the compatibility mode the test ran with.
+	Mode string `json:"mode"`
+}
+
+// tc39DefaultMode is the mode of the legacy keys without a -mode: suffix.
+//nolint:gochecknoglobals
+var tc39DefaultMode = lib.CompatibilityModeExtended.String()
+
+type tc39ResultRecord struct {
+	Key      tc39ResultKey `json:"key"`
+	Category string        `json:"category,omitempty"`
+	Message  string        `json:"message,omitempty"`
+	Expected string        `json:"expected,omitempty"`
+	Details  string        `json:"details,omitempty"`
+	Native   string        `json:"native,omitempty"`
+}
+
+type tc39ResultsDoc struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Results       []tc39ResultRecord `json:"results"`
+	// Transitions has the variants that stopped passing, changed their error or got fixed, nil for
+	// a run where none did.
+	Transitions *tc39TransitionsDoc `json:"transitions,omitempty"`
+}
+
+// legacy returns the flat key of a variant, an error if it has a dimension a flat key can't
+// express.
+func (k tc39ResultKey) legacy() (string, error) {
+	mode, err := lib.CompatibilityModeString(k.Mode)
+	if err != nil {
+		return "", fmt.Errorf("%s runs in the %s mode, which a flat key can't express", path.Join(k.Suite, k.Test), k.Mode)
+	}
+	return modeKey(path.Join(k.Suite, k.Test), k.Strict, mode), nil
+}
+
+// parseLegacyKey returns the structured key of a flat one, the first directory of its name being
+// the suite if it's one of the extra suites.
+func parseLegacyKey(nameKey string, suites []string) (tc39ResultKey, error) {
+	mode := tc39DefaultMode
+	if i := strings.LastIndex(nameKey, "-mode:"); i > 0 {
+		if m, err := lib.CompatibilityModeString(nameKey[i+len("-mode:"):]); err == nil && m.String() != tc39DefaultMode {
+			mode, nameKey = m.String(), nameKey[:i]
+		}
+	}
+	i := strings.LastIndex(nameKey, "-strict:")
+	if i <= 0 {
+		return tc39ResultKey{}, fmt.Errorf("%q isn't a name-strict:bool key", nameKey)
+	}
+	var strict bool
+	switch nameKey[i+len("-strict:"):] {
+	case "true":
+		strict = true
+	case "false":
+	default:
+		return tc39ResultKey{}, fmt.Errorf("%q isn't a name-strict:bool key", nameKey)
+	}
+	k := tc39ResultKey{Test: nameKey[:i], Strict: strict, Mode: mode}
+	for _, suite := range suites {
+		if strings.HasPrefix(k.Test, suite+"/") {
+			k.Suite, k.Test = suite, k.Test[len(suite)+1:]
+			break
+		}
+	}
+	return k, nil
+}
+
+// recordsFromLegacy converts a flat map, like breaking_test_errors.json, to records sorted by
+// their flat keys.
+func recordsFromLegacy(legacy map[string]string, suites []string) ([]tc39ResultRecord, error) {
+	nameKeys := make([]string, 0, len(legacy))
+	for nameKey := range legacy {
+		nameKeys = append(nameKeys, nameKey)
+	}
+	sort.Strings(nameKeys)
+	records := make([]tc39ResultRecord, 0, len(nameKeys))
+	for _, nameKey := range nameKeys {
+		k, err := parseLegacyKey(nameKey, suites)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, tc39ResultRecord{Key: k, Message: legacy[nameKey]})
+	}
+	return records, nil
+}
+
+// legacyFromRecords converts records back to a flat map of their messages.
+func legacyFromRecords(records []tc39ResultRecord) (map[string]string, error) {
+	legacy := make(map[string]string, len(records))
+	for _, r := range records {
+		nameKey, err := r.Key.legacy()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := legacy[nameKey]; ok {
+			return nil, fmt.Errorf("%s is there twice", nameKey)
+		}
+		legacy[nameKey] = r.Message
+	}
+	return legacy, nil
+}
+
+// readExpectedErrors reads a baseline in any format, see readExpectedEntries, as the flat map the
+// runner compares the errors with.
+func readExpectedErrors(b []byte) (map[string]string, error) {
+	entries, err := readExpectedEntries(b)
+	if err != nil {
+		return nil, err
+	}
+	return errorsOf(entries), nil
+}
+
+// readVersionedErrors reads a baseline that's a versioned document as a flat map.
+func readVersionedErrors(b []byte) (map[string]string, error) {
+	var doc tc39ResultsDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, jsonError(b, err)
+	}
+	if doc.SchemaVersion != tc39ResultsSchemaVersion {
+		return nil, fmt.Errorf("the schema version is %d, only %d is supported", doc.SchemaVersion, tc39ResultsSchemaVersion)
+	}
+	return legacyFromRecords(doc.Results)
+}
+
+// suiteNames returns the names of the extra suites.
+func (ctx *tc39TestCtx) suiteNames() []string {
+	names := make([]string, len(ctx.extraSuites))
+	for i, s := range ctx.extraSuites {
+		names[i] = s.name
+	}
+	return names
+}
+
+// writeResults writes all the results of the run with structured keys.
+func (ctx *tc39TestCtx) writeResults(file string) error {
+	results := ctx.results.resultsCopy()
+	doc := tc39ResultsDoc{SchemaVersion: tc39ResultsSchemaVersion, Results: make([]tc39ResultRecord, 0, len(results))}
+	for nameKey, result := range results {
+		k, err := parseLegacyKey(nameKey, ctx.suiteNames())
+		if err != nil {
+			return err
+		}
+		doc.Results = append(doc.Results, tc39ResultRecord{
+			Key: k, Category: result.Category.String(), Message: result.Message, Expected: result.Expected,
+			Details: result.Details, Native: result.Native,
+		})
+	}
+	sort.Slice(doc.Results, func(i, j int) bool {
+		a, b := doc.Results[i].Key, doc.Results[j].Key
+		if a.Suite != b.Suite {
+			return a.Suite < b.Suite
+		}
+		if a.Test != b.Test {
+			return a.Test < b.Test
+		}
+		if a.Strict != b.Strict {
+			return !a.Strict
+		}
+		return a.Mode < b.Mode
+	})
+	if tr := ctx.transitions(results); !tr.empty() {
+		var err error
+		if doc.Transitions, err = tr.doc(ctx.suiteNames()); err != nil {
+			return err
+		}
+	}
+	return writeArtifact(file, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	})
+}
+
+func TestResultKeys(t *testing.T) {
+	suites := []string{"k6tests", "own"}
+	for nameKey, expected := range map[string]tc39ResultKey{
+		"test/built-ins/Array/length.js-strict:false": {Test: "test/built-ins/Array/length.js", Mode: "extended"},
+		"test/built-ins/Array/length.js-strict:true":  {Test: "test/built-ins/Array/length.js", Strict: true, Mode: "extended"},
+		"k6tests/foo.js-strict:true":                  {Suite: "k6tests", Test: "foo.js", Strict: true, Mode: "extended"},
+		"own/sub/bar.js-strict:false":                 {Suite: "own", Test: "sub/bar.js", Mode: "extended"},
+		// only a whole first directory is a suite
+		"ownership/a.js-strict:false": {Test: "ownership/a.js", Mode: "extended"},
+		// only the last -strict: is the suffix
+		"test/a-strict:true.js-strict:false": {Test: "test/a-strict:true.js", Mode: "extended"},
+		"test/a.js-strict:true-mode:base":    {Test: "test/a.js", Strict: true, Mode: "base"},
+		"own/bar.js-strict:false-mode:base":  {Suite: "own", Test: "bar.js", Mode: "base"},
+	} {
+		k, err := parseLegacyKey(nameKey, suites)
+		require.NoError(t, err, nameKey)
+		require.Equal(t, expected, k, nameKey)
+		back, err := k.legacy()
+		require.NoError(t, err)
+		require.Equal(t, nameKey, back)
+	}
+	for _, nameKey := range []string{"", "test/a.js", "-strict:true", "test/a.js-strict:", "test/a.js-strict:1", "test/a.js-strict:TRUE",
+		// the default mode has no suffix
+		"test/a.js-strict:true-mode:extended", "test/a.js-strict:true-mode:es6", "test/a.js-mode:base"} {
+		_, err := parseLegacyKey(nameKey, suites)
+		require.Error(t, err, nameKey)
+	}
+	_, err := tc39ResultKey{Test: "test/a.js", Mode: "es6"}.legacy()
+	require.EqualError(t, err, "test/a.js runs in the es6 mode, which a flat key can't express")
+
+	legacy := map[string]string{
+		"test/a.js-strict:false":     "test/a.js: Test262Error: a",
+		"test/a.js-strict:true":      "test/a.js: Test262Error: a",
+		"k6tests/foo.js-strict:true": "k6tests/foo.js: TypeError: b",
+	}
+	records, err := recordsFromLegacy(legacy, suites)
+	require.NoError(t, err)
+	require.Equal(t, []tc39ResultRecord{
+		{Key: tc39ResultKey{Suite: "k6tests", Test: "foo.js", Strict: true, Mode: "extended"}, Message: "k6tests/foo.js: TypeError: b"},
+		{Key: tc39ResultKey{Test: "test/a.js", Mode: "extended"}, Message: "test/a.js: Test262Error: a"},
+		{Key: tc39ResultKey{Test: "test/a.js", Strict: true, Mode: "extended"}, Message: "test/a.js: Test262Error: a"},
+	}, records)
+	back, err := legacyFromRecords(records)
+	require.NoError(t, err)
+	require.Equal(t, legacy, back)
+	_, err = legacyFromRecords(append(records, records[0]))
+	require.EqualError(t, err, "k6tests/foo.js-strict:true is there twice")
+
+	// the baseline can be in either format
+	b, err := json.Marshal(tc39ResultsDoc{SchemaVersion: tc39ResultsSchemaVersion, Results: records})
+	require.NoError(t, err)
+	for _, src := range [][]byte{b, []byte(`{"test/a.js-strict:false": "test/a.js: Test262Error: a",
+		"test/a.js-strict:true": "test/a.js: Test262Error: a", "k6tests/foo.js-strict:true": "k6tests/foo.js: TypeError: b"}`)} {
+		read, err := readExpectedErrors(src)
+		require.NoError(t, err)
+		require.Equal(t, legacy, read)
+	}
+	_, err = readExpectedErrors([]byte(`{"schemaVersion": 2, "results": []}`))
+	require.EqualError(t, err, "the schema version is 2, only 1 is supported")
+	read, err := readExpectedErrors([]byte(`{}`))
+	require.NoError(t, err)
+	require.Empty(t, read)
+}
+
+func TestWriteResults(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	var err error
+	ctx.extraSuites, err = extraSuites(ctx.base, "testdata/suites/own")
+	require.NoError(t, err)
+	ctx.results.recordResult("own/bar.js-strict:false", TestResult{Category: CategoryPass})
+	ctx.results.recordResult("test/b.js-strict:true", TestResult{Category: CategoryNewFailure, Message: "test/b.js: boom"})
+	ctx.results.recordResult("test/b.js-strict:false", TestResult{Category: CategorySkippedFeature, Message: "Blacklisted feature BigInt"})
+
+	dir, err := ioutil.TempDir("", "tc39-results")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, tc39ResultsFile)
+	require.NoError(t, ctx.writeResults(file))
+	b, err := ioutil.ReadFile(file) //nolint:gosec
+	require.NoError(t, err)
+	require.Equal(t, `{
+  "schemaVersion": 1,
+  "results": [
+    {
+      "key": {
+        "test": "test/b.js",
+        "strict": false,
+        "mode": "extended"
+      },
+      "category": "skipped-feature",
+      "message": "Blacklisted feature BigInt"
+    },
+    {
+      "key": {
+        "test": "test/b.js",
+        "strict": true,
+        "mode": "extended"
+      },
+      "category": "new-failure",
+      "message": "test/b.js: boom"
+    },
+    {
+      "key": {
+        "suite": "own",
+        "test": "bar.js",
+        "strict": false,
+        "mode": "extended"
+      },
+      "category": "pass"
+    }
+  ],
+  "transitions": {
+    "newFailures": 1,
+    "changedFailures": 0,
+    "fixed": 0,
+    "new": [
+      {
+        "test": "test/b.js",
+        "strict": true,
+        "mode": "extended"
+      }
+    ]
+  }
+}
+`, string(b))
+}
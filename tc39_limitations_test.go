@@ -0,0 +1,113 @@
+package test262
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// tc39KnownLimitationsFile lists the features not everything works for, it's regenerated in
+	// update mode (TC39_UPDATE_EXPECTED=1).
+	tc39KnownLimitationsFile = "./known_limitations.json"
+	tc39LimitationExamples   = 3
+)
+
+// tc39Limitation summarizes the failing tests of a feature.
+type tc39Limitation struct {
+	Tests   int `json:"tests"`
+	Failing int `json:"failing"`
+	// Error is the error of the first failing test, by name.
+	Error    string   `json:"error"`
+	Examples []string `json:"examples"`
+}
+
+// knownLimitations groups the failures by feature, leaving out the ones all tests pass for. It
+// only depends on the names of the tests, so it doesn't change between runs with the same results.
+func knownLimitations(tests map[string][]string, failures map[string]string) map[string]*tc39Limitation {
+	names := make([]string, 0, len(tests))
+	for name := range tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	all := make(map[string]*tc39Limitation)
+	for _, name := range names {
+		errStr, failed := failures[name]
+		for _, feature := range tests[name] {
+			l := all[feature]
+			if l == nil {
+				l = &tc39Limitation{Examples: make([]string, 0, tc39LimitationExamples)}
+				all[feature] = l
+			}
+			l.Tests++
+			if !failed {
+				continue
+			}
+			if l.Failing == 0 {
+				l.Error = errStr
+			}
+			l.Failing++
+			if len(l.Examples) < tc39LimitationExamples {
+				l.Examples = append(l.Examples, name)
+			}
+		}
+	}
+	for feature, l := range all {
+		if l.Failing == 0 {
+			delete(all, feature)
+		}
+	}
+	return all
+}
+
+func (ctx *tc39TestCtx) writeKnownLimitations(name string) error {
+	tests, failures := ctx.results.testsRun()
+	b, err := json.MarshalIndent(knownLimitations(tests, failures), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeArtifact(name, func(w io.Writer) error {
+		_, err := w.Write(append(b, '\n'))
+		return err
+	})
+}
+
+func TestKnownLimitations(t *testing.T) {
+	tests := map[string][]string{
+		"test/built-ins/Proxy/a.js":    {"Proxy"},
+		"test/built-ins/Proxy/b.js":    {"Proxy", "Reflect"},
+		"test/built-ins/Proxy/c.js":    {"Proxy"},
+		"test/built-ins/Proxy/d.js":    {"Proxy"},
+		"test/built-ins/Proxy/e.js":    {"Proxy"},
+		"test/built-ins/Reflect/a.js":  {"Reflect"},
+		"test/built-ins/Symbol/a.js":   {"Symbol"},
+		"test/language/no-features.js": nil,
+	}
+	failures := map[string]string{
+		"test/built-ins/Proxy/e.js":    "e failed",
+		"test/built-ins/Proxy/d.js":    "d failed",
+		"test/built-ins/Proxy/c.js":    "c failed",
+		"test/built-ins/Proxy/b.js":    "b failed",
+		"test/language/no-features.js": "failed",
+	}
+	expected := map[string]*tc39Limitation{
+		"Proxy": {
+			Tests: 5, Failing: 4, Error: "b failed",
+			Examples: []string{"test/built-ins/Proxy/b.js", "test/built-ins/Proxy/c.js", "test/built-ins/Proxy/d.js"},
+		},
+		"Reflect": {Tests: 2, Failing: 1, Error: "b failed", Examples: []string{"test/built-ins/Proxy/b.js"}},
+	}
+	require.Equal(t, expected, knownLimitations(tests, failures))
+
+	first, err := json.Marshal(knownLimitations(tests, failures))
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		b, err := json.Marshal(knownLimitations(tests, failures))
+		require.NoError(t, err)
+		require.Equal(t, string(first), string(b))
+	}
+}
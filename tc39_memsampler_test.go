@@ -0,0 +1,164 @@
+package test262
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+const tc39MemSampleInterval = 100 * time.Millisecond
+
+type tc39MemSample struct {
+	at        time.Time
+	heapAlloc uint64
+}
+
+// tc39MemSampler periodically records runtime.MemStats.HeapAlloc so the peak heap usage can be
+// attributed to the tests that were running at that time.
+type tc39MemSampler struct {
+	mu      sync.Mutex
+	samples []tc39MemSample
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func startMemSampler(interval time.Duration) *tc39MemSampler {
+	return startMemSamplerOf(interval, readHeapAlloc)
+}
+
+func readHeapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// startMemSamplerOf samples heapAlloc every interval. A sample is taken at the time heapAlloc
+// returned, which can be well after the tick as reading the stats stops the world.
+func startMemSamplerOf(interval time.Duration, heapAlloc func() uint64) *tc39MemSampler {
+	s := &tc39MemSampler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				sample := tc39MemSample{heapAlloc: heapAlloc()}
+				sample.at = time.Now()
+				s.mu.Lock()
+				s.samples = append(s.samples, sample)
+				s.mu.Unlock()
+			}
+		}
+	}()
+	return s
+}
+
+func (s *tc39MemSampler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// peak returns the highest HeapAlloc sampled between start and end, or 0 if no sample was taken
+// in that window.
+func (s *tc39MemSampler) peak(start, end time.Time) (peak uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.samples), func(i int) bool {
+		return !s.samples[i].at.Before(start)
+	})
+	for ; i < len(s.samples) && !s.samples[i].at.After(end); i++ {
+		if s.samples[i].heapAlloc > peak {
+			peak = s.samples[i].heapAlloc
+		}
+	}
+	return peak
+}
+
+func TestMemSampler(t *testing.T) {
+	// every value sent is sampled, the sampler reads 0 in between
+	values := make(chan uint64)
+	s := startMemSamplerOf(time.Millisecond, func() uint64 {
+		select {
+		case v := <-values:
+			return v
+		default:
+			return 0
+		}
+	})
+	defer s.Stop()
+	// sending a value waits for the one before it to be recorded
+	start := time.Now()
+	for _, v := range []uint64{1, 5, 3, 0} {
+		values <- v
+	}
+	end := time.Now()
+	values <- 100
+	values <- 0
+	require.Equal(t, uint64(5), s.peak(start, end))
+	require.Equal(t, uint64(100), s.peak(start, time.Now()))
+	require.Equal(t, uint64(0), s.peak(start.Add(-time.Hour), start.Add(-time.Minute)))
+}
+
+// tc39BeforeHook calls before before each test.
+type tc39BeforeHook struct {
+	before func()
+}
+
+func (h tc39BeforeHook) Before(tc *TestInfo, vm *goja.Runtime) error {
+	h.before()
+	return nil
+}
+
+func (h tc39BeforeHook) After(tc *TestInfo, result *TestResult) {}
+
+func TestMemSamplerPeak(t *testing.T) {
+	var heap uint64 = 1 << 20
+	sampled := make(chan struct{})
+	ctx := newFixtureCtx(t)
+	ctx.enableBench = true
+	ctx.benchIterations = 1
+	ctx.benchHook = newBenchHook()
+	ctx.memSampler = startMemSamplerOf(time.Millisecond, func() uint64 {
+		select {
+		case sampled <- struct{}{}:
+		default:
+		}
+		return atomic.LoadUint64(&heap)
+	})
+	defer ctx.memSampler.Stop()
+	// the test only runs once the heap was sampled twice, so the first sample is recorded
+	ctx.opts.Hooks = []TestHook{ctx.benchHook, tc39BeforeHook{before: func() {
+		atomic.StoreUint64(&heap, 64<<20)
+		<-sampled
+		<-sampled
+	}}}
+
+	defer func(old int) { parallelism = old }(parallelism)
+	parallelism = 1
+	v := ctx.benchTC39Test(t, "test/bench.js", `var a = 1;`, &tc39Meta{}, true)
+	require.Equal(t, uint64(64<<20), v.peakHeap)
+	require.False(t, v.peakHeapUnattributed)
+	require.Equal(t, "64.0", v.peakHeapString())
+
+	// with other tests running at the same time it can be any of theirs
+	parallelism = 2
+	v = ctx.benchTC39Test(t, "test/bench.js", `var a = 1;`, &tc39Meta{}, false)
+	require.Equal(t, uint64(64<<20), v.peakHeap)
+	require.True(t, v.peakHeapUnattributed)
+	require.Equal(t, "(64.0)", v.peakHeapString())
+
+	// no sample while a test ran
+	require.Equal(t, "-", tc39BenchmarkVariant{}.peakHeapString())
+}
@@ -0,0 +1,161 @@
+package test262
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNegative(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-meta")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	tests := []struct {
+		name, frontmatter string
+		expected          TC39MetaNegative
+	}{
+		{"mapping", "negative:\n  phase: parse\n  type: SyntaxError\n", TC39MetaNegative{Phase: "parse", Type: "SyntaxError"}},
+		{"resolution", "negative:\n  phase: resolution\n  type: SyntaxError\nflags: [module]\n",
+			TC39MetaNegative{Phase: "resolution", Type: "SyntaxError"}},
+		{"shorthand", "negative: SyntaxError\n", TC39MetaNegative{Phase: "early", Type: "SyntaxError"}},
+		{"positive", "description: positive\n", TC39MetaNegative{}},
+		// the last one wins, whatever its form
+		{"shorthand then mapping", "negative: ReferenceError\nnegative:\n  phase: runtime\n  type: TypeError\n",
+			TC39MetaNegative{Phase: "runtime", Type: "TypeError"}},
+		{"mapping then shorthand", "negative:\n  phase: runtime\n  type: TypeError\nnegative: ReferenceError\n",
+			TC39MetaNegative{Phase: "early", Type: "ReferenceError"}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(dir, "test.js")
+			src := "/*---\n" + test.frontmatter + "---*/\nvar x;\n"
+			require.NoError(t, ioutil.WriteFile(file, []byte(src), 0o644))
+			meta, _, err := parseTC39File(file, dir)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, meta.Negative)
+		})
+	}
+
+	file := filepath.Join(dir, "test.js")
+	require.NoError(t, ioutil.WriteFile(file, []byte("/*---\nnegative: [SyntaxError]\n---*/\n"), 0o644))
+	_, _, err = parseTC39File(file, dir)
+	require.Error(t, err)
+
+	require.NoError(t, ioutil.WriteFile(file, []byte("/*---\nnegative:\n  phase: link\n  type: SyntaxError\n---*/\n"), 0o644))
+	_, _, err = parseTC39File(file, dir)
+	require.EqualError(t, err, `unknown negative phase "link"`)
+}
+
+func TestNegativePhases(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	tb := &tc39CountingTB{TB: t}
+	parse := &tc39Meta{Negative: TC39MetaNegative{Phase: "parse", Type: "SyntaxError"}}
+	ctx.runTC39Test(tb, "test/parse.js", "var 1;", parse, false)
+	ctx.runTC39Test(tb, "test/parse-at-runtime.js", "throw new SyntaxError();", parse, false)
+	// the import only fails once it runs, when the require it's compiled to can't load the module
+	resolution := &tc39Meta{Negative: TC39MetaNegative{Phase: "resolution", Type: "TypeError"}, Flags: []string{"module"}}
+	ctx.runTC39Test(tb, "test/resolution.js", `import { x } from "./missing.js";`, resolution, false)
+	ctx.runTC39Test(tb, "test/resolution-at-parse.js", "var 1;", resolution, false)
+
+	results := ctx.results.resultsCopy()
+	require.Equal(t, CategoryPass, results["test/parse.js-strict:false"].Category)
+	require.Equal(t, CategoryPass, results["test/resolution.js-strict:false"].Category)
+	for _, name := range []string{"test/parse-at-runtime.js", "test/resolution-at-parse.js"} {
+		result := results[name+"-strict:false"]
+		require.Equal(t, CategoryNewFailure, result.Category, name)
+		require.Contains(t, result.Message, "happened at the wrong phase", name)
+	}
+	require.Equal(t, 2, tb.errors)
+}
+
+func TestParseFrontmatter(t *testing.T) {
+	meta, _, err := parseTC39File(filepath.Join(tc39FixturesBase, "test", "frontmatter.js"), tc39FixturesBase)
+	require.NoError(t, err)
+	require.Equal(t, &tc39Meta{
+		Negative:    TC39MetaNegative{Phase: "runtime", Type: "TypeError"},
+		Includes:    []string{"compareArray.js"},
+		Flags:       []string{"noStrict"},
+		Features:    []string{"Symbol"},
+		Es5id:       "10.1.1_1",
+		Es6id:       "10.1.1",
+		Esid:        "sec-intl.collator",
+		Description: "A test with every field of the frontmatter.\n",
+		Info:        "The info block, kept\nas it is.\n",
+		Author:      "Jane Doe",
+		Locale:      []string{"en-US", "de"},
+	}, meta)
+
+	require.Equal(t, "test/a.js: Test262Error: x (A test with every field of the frontmatter.)",
+		withDescription("test/a.js: Test262Error: x", meta.Description))
+	require.Equal(t, "test/a.js: Test262Error: x", withDescription("test/a.js: Test262Error: x", " \n"))
+
+	// the baseline may have the error with and without the description
+	ctx := newFixtureCtx(t)
+	ctx.expectedErrors = map[string]string{
+		"test/legacy.js-strict:false":  "test/legacy.js: boom",
+		"test/current.js-strict:false": "test/current.js: boom (what it checks)",
+	}
+	for _, name := range []string{"test/legacy.js", "test/current.js"} {
+		require.Equal(t, CategoryExpectedFailure, ctx.fail(t, name, false, lib.CompatibilityModeExtended, name+": boom", "what it checks"))
+	}
+	tb := &tc39CountingTB{TB: t}
+	require.Equal(t, CategoryChangedFailure, ctx.fail(tb, "test/legacy.js", false, lib.CompatibilityModeExtended, "test/legacy.js: bang", "what it checks"))
+}
+
+func TestParseFlagsAndIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-meta")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, "test.js")
+	parse := func(frontmatter string) error {
+		require.NoError(t, ioutil.WriteFile(file, []byte("/*---\n"+frontmatter+"---*/\n"), 0o644))
+		_, _, err := parseTC39File(file, tc39FixturesBase)
+		return err
+	}
+
+	require.NoError(t, parse("flags: [onlyStrict, generated, CanBlockIsFalse]\nincludes: [compareArray.js]\n"))
+
+	err = parse("flags: [onlyStrict, onlyStirct]\n")
+	var unknown *unknownFlagError
+	require.True(t, errors.As(err, &unknown), "%v", err)
+	require.EqualError(t, err, `unknown flag "onlyStirct"`)
+
+	err = parse("includes: [compareArray.js, missing.js]\n")
+	require.Error(t, err)
+	require.True(t, os.IsNotExist(errors.Unwrap(err)), "%v", err)
+	require.Contains(t, err.Error(), "the included testdata/fixtures/harness/missing.js can't be used: ")
+}
+
+func TestParseLineEndings(t *testing.T) {
+	for name, description := range map[string]string{
+		"bom.js":  "Saved with a byte order mark.\n",
+		"crlf.js": "Saved with Windows line endings.\n",
+	} {
+		file := filepath.Join(tc39FixturesBase, "test", name)
+		meta, src, err := parseTC39File(file, tc39FixturesBase)
+		require.NoError(t, err, name)
+		require.Equal(t, description, meta.Description, name)
+		require.Equal(t, []string{"noStrict"}, meta.Flags, name)
+
+		b, err := ioutil.ReadFile(file) //nolint:gosec
+		require.NoError(t, err)
+		require.Equal(t, strings.TrimPrefix(string(b), "\ufeff"), src, name)
+	}
+	_, src, err := parseTC39File(filepath.Join(tc39FixturesBase, "test", "crlf.js"), tc39FixturesBase)
+	require.NoError(t, err)
+	require.Contains(t, src, "var x = 1;\r\n")
+
+	ctx := newFixtureCtx(t)
+	for _, name := range []string{"test/bom.js", "test/crlf.js"} {
+		ctx.runTC39File(name, name, t)
+		require.Equal(t, CategoryPass, ctx.results.resultsCopy()[name+"-strict:false"].Category, name)
+	}
+}
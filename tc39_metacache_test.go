@@ -0,0 +1,196 @@
+package test262
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39MetaManifestFile caches the frontmatter of every test262 test between the runs, the
+// checkout being pinned it only changes when it's updated. It's next to the checkout and not in
+// the artifacts, which are only of one run.
+const tc39MetaManifestFile = "testdata/manifest.json"
+
+// tc39MetaManifestVersion is bumped whenever tc39Meta changes, as the entries of an older manifest
+// would miss what was added.
+const tc39MetaManifestVersion = 1
+
+type tc39MetaEntry struct {
+	Size    int64     `json:"size"`
+	ModTime int64     `json:"mtime"`
+	Meta    *tc39Meta `json:"meta"`
+}
+
+type tc39MetaManifestDoc struct {
+	SchemaVersion int                      `json:"schemaVersion"`
+	Files         map[string]tc39MetaEntry `json:"files"`
+}
+
+// tc39MetaManifest has the frontmatter of the tests below root by their slash separated path,
+// with the size and mtime of the file it was parsed from. A file whose size or mtime differ is
+// parsed again, so an updated checkout only costs parsing what changed.
+type tc39MetaManifest struct {
+	root string
+
+	mu      sync.Mutex
+	entries map[string]tc39MetaEntry
+	changed int
+}
+
+// loadMetaManifest reads the manifest in file, generating it by parsing every test below root if
+// there's none, it's of another version or rebuild is set. The returned note says which it was.
+func loadMetaManifest(file, root string, rebuild bool) (*tc39MetaManifest, string, error) {
+	m := &tc39MetaManifest{root: root, entries: make(map[string]tc39MetaEntry)}
+	reason := "TC39_REBUILD_MANIFEST is set"
+	if !rebuild {
+		b, err := ioutil.ReadFile(file) //nolint:gosec
+		switch {
+		case os.IsNotExist(err):
+			reason = "there's none"
+		case err != nil:
+			return nil, "", err
+		default:
+			var doc tc39MetaManifestDoc
+			if err = json.Unmarshal(b, &doc); err != nil {
+				return nil, "", fmt.Errorf("%s: %w", file, err)
+			}
+			if doc.SchemaVersion == tc39MetaManifestVersion {
+				m.entries = doc.Files
+				return m, "", nil
+			}
+			reason = fmt.Sprintf("it's of version %d", doc.SchemaVersion)
+		}
+	}
+	d, err := discoverTests(root, "test")
+	if err != nil {
+		return nil, "", err
+	}
+	for _, rel := range d.names {
+		// a test that can't be parsed is reported when it runs
+		_, _, _ = m.meta(d.path(rel))
+	}
+	return m, fmt.Sprintf("generated %s with %d tests, as %s", file, len(m.entries), reason), nil
+}
+
+// meta returns the frontmatter of the test file, relative to the root. The source is only
+// returned if the file had to be parsed, it's empty if the frontmatter came from the manifest.
+func (m *tc39MetaManifest) meta(file string) (*tc39Meta, string, error) {
+	name := osPath(m.root, file)
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, "", err
+	}
+	m.mu.Lock()
+	e, ok := m.entries[file]
+	m.mu.Unlock()
+	if ok && e.Size == fi.Size() && e.ModTime == fi.ModTime().UnixNano() {
+		// a copy, the runner changes the frontmatter of a test
+		meta := *e.Meta
+		return &meta, "", nil
+	}
+	meta, src, err := parseTC39File(name, m.root)
+	if err != nil {
+		return nil, "", err
+	}
+	cached := *meta
+	m.mu.Lock()
+	m.entries[file] = tc39MetaEntry{Size: fi.Size(), ModTime: fi.ModTime().UnixNano(), Meta: &cached}
+	m.changed++
+	m.mu.Unlock()
+	return meta, src, nil
+}
+
+// write writes the manifest to file if any of its entries changed since it was read.
+func (m *tc39MetaManifest) write(file string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.changed == 0 {
+		return nil
+	}
+	err := writeArtifact(file, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(tc39MetaManifestDoc{SchemaVersion: tc39MetaManifestVersion, Files: m.entries})
+	})
+	if err == nil {
+		m.changed = 0
+	}
+	return err
+}
+
+// readMeta returns the frontmatter of the test file of the suite s, from the manifest for
+// test262 if there's one, and its source if it had to be read for it.
+func (ctx *tc39TestCtx) readMeta(s tc39Suite, file string) (*tc39Meta, string, error) {
+	if s.name != "" || ctx.metaManifest == nil {
+		return parseTC39File(osPath(s.root, file), s.harness)
+	}
+	return ctx.metaManifest.meta(file)
+}
+
+func TestMetaManifest(t *testing.T) {
+	root, err := ioutil.TempDir("", "tc39-manifest")
+	require.NoError(t, err)
+	defer os.RemoveAll(root) //nolint:errcheck
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "test", "sub"), 0o755))
+	src, err := ioutil.ReadFile(filepath.Join(tc39FixturesBase, "test", "frontmatter.js"))
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "harness"), 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "harness", "compareArray.js"), nil, 0o644))
+	test := filepath.Join(root, "test", "sub", "a.js")
+	require.NoError(t, ioutil.WriteFile(test, src, 0o644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "test", "broken.js"), []byte("no frontmatter"), 0o644))
+	file := filepath.Join(root, "manifest.json")
+
+	m, note, err := loadMetaManifest(file, root, false)
+	require.NoError(t, err)
+	require.Equal(t, "generated "+file+" with 1 tests, as there's none", note)
+	require.NoError(t, m.write(file))
+
+	expected, _, err := parseTC39File(test, root)
+	require.NoError(t, err)
+	m, note, err = loadMetaManifest(file, root, false)
+	require.NoError(t, err)
+	require.Empty(t, note)
+	meta, cachedSrc, err := m.meta("test/sub/a.js")
+	require.NoError(t, err)
+	require.Equal(t, expected, meta)
+	require.Empty(t, cachedSrc, "the source isn't read for a cached entry")
+	meta.Output = new(string)
+	meta, _, err = m.meta("test/sub/a.js")
+	require.NoError(t, err)
+	require.Nil(t, meta.Output, "the cached entry can't be changed through what was returned")
+	require.Equal(t, 0, m.changed)
+
+	// a changed file is parsed again
+	changed := []byte(string(src[:len(src)-len("throw new TypeError();\n")]) + "var changed;\n")
+	require.NoError(t, ioutil.WriteFile(test, changed, 0o644))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(test, later, later))
+	meta, parsedSrc, err := m.meta("test/sub/a.js")
+	require.NoError(t, err)
+	require.Equal(t, expected, meta)
+	require.Equal(t, string(changed), parsedSrc)
+	require.Equal(t, 1, m.changed)
+	require.NoError(t, m.write(file))
+	m, _, err = loadMetaManifest(file, root, false)
+	require.NoError(t, err)
+	require.Equal(t, later.UnixNano(), m.entries["test/sub/a.js"].ModTime)
+
+	_, _, err = m.meta("test/broken.js")
+	require.True(t, errors.Is(err, invalidFormatError), "%v", err)
+
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{"schemaVersion": 0, "files": {}}`), 0o644))
+	_, note, err = loadMetaManifest(file, root, false)
+	require.NoError(t, err)
+	require.Equal(t, "generated "+file+" with 1 tests, as it's of version 0", note)
+	_, note, err = loadMetaManifest(file, root, true)
+	require.NoError(t, err)
+	require.Equal(t, "generated "+file+" with 1 tests, as TC39_REBUILD_MANIFEST is set", note)
+}
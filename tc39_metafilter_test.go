@@ -0,0 +1,192 @@
+package test262
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39MetaFilter runs only the tests whose metadata matches TC39_META_FILTER, a comma separated
+// list of terms all of which have to match. A term is one of
+//
+//	negative          the test expects an error
+//	phase:<phase>     the test expects an error at that phase, parse, early, resolution or runtime
+//	flag:<flag>       the test has the flag, one of tc39KnownFlags
+//	feature:<feature> the test uses the feature
+//	include:<file>    the test includes the harness file
+//
+// and a ! before it matches the tests it doesn't. A nil filter matches every test.
+type tc39MetaFilter struct {
+	expr  string
+	terms []tc39MetaTerm
+
+	mu             sync.Mutex
+	tests, matched int
+}
+
+type tc39MetaTerm struct {
+	not   bool
+	match func(meta *tc39Meta) bool
+}
+
+func metaFilterFromEnv() (*tc39MetaFilter, error) {
+	v := os.Getenv("TC39_META_FILTER")
+	if v == "" {
+		return nil, nil
+	}
+	return parseMetaFilter(v)
+}
+
+// parseMetaFilter parses the terms of expr.
+func parseMetaFilter(expr string) (*tc39MetaFilter, error) {
+	f := &tc39MetaFilter{expr: expr}
+	for _, s := range strings.Split(expr, ",") {
+		term, err := parseMetaTerm(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("TC39_META_FILTER %q: %w", expr, err)
+		}
+		f.terms = append(f.terms, term)
+	}
+	return f, nil
+}
+
+func parseMetaTerm(s string) (tc39MetaTerm, error) {
+	var term tc39MetaTerm
+	if strings.HasPrefix(s, "!") {
+		term.not, s = true, strings.TrimSpace(s[1:])
+	}
+	key, value := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		key, value = s[:i], s[i+1:]
+		if value == "" {
+			return term, fmt.Errorf("%s: needs a value", key)
+		}
+	}
+	switch key {
+	case "":
+		return term, fmt.Errorf("empty term")
+	case "negative":
+		if value != "" {
+			return term, fmt.Errorf("negative doesn't take a value, phase:%s does", value)
+		}
+		term.match = func(meta *tc39Meta) bool { return meta.Negative.Phase != "" || meta.Negative.Type != "" }
+		return term, nil
+	}
+	if value == "" {
+		return term, fmt.Errorf("unknown term %q", key)
+	}
+	switch key {
+	case "phase":
+		if _, ok := tc39NegativePhases[value]; !ok {
+			return term, fmt.Errorf("unknown negative phase %q", value)
+		}
+		term.match = func(meta *tc39Meta) bool { return meta.Negative.Phase == value }
+	case "flag":
+		if !tc39KnownFlags[value] {
+			return term, &unknownFlagError{flag: value}
+		}
+		term.match = func(meta *tc39Meta) bool { return meta.hasFlag(value) }
+	case "feature":
+		term.match = func(meta *tc39Meta) bool { return meta.hasFeature(value) }
+	case "include":
+		term.match = func(meta *tc39Meta) bool { return meta.hasInclude(value) }
+	default:
+		return term, fmt.Errorf("unknown term %q", key)
+	}
+	return term, nil
+}
+
+// matches tells if the metadata matches all the terms.
+func (f *tc39MetaFilter) matches(meta *tc39Meta) bool {
+	for _, term := range f.terms {
+		if term.match(meta) == term.not {
+			return false
+		}
+	}
+	return true
+}
+
+// take counts the test and tells if it matches.
+func (f *tc39MetaFilter) take(meta *tc39Meta) bool {
+	if f == nil {
+		return true
+	}
+	ok := f.matches(meta)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tests++
+	if ok {
+		f.matched++
+	}
+	return ok
+}
+
+func (f *tc39MetaFilter) summary() string {
+	if f == nil {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return fmt.Sprintf("%d of the %d tests matched TC39_META_FILTER %s", f.matched, f.tests, f.expr)
+}
+
+func TestParseMetaFilter(t *testing.T) {
+	negative := &tc39Meta{Negative: TC39MetaNegative{Phase: "parse", Type: "SyntaxError"}}
+	module := &tc39Meta{Flags: []string{"module", "onlyStrict"}, Features: []string{"let"}}
+	includes := &tc39Meta{Includes: []string{"compareArray.js"}}
+	for expr, expected := range map[string][]bool{
+		"negative":                  {true, false, false},
+		"!negative":                 {false, true, true},
+		"phase:parse":               {true, false, false},
+		"phase:runtime":             {false, false, false},
+		"flag:module":               {false, true, false},
+		"flag:module, feature:let":  {false, true, false},
+		"flag:module,! feature:let": {false, false, false},
+		"feature:BigInt":            {false, false, false},
+		"include:compareArray.js":   {false, false, true},
+		"!flag:raw,!negative":       {false, true, true},
+	} {
+		f, err := parseMetaFilter(expr)
+		require.NoError(t, err, expr)
+		for i, meta := range []*tc39Meta{negative, module, includes} {
+			require.Equal(t, expected[i], f.matches(meta), "%s %d", expr, i)
+		}
+	}
+	for expr, expected := range map[string]string{
+		"":                   "empty term",
+		"negative,":          "empty term",
+		"!":                  "empty term",
+		"negative:TypeError": "negative doesn't take a value, phase:TypeError does",
+		"flag:":              "flag: needs a value",
+		"flag:strict":        `unknown flag "strict"`,
+		"phase:late":         `unknown negative phase "late"`,
+		"strict":             `unknown term "strict"`,
+		"esid:sec-array":     `unknown term "esid"`,
+	} {
+		_, err := parseMetaFilter(expr)
+		require.EqualError(t, err, fmt.Sprintf("TC39_META_FILTER %q: %s", expr, expected), expr)
+	}
+}
+
+func TestMetaFilterRun(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	var err error
+	ctx.metaFilter, err = parseMetaFilter("negative")
+	require.NoError(t, err)
+	for _, name := range []string{"test/pool/negative.js", "test/compat/es5.js", "test/raw.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, t)
+		})
+	}
+	results := ctx.results.resultsCopy()
+	require.Equal(t, CategoryPass, results[variantKey("test/pool/negative.js", false)].Category)
+	// the others aren't skipped, they're not part of the run at all
+	require.Len(t, results, 2)
+	require.Equal(t, "1 of the 3 tests matched TC39_META_FILTER negative", ctx.metaFilter.summary())
+	require.Empty(t, (*tc39MetaFilter)(nil).summary())
+}
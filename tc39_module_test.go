@@ -0,0 +1,152 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// a module is compiled to the function CommonJS modules are, the exports Babel compiles it to
+// being properties of its exports
+const (
+	tc39ModulePre  = "(function(require, module, exports) {\n"
+	tc39ModulePost = "\n})\n"
+)
+
+// tc39Modules loads the modules a module test imports, each once, relative to the module importing
+// it below the root of the test's suite. goja has no modules, Babel compiles the imports to calls
+// of require, so the modules are evaluated when they're first imported and not all before the
+// test, and an export missing from a module is just undefined.
+type tc39Modules struct {
+	ctx  *tc39TestCtx
+	vm   *goja.Runtime
+	root string
+	// loaded has the module object of every module by its path relative to root, the test's
+	// included, so the cycles get the exports so far
+	loaded map[string]*goja.Object
+	// failed is what a module that couldn't be loaded threw
+	failed goja.Value
+}
+
+// moduleResolutionError is the error of a test that failed because a module it imports couldn't
+// be loaded, which a negative test expects at the resolution phase.
+type moduleResolutionError struct {
+	err error
+}
+
+func (e *moduleResolutionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *moduleResolutionError) Unwrap() error {
+	return e.err
+}
+
+// compileModule compiles the module, returning the code it compiled as compileJS does and
+// recording the use of the transform cache as compileJSWith.
+func (ctx *tc39TestCtx) compileModule(name, src string, use *tc39CacheUse) (*goja.Program, string, error) {
+	return ctx.compileJSWith(src, name, tc39ModulePre, tc39ModulePost, true, use)
+}
+
+// runModule runs the compiled module test with the given name in vm.
+func (ctx *tc39TestCtx) runModule(name string, prg *goja.Program, vm *goja.Runtime) error {
+	s, rel := ctx.suite(name)
+	m := &tc39Modules{ctx: ctx, vm: vm, root: s.root, loaded: make(map[string]*goja.Object)}
+	err := m.run(rel, prg)
+	var exc *goja.Exception
+	if m.failed != nil && errors.As(err, &exc) && exc.Value() == m.failed {
+		return &moduleResolutionError{err: err}
+	}
+	return err
+}
+
+// run evaluates the compiled module in file.
+func (m *tc39Modules) run(file string, prg *goja.Program) error {
+	module := m.vm.NewObject()
+	exports := m.vm.NewObject()
+	_ = module.Set("exports", exports)
+	m.loaded[file] = module
+	v, err := m.vm.RunProgram(prg)
+	if err != nil {
+		return err
+	}
+	fn, ok := goja.AssertFunction(v)
+	if !ok {
+		return fmt.Errorf("%s didn't compile to a function", file)
+	}
+	_, err = fn(goja.Undefined(), m.vm.ToValue(m.require(file)), module, exports)
+	return err
+}
+
+// require returns the require of the module in file.
+func (m *tc39Modules) require(file string) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		specifier := call.Argument(0).String()
+		imported := path.Join(path.Dir(file), specifier)
+		if module, ok := m.loaded[imported]; ok {
+			return module.Get("exports")
+		}
+		if imported == ".." || strings.HasPrefix(imported, "../") {
+			m.fail("TypeError", fmt.Sprintf("%s imports %s, which is outside of its suite", file, specifier))
+		}
+		b, err := m.ctx.sources.read(m.root, imported)
+		if err != nil {
+			m.fail("TypeError", fmt.Sprintf("%s imports %s, which can't be read: %v", file, specifier, err))
+		}
+		prg, _, err := m.ctx.compileModule(imported, strings.TrimPrefix(string(b), "\ufeff"), nil)
+		if err != nil {
+			m.fail("SyntaxError", errorMessage(err))
+		}
+		// what it throws while it's evaluated is thrown to the importing module like it is
+		if err = m.run(imported, prg); err != nil {
+			if exc, ok := err.(*goja.Exception); ok {
+				panic(exc)
+			}
+			panic(m.vm.NewGoError(err))
+		}
+		return m.loaded[imported].Get("exports")
+	}
+}
+
+// fail throws an error of the constructor that fails the test at the resolution phase.
+func (m *tc39Modules) fail(constructor, message string) {
+	defer func() {
+		x := recover()
+		if exc, ok := x.(*goja.Object); ok && m.failed == nil {
+			m.failed = exc
+		}
+		panic(x)
+	}()
+	throwError(m.vm, constructor, message)
+}
+
+func TestModuleTests(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	for _, name := range []string{"test/module/import.js", "test/module/resolution.js", "test/module/missing.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, &tc39CountingTB{TB: t})
+		})
+	}
+	results := ctx.results.resultsCopy()
+	require.Equal(t, CategoryPass, results["test/module/import.js-strict:true"].Category, results["test/module/import.js-strict:true"].Message)
+	require.NotContains(t, results, "test/module/import.js-strict:false", "module code is always strict")
+	require.Equal(t, CategoryPass, results["test/module/resolution.js-strict:true"].Category, results["test/module/resolution.js-strict:true"].Message)
+	require.Equal(t, CategoryNewFailure, results["test/module/missing.js-strict:true"].Category)
+	require.Contains(t, results["test/module/missing.js-strict:true"].Message,
+		"TypeError: test/module/missing.js imports ./missing_FIXTURE.js, which can't be read")
+
+	// failing to load a module isn't a runtime error
+	tb := &tc39CountingTB{TB: t}
+	meta, src, err := parseTC39File(osPath(tc39FixturesBase, "test/module/resolution.js"), tc39FixturesBase)
+	require.NoError(t, err)
+	meta.Negative.Phase = "runtime"
+	ctx.runTC39Test(tb, "test/module/resolution.js", src, meta, true)
+	require.Contains(t, ctx.results.resultsCopy()["test/module/resolution.js-strict:true"].Message, "happened at the wrong phase")
+	require.Equal(t, 1, tb.errors)
+}
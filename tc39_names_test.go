@@ -0,0 +1,68 @@
+package test262
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39NameSeparator stands for the slashes of a test's path in its subtest name. go test splits
+// -run at every slash, each part matching one subtest level, so the path of a test can't be used
+// as is without its directories turning into levels. No test262 file or directory has a colon in
+// its name, as it couldn't be checked out on Windows, so the name maps back to the path.
+const tc39NameSeparator = ":"
+
+// subtestName is the name of the subtest running the test with the given path, like
+// test:built-ins:Array:length.js.
+func subtestName(name string) string {
+	return strings.ReplaceAll(slashPath(name), "/", tc39NameSeparator)
+}
+
+// testPath is the path of the test a subtest name is for.
+func testPath(subtest string) string {
+	return strings.ReplaceAll(subtest, tc39NameSeparator, "/")
+}
+
+// runPattern is the -run pattern matching exactly the subtest of the test with the given path.
+func runPattern(name string) string {
+	elems := append([]string{"TestTC39"}, tc39SubtestLevels()...)
+	elems = append(elems, subtestName(name))
+	for i, elem := range elems {
+		elems[i] = "^" + regexp.QuoteMeta(elem) + "$"
+	}
+	return strings.Join(elems, "/")
+}
+
+// rerunCommand is the command running only the test with the given path, built the way this run
+// was, quoted for a POSIX shell.
+func rerunCommand(name string) string {
+	args := append([]string{"go", "test"}, tc39BuildFlags()...)
+	return strings.Join(args, " ") + " -run '" + strings.ReplaceAll(runPattern(name), "'", `'\''`) + "'"
+}
+
+func TestSubtestNames(t *testing.T) {
+	for _, name := range []string{
+		"test/built-ins/Array/length.js",
+		"k6tests/foo.js",
+		"test/intl402/Collator/prototype/compare/non-normative-basic.js",
+	} {
+		sub := subtestName(name)
+		require.NotContains(t, sub, "/")
+		require.Equal(t, name, testPath(sub))
+	}
+	require.Equal(t, "test:built-ins:Array:length.js", subtestName(`test\built-ins\Array\length.js`))
+
+	prefix := "^TestTC39$/^tc39$/"
+	require.Equal(t, prefix+`^test:built-ins:RegExp:S15\.10\.2\.12_A1_T1\.js$`,
+		runPattern("test/built-ins/RegExp/S15.10.2.12_A1_T1.js"))
+	command := strings.Join(append([]string{"go", "test"}, tc39BuildFlags()...), " ")
+	require.Equal(t, command+" -run '"+prefix+`^test:it'\''s\.js$'`, rerunCommand("test/it's.js"))
+
+	// the pattern matches the test and nothing else, like a test whose name only differs by a dot
+	re := regexp.MustCompile(strings.Split(runPattern("test/a.js"), "/")[len(tc39SubtestLevels())+1])
+	require.True(t, re.MatchString(subtestName("test/a.js")))
+	require.False(t, re.MatchString(subtestName("test/aajs")))
+	require.False(t, re.MatchString(subtestName("test/a.js/b.js")))
+}
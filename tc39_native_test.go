@@ -0,0 +1,155 @@
+package test262
+
+import (
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// How a failure compares to the run of the same variant on plain goja, for TC39_NATIVE_COMPARE.
+const (
+	// nativePass is a failure of the transpiler, the variant passes on plain goja.
+	nativePass = "pass"
+	// nativeSame is a failure of goja, the variant fails with the same error on plain goja.
+	nativeSame = "same"
+	// nativeDifferent is a variant that fails on plain goja too, but with another error.
+	nativeDifferent = "different"
+)
+
+// compareNative tells if the failure of the variant is compared with a run on plain goja. Only the
+// extended mode has something else than goja to compare with, and the async and module tests need
+// core-js and Babel to run at all.
+func (ctx *tc39TestCtx) compareNative(meta *tc39Meta, mode lib.CompatibilityMode, category ResultCategory) bool {
+	return ctx.nativeCompare && mode == lib.CompatibilityModeExtended && !meta.hasFlag("async") &&
+		!meta.hasFlag("module") &&
+		(category == CategoryExpectedFailure || category == CategoryNewFailure || category == CategoryChangedFailure)
+}
+
+// runNative runs the variant that failed with err again on a plain goja runtime, the harness and the
+// test compiled by goja itself without Babel, core-js or the hosts of the runner, and returns how
+// the two compare.
+func (ctx *tc39TestCtx) runNative(name, src string, meta *tc39Meta, strict bool, err error) string {
+	vm := goja.New()
+	stop := interruptAfter(vm, testTimeout, testTimeoutError{testTimeout})
+	nativeErr := ctx.runNativeScript(vm, name, src, meta, strict)
+	stop()
+	switch {
+	case nativeErr == nil && meta.Negative.Type == "",
+		nativeErr != nil && meta.Negative.Type != "" && errorType(nativeErr) == meta.Negative.Type:
+		return nativePass
+	case nativeErr != nil && err != nil && nativeErr.Error() == err.Error():
+		return nativeSame
+	default:
+		return nativeDifferent
+	}
+}
+
+// runNativeScript runs the harness and the test on vm. It runs while the variant is recorded, past
+// the recover of runTC39TestIn, so a panic of goja is only an error of the comparison.
+func (ctx *tc39TestCtx) runNativeScript(vm *goja.Runtime, name, src string, meta *tc39Meta, strict bool) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("panic: %s: %v", name, x)
+		}
+	}()
+	s, _ := ctx.suite(name)
+	for _, include := range harnessFiles(meta) {
+		file := path.Join("harness", include)
+		b, err := ctx.sources.read(s.harness, file)
+		if err != nil {
+			return err
+		}
+		prg, err := goja.Compile(file, string(b), false)
+		if err != nil {
+			return err
+		}
+		if _, err = vm.RunProgram(prg); err != nil {
+			return err
+		}
+	}
+	prg, err := goja.Compile(name, src, strict)
+	if err != nil {
+		return err
+	}
+	_, err = vm.RunProgram(prg)
+	return err
+}
+
+// errorType returns the name of the constructor of what was thrown, or the type of an error of the
+// compilation, empty if it's neither.
+func errorType(err error) string {
+	switch err := err.(type) {
+	case *goja.Exception:
+		if o, ok := err.Value().(*goja.Object); ok {
+			if c, ok := o.Get("constructor").(*goja.Object); ok {
+				return c.Get("name").String()
+			}
+		}
+	case *goja.CompilerSyntaxError, *parser.Error, parser.ErrorList:
+		return "SyntaxError"
+	case *goja.CompilerReferenceError:
+		return "ReferenceError"
+	}
+	return ""
+}
+
+// nativeSummary is the line with how the failures compared with plain goja, empty if none was.
+func (r *tc39Results) nativeSummary() string {
+	counts := make(map[string]int)
+	r.mu.Lock()
+	for _, result := range r.results {
+		if result.Native != "" {
+			counts[result.Native]++
+		}
+	}
+	r.mu.Unlock()
+	if len(counts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d failures are transpiler-only, passing on plain goja (TC39_NATIVE_COMPARE), "+
+		"%d fail the same way there and %d differently", counts[nativePass], counts[nativeSame], counts[nativeDifferent])
+}
+
+func TestNativeCompare(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.nativeCompare = true
+	tb := &tc39CountingTB{TB: t}
+	names := []string{"test/native/core-js.js", "test/native/both.js", "test/native/babel.js", "test/compat/es5.js"}
+	for _, name := range names {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, tb)
+		})
+	}
+	require.Equal(t, 6, tb.errors)
+
+	results := ctx.results.resultsCopy()
+	for _, strict := range []bool{false, true} {
+		for name, native := range map[string]string{
+			// core-js adds a global plain goja doesn't have
+			"test/native/core-js.js": nativePass,
+			"test/native/both.js":    nativeSame,
+			// plain goja can't parse what Babel transformed
+			"test/native/babel.js": nativeDifferent,
+			// a pass isn't compared
+			"test/compat/es5.js": "",
+		} {
+			require.Equal(t, native, results[variantKey(name, strict)].Native, variantKey(name, strict))
+		}
+	}
+	require.Equal(t, "2 failures are transpiler-only, passing on plain goja (TC39_NATIVE_COMPARE), "+
+		"2 fail the same way there and 2 differently", ctx.results.nativeSummary())
+
+	// the comparison is only made when asked for
+	ctx = newFixtureCtx(t)
+	t.Run("test/native/both.js", func(t *testing.T) {
+		ctx.runTC39File("test/native/both.js", "test/native/both.js", &tc39CountingTB{TB: t})
+	})
+	require.Empty(t, ctx.results.resultsCopy()[variantKey("test/native/both.js", false)].Native)
+	require.Empty(t, ctx.results.nativeSummary())
+}
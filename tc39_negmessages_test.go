@@ -0,0 +1,146 @@
+package test262
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// tc39NegativeMessagesFile has for some negative tests what the message of their error has to
+// be, not only its type. A SyntaxError Babel throws for something it doesn't support has the right
+// type too, but isn't goja rejecting the construct under test.
+const tc39NegativeMessagesFile = "./negative_messages.yaml"
+
+type tc39NegativeMessage struct {
+	Test string `yaml:"test"`
+	// Message is a part of the message, Pattern a regexp it has to match, an entry has one of them.
+	Message string `yaml:"message"`
+	Pattern string `yaml:"pattern"`
+
+	re *regexp.Regexp
+}
+
+func (e tc39NegativeMessage) matches(message string) bool {
+	if e.re != nil {
+		return e.re.MatchString(message)
+	}
+	return strings.Contains(message, e.Message)
+}
+
+func (e tc39NegativeMessage) String() string {
+	if e.re != nil {
+		return fmt.Sprintf("matching /%s/", e.Pattern)
+	}
+	return fmt.Sprintf("containing %q", e.Message)
+}
+
+// parseNegativeMessages validates the entries, returning them by their test.
+func parseNegativeMessages(name string, b []byte) (map[string]tc39NegativeMessage, error) {
+	var entries []tc39NegativeMessage
+	if err := yaml.UnmarshalStrict(b, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	messages := make(map[string]tc39NegativeMessage, len(entries))
+	for i, e := range entries {
+		switch {
+		case e.Test == "":
+			return nil, fmt.Errorf("%s: entry %d has no test", name, i+1)
+		case (e.Message == "") == (e.Pattern == ""):
+			return nil, fmt.Errorf("%s: %s needs either a message or a pattern", name, e.Test)
+		}
+		e.Test = slashPath(e.Test)
+		if _, ok := messages[e.Test]; ok {
+			return nil, fmt.Errorf("%s: %s is there twice", name, e.Test)
+		}
+		if e.Pattern != "" {
+			var err error
+			if e.re, err = regexp.Compile(e.Pattern); err != nil {
+				return nil, fmt.Errorf("%s: the pattern of %s: %w", name, e.Test, err)
+			}
+		}
+		messages[e.Test] = e
+	}
+	return messages, nil
+}
+
+// loadNegativeMessages reads the file, which is optional.
+func loadNegativeMessages(name string) (map[string]tc39NegativeMessage, error) {
+	b, err := ioutil.ReadFile(name) //nolint:gosec
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseNegativeMessages(name, b)
+}
+
+// errorMessage is the message of the error a test threw, the message property if it's an object
+// with one.
+func errorMessage(err error) string {
+	if exc, ok := err.(*goja.Exception); ok {
+		if o, ok := exc.Value().(*goja.Object); ok {
+			if m := o.Get("message"); m != nil && !goja.IsUndefined(m) {
+				return m.String()
+			}
+		}
+		return exc.Value().String()
+	}
+	return err.Error()
+}
+
+func TestNegativeMessages(t *testing.T) {
+	_, err := loadNegativeMessages(tc39NegativeMessagesFile)
+	require.NoError(t, err)
+
+	for src, expected := range map[string]string{
+		"- message: m\n": "entry 1 has no test",
+		"- test: a.js\n": "a.js needs either a message or a pattern",
+		"- test: a.js\n  message: m\n  pattern: p\n":                 "a.js needs either a message or a pattern",
+		"- test: a.js\n  pattern: '('\n":                             "the pattern of a.js: error parsing regexp",
+		"- test: a.js\n  message: m\n- test: ./a.js\n  message: m\n": "a.js is there twice",
+		"- test: a.js\n  messages: m\n":                              "field messages not found",
+	} {
+		_, err = parseNegativeMessages("negative_messages.yaml", []byte(src))
+		require.Error(t, err, src)
+		require.Contains(t, err.Error(), expected)
+	}
+
+	ctx := newFixtureCtx(t)
+	ctx.negativeMessages, err = parseNegativeMessages("negative_messages.yaml", []byte(`
+- test: test/right.js
+  message: Unexpected token
+- test: test/babel.js
+  pattern: ^Invalid left-hand side
+- test: test/type.js
+  message: "is not a function"
+- test: test/runtime.js
+  pattern: ^Cannot read property 'x' of (null|undefined)$
+`))
+	require.NoError(t, err)
+	syntaxError := &tc39Meta{Negative: TC39MetaNegative{Phase: "parse", Type: "SyntaxError"}}
+	tb := &tc39CountingTB{TB: t}
+	ctx.runTC39Test(tb, "test/right.js", "var 1;", syntaxError, false)
+	ctx.runTC39Test(tb, "test/babel.js", "var 1;", syntaxError, false)
+	ctx.runTC39Test(tb, "test/type.js", "var 1;", &tc39Meta{Negative: TC39MetaNegative{Phase: "parse", Type: "TypeError"}}, false)
+	ctx.runTC39Test(tb, "test/runtime.js", "null.x;", &tc39Meta{Negative: TC39MetaNegative{Phase: "runtime", Type: "TypeError"}}, false)
+	// without an entry only the type counts
+	ctx.runTC39Test(tb, "test/any.js", "var 1;", syntaxError, false)
+
+	results := ctx.results.resultsCopy()
+	for _, name := range []string{"test/right.js", "test/runtime.js", "test/any.js"} {
+		require.Equal(t, CategoryPass, results[name+"-strict:false"].Category, name)
+	}
+	require.Equal(t, "test/babel.js: unexpected error SyntaxError: \"test/babel.js: Unexpected token (1:4)\\n> 1 | var 1;\\n    |     ^\", "+
+		"expected SyntaxError matching /^Invalid left-hand side/", results["test/babel.js-strict:false"].Message)
+	require.Contains(t, results["test/type.js-strict:false"].Message, "test/type.js: unexpected error SyntaxError: \"test/type.js: Unexpected token")
+	require.Contains(t, results["test/type.js-strict:false"].Message, "expected TypeError containing \"is not a function\"")
+	require.Equal(t, 2, tb.errors)
+}
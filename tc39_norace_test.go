@@ -4,12 +4,34 @@ package test262
 
 import "testing"
 
+const (
+	// the walk of the test tree waits for every group, so it doesn't get too far ahead of the
+	// tests that ran
+	tc39MaxTestGroupSize = 1000
+)
+
 func (ctx *tc39TestCtx) runTest(name string, f func(t *testing.T)) {
-	ctx.t.Run(name, func(t *testing.T) {
-		// t.Parallel()
-		f(t)
-	})
+	if parallelism == 1 {
+		ctx.t.Run(subtestName(name), f)
+		return
+	}
+	ctx.testQueue = append(ctx.testQueue, tc39Test{name: name, f: f})
+	if len(ctx.testQueue) >= tc39MaxTestGroupSize {
+		ctx.flush()
+	}
 }
 
 func (ctx *tc39TestCtx) flush() {
+	runPool(ctx.t, ctx.testQueue, parallelism)
+	ctx.testQueue = ctx.testQueue[:0]
+}
+
+// tc39SubtestLevels are the names of the subtests between TestTC39 and the tests.
+func tc39SubtestLevels() []string {
+	return []string{"tc39"}
+}
+
+// tc39BuildFlags are the flags of go test building the tests as they are, for rerunCommand.
+func tc39BuildFlags() []string {
+	return nil
 }
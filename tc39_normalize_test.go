@@ -0,0 +1,145 @@
+package test262
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39MaxErrorLength is how much of an error is compared with the expected one, the rest of a
+// long Babel code frame or stack tells nothing more.
+const tc39MaxErrorLength = 1000
+
+//nolint:gochecknoglobals
+var (
+	// errorPositionRe is the position of a frame, "at harness/sta.js:22:9(49)", which a change of
+	// the harness or of goja moves, errorCallRe the same inside the parentheses of a named frame.
+	errorPositionRe = regexp.MustCompile(`\bat ([^\s:()]+):\d+:\d+(?:\(\d+\))?`)
+	errorCallRe     = regexp.MustCompile(`\(([^\s:()]+):\d+:\d+(?:\(\d+\))?\)`)
+	errorSpaceRe    = regexp.MustCompile(`\s+`)
+)
+
+// normalizeError returns the form of an error it's compared with the expected ones in: without the
+// roots, absolute directories, in front of the paths, with the frames without their line, column
+// and program counter, the whitespace collapsed and cut after tc39MaxErrorLength bytes. It's
+// idempotent, so normalizing the expected errors again changes nothing.
+func normalizeError(errStr string, roots []string) string {
+	for _, root := range roots {
+		errStr = strings.ReplaceAll(errStr, root+"/", "")
+	}
+	errStr = errorPositionRe.ReplaceAllString(errStr, "at $1")
+	errStr = errorCallRe.ReplaceAllString(errStr, "($1)")
+	errStr = strings.TrimSpace(errorSpaceRe.ReplaceAllString(errStr, " "))
+	if len(errStr) > tc39MaxErrorLength {
+		cut := tc39MaxErrorLength
+		for cut > 0 && !utf8.RuneStart(errStr[cut]) {
+			cut--
+		}
+		errStr = errStr[:cut] + "…"
+	}
+	return errStr
+}
+
+// errorRoots returns the absolute directories of the checkout, the suites and the working
+// directory in the slash separated form the errors have them in, the longest first so a root isn't
+// only partially removed for another one it's in.
+func (ctx *tc39TestCtx) errorRoots() []string {
+	dirs := []string{".", ctx.base}
+	for _, s := range ctx.extraSuites {
+		dirs = append(dirs, s.root, s.harness)
+	}
+	roots := make([]string, 0, 2*len(dirs))
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil || abs == string(filepath.Separator) {
+			continue
+		}
+		roots = append(roots, strings.TrimSuffix(filepath.ToSlash(abs), "/"))
+		if filepath.Separator != '/' {
+			roots = append(roots, strings.TrimSuffix(abs, string(filepath.Separator)))
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return len(roots[i]) > len(roots[j]) })
+	return roots
+}
+
+// normalizeErrors returns the errors in their normalized form, the one written to the baselines. The
+// regexps stay as they are.
+func (ctx *tc39TestCtx) normalizeErrors(errs map[string]string) map[string]string {
+	return errorsOf(ctx.normalizeEntries(entriesOf(errs)))
+}
+
+// normalizeEntries returns the entries with their errors normalized, see normalizeErrors.
+func (ctx *tc39TestCtx) normalizeEntries(entries map[string]tc39ExpectedEntry) map[string]tc39ExpectedEntry {
+	roots := ctx.errorRoots()
+	normalized := make(map[string]tc39ExpectedEntry, len(entries))
+	for nameKey, e := range entries {
+		if _, ok := expectedPattern(e.Error); !ok {
+			e.Error = normalizeError(e.Error, roots)
+		}
+		normalized[nameKey] = e
+	}
+	return normalized
+}
+
+func TestNormalizeError(t *testing.T) {
+	roots := []string{"/home/user/k6-test262/TestTC39/test262", "/home/user/k6-test262"}
+	long := strings.Repeat("é", tc39MaxErrorLength)
+	for _, tc := range []struct{ name, errStr, normalized string }{
+		{"unchanged", "test/a.js: Test262Error: failed", "test/a.js: Test262Error: failed"},
+		{
+			"frame",
+			"[test/a.js Test262Error: obj should have an own property at harness/sta.js:22:9(49)]: %!v(MISSING)",
+			"[test/a.js Test262Error: obj should have an own property at harness/sta.js]: %!v(MISSING)",
+		},
+		{"frame without pc", "TypeError: x at <eval>:2:28542", "TypeError: x at <eval>"},
+		{
+			"named frame",
+			"Test262Error: x at assert (harness/assert.js:10:5(12)) at test/a.js:3:1(4)",
+			"Test262Error: x at assert (harness/assert.js) at test/a.js",
+		},
+		{
+			"absolute path",
+			"/home/user/k6-test262/TestTC39/test262/test/a.js: Line 6:18 Unexpected token",
+			"test/a.js: Line 6:18 Unexpected token",
+		},
+		{
+			"absolute path of an extra suite",
+			"TypeError: x at /home/user/k6-test262/suites/own/b.js:1:2(3)",
+			"TypeError: x at suites/own/b.js",
+		},
+		{"other absolute path", "open /tmp/x.js: no such file", "open /tmp/x.js: no such file"},
+		{"whitespace", "SyntaxError: x (13:22)\n  11 | ---*/\n> 13 | a\t b\n ", "SyntaxError: x (13:22) 11 | ---*/ > 13 | a b"},
+		{"long", long, long[:tc39MaxErrorLength] + "…"},
+		{"long cut inside a rune", "x" + long, "x" + long[:tc39MaxErrorLength-2] + "…"},
+	} {
+		normalized := normalizeError(tc.errStr, roots)
+		require.Equal(t, tc.normalized, normalized, tc.name)
+		require.Equal(t, normalized, normalizeError(normalized, roots), tc.name)
+	}
+}
+
+// TestTC39NormalizeBaselines rewrites the baselines with their errors normalized, given
+// TC39_NORMALIZE_BASELINES=1, which the files from before the normalization need once.
+func TestTC39NormalizeBaselines(t *testing.T) {
+	if os.Getenv("TC39_NORMALIZE_BASELINES") == "" {
+		t.Skip("set TC39_NORMALIZE_BASELINES=1 to rewrite " + tc39BaselineDir + " and " + tc39IntlBaseline + " with the errors normalized")
+	}
+	ctx := &tc39TestCtx{base: tc39BASE}
+	files, err := baselineFiles(tc39Baseline, tc39BaselineDir)
+	require.NoError(t, err)
+	for _, name := range append(files, tc39IntlBaseline) {
+		b, err := ioutil.ReadFile(name) //nolint:gosec
+		require.NoError(t, err)
+		entries, err := readExpectedEntries(b)
+		require.NoError(t, err, name)
+		require.NoError(t, writeExpectedEntries(name, ctx.normalizeEntries(entries)))
+	}
+}
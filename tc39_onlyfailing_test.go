@@ -0,0 +1,132 @@
+package test262
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39OnlyFailing runs only the tests with an expected error, for TC39_ONLY_FAILING, to see which
+// of them a fix made pass. None of the other tests runs, so none of them is missed. A nil one runs
+// the whole tree.
+type tc39OnlyFailing struct {
+	// tests are the tests with an expected error, sorted, gone the ones of them that don't exist
+	tests, gone []string
+}
+
+// newOnlyFailing derives the tests to run from the keys of the expected errors.
+func newOnlyFailing(ctx *tc39TestCtx, errs map[string]string) *tc39OnlyFailing {
+	seen := make(map[string]bool)
+	f := &tc39OnlyFailing{}
+	for nameKey := range errs {
+		k, err := parseLegacyKey(nameKey, nil)
+		if err != nil || seen[k.Test] {
+			continue
+		}
+		seen[k.Test] = true
+		s, rel := ctx.suite(k.Test)
+		if _, err = os.Stat(osPath(s.root, rel)); err != nil {
+			f.gone = append(f.gone, k.Test)
+			continue
+		}
+		f.tests = append(f.tests, k.Test)
+	}
+	sort.Strings(f.tests)
+	sort.Strings(f.gone)
+	return f
+}
+
+// run runs the tests instead of walking the tree.
+func (f *tc39OnlyFailing) run(ctx *tc39TestCtx) {
+	for _, name := range f.tests {
+		if ctx.firstNew.stopped() {
+			break
+		}
+		name := name
+		_, rel := ctx.suite(name)
+		ctx.runTest(name, func(t *testing.T) {
+			ctx.runTC39File(name, rel, t)
+		})
+	}
+}
+
+// removable returns the keys of the expected errors that can be removed, as their variant ran and
+// passed or their test doesn't exist anymore. A variant that didn't run isn't one of them.
+func (f *tc39OnlyFailing) removable(errs map[string]string, results map[string]TestResult) []string {
+	gone := make(map[string]bool, len(f.gone))
+	for _, name := range f.gone {
+		gone[name] = true
+	}
+	var keys []string
+	for nameKey := range errs {
+		k, err := parseLegacyKey(nameKey, nil)
+		if result, ok := results[nameKey]; err == nil && (gone[k.Test] || ok && result.Category == CategoryPass) {
+			keys = append(keys, nameKey)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// summary has what the run found, nothing without TC39_ONLY_FAILING.
+func (f *tc39OnlyFailing) summary(errs map[string]string, results map[string]TestResult) []string {
+	if f == nil {
+		return nil
+	}
+	keys := f.removable(errs, results)
+	lines := []string{fmt.Sprintf("TC39_ONLY_FAILING ran the %d tests with an expected error, %d of whose %d expected errors can be removed",
+		len(f.tests), len(keys), len(errs))}
+	if len(f.gone) > 0 {
+		lines = append(lines, fmt.Sprintf("%d of the tests don't exist anymore, all their expected errors can be removed", len(f.gone)))
+	}
+	for _, key := range keys {
+		lines = append(lines, "removable: "+key)
+	}
+	return lines
+}
+
+func TestOnlyFailing(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.expectedErrors = map[string]string{
+		// passes now
+		"test/compat/es5.js-strict:false": "test/compat/es5.js: Test262Error: fixed",
+		"test/compat/es5.js-strict:true":  "test/compat/es5.js: Test262Error: fixed",
+		// still fails the same way
+		"test/dump/fail.js-strict:false": "test/dump/fail.js: Test262Error: still failing",
+		// only the strict variant of a module runs
+		"test/module/import.js-strict:false": "test/module/import.js: not run",
+		"test/removed.js-strict:false":       "test/removed.js: gone",
+	}
+	f := newOnlyFailing(ctx, ctx.expectedErrors)
+	require.Equal(t, []string{"test/compat/es5.js", "test/dump/fail.js", "test/module/import.js"}, f.tests)
+	require.Equal(t, []string{"test/removed.js"}, f.gone)
+
+	for _, name := range f.tests {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			_, rel := ctx.suite(name)
+			ctx.runTC39File(name, rel, &tc39CountingTB{TB: t})
+		})
+	}
+	results := ctx.results.resultsCopy()
+	// the tests without an expected error didn't run
+	for nameKey := range results {
+		k, err := parseLegacyKey(nameKey, nil)
+		require.NoError(t, err)
+		require.Contains(t, f.tests, k.Test)
+	}
+	require.Equal(t, []string{
+		"test/compat/es5.js-strict:false", "test/compat/es5.js-strict:true", "test/removed.js-strict:false",
+	}, f.removable(ctx.expectedErrors, results))
+	require.Equal(t, []string{
+		"TC39_ONLY_FAILING ran the 3 tests with an expected error, 3 of whose 5 expected errors can be removed",
+		"1 of the tests don't exist anymore, all their expected errors can be removed",
+		"removable: test/compat/es5.js-strict:false",
+		"removable: test/compat/es5.js-strict:true",
+		"removable: test/removed.js-strict:false",
+	}, f.summary(ctx.expectedErrors, results))
+	require.Nil(t, (*tc39OnlyFailing)(nil).summary(ctx.expectedErrors, results))
+}
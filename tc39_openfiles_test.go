@@ -0,0 +1,131 @@
+package test262
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39DefaultMaxOpenFiles stays well below the 256 descriptors macOS allows by default, leaving
+// room for the ones the go tool and the test binary itself have open.
+const tc39DefaultMaxOpenFiles = 64
+
+// tc39FileLimiter bounds how many files the runner has open at once. Every path opening a file,
+// reading a test or harness file, listing a directory or writing an artifact, holds a slot for
+// as long as the file is open.
+type tc39FileLimiter struct {
+	slots chan struct{}
+
+	mu         sync.Mutex
+	open, peak int
+}
+
+func newFileLimiter(n int) *tc39FileLimiter {
+	return &tc39FileLimiter{slots: make(chan struct{}, n)}
+}
+
+// openFiles limits the files open at once, it's shared by everything opening them.
+var openFiles = newFileLimiter(tc39DefaultMaxOpenFiles) //nolint:gochecknoglobals
+
+// acquire blocks until a file can be opened, the returned function gives the slot back.
+func (l *tc39FileLimiter) acquire() (release func()) {
+	l.slots <- struct{}{}
+	l.mu.Lock()
+	l.open++
+	if l.open > l.peak {
+		l.peak = l.open
+	}
+	l.mu.Unlock()
+	return func() {
+		l.mu.Lock()
+		l.open--
+		l.mu.Unlock()
+		<-l.slots
+	}
+}
+
+// peakOpen returns the most slots that were held at once.
+func (l *tc39FileLimiter) peakOpen() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.peak
+}
+
+// maxOpenFilesFromEnv returns TC39_MAX_OPEN_FILES. It can't be less than 2, as a trace is
+// written while its test reads its files.
+func maxOpenFilesFromEnv() (int, error) {
+	v := os.Getenv("TC39_MAX_OPEN_FILES")
+	if v == "" {
+		return tc39DefaultMaxOpenFiles, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 2 {
+		return 0, fmt.Errorf("TC39_MAX_OPEN_FILES must be a number of at least 2, got %q", v)
+	}
+	return n, nil
+}
+
+// TestMaxOpenFiles discovers, runs and writes artifacts from many goroutines with only two
+// files allowed to be open at once, which would fail with EMFILE if the limit were the real
+// descriptor limit and any of the paths opened files without a slot.
+func TestMaxOpenFiles(t *testing.T) {
+	saved := openFiles
+	defer func() { openFiles = saved }()
+	openFiles = newFileLimiter(2)
+
+	base, err := ioutil.TempDir("", "tc39-open-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(base) //nolint:errcheck
+	for _, file := range []string{"assert.js", "sta.js"} {
+		b, err := ioutil.ReadFile(filepath.Join(tc39FixturesBase, "harness", file)) //nolint:gosec
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Join(base, "harness"), 0o755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(base, "harness", file), b, 0o644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(base, "harness", "inc.js"), []byte("var inc = 1;"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "test"), 0o755))
+	const tests, goroutines = 4, 8
+	for i := 0; i < tests; i++ {
+		src := fmt.Sprintf("/*---\nes6id: 1\nincludes: [inc.js]\n---*/\nassert.sameValue(inc, %d - %d);\n", i+1, i)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(base, "test", fmt.Sprintf("%d.js", i)), []byte(src), 0o644))
+	}
+
+	ctx := newFixtureCtx(t)
+	ctx.base = base
+	tb := &tc39CountingTB{TB: t}
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			d, err := discoverTests(base, "test")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for _, name := range d.names {
+				ctx.runTC39File(name, d.path(name), tb)
+			}
+			err = writeArtifact(filepath.Join(base, fmt.Sprintf("%d.json", i)), func(w io.Writer) error {
+				_, err := io.WriteString(w, "{}")
+				return err
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Zero(t, tb.errors)
+	require.Empty(t, ctx.results.errorsCopy())
+	require.Len(t, ctx.results.resultsCopy(), 2*tests)
+	require.True(t, openFiles.peakOpen() <= 2, openFiles.peakOpen())
+}
@@ -0,0 +1,160 @@
+package test262
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Output captures what a test prints. It's flushed to the test's own testing.TB before
+// runTC39Test returns, as logging to it after the test has completed panics.
+type tc39Output struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (o *tc39Output) print(call goja.FunctionCall) goja.Value {
+	args := make([]string, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		args[i] = arg.String()
+	}
+	o.mu.Lock()
+	o.lines = append(o.lines, strings.Join(args, " "))
+	o.mu.Unlock()
+	return goja.Undefined()
+}
+
+// captured returns the lines printed so far.
+func (o *tc39Output) captured() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.lines...)
+}
+
+func (o *tc39Output) flush(tb testing.TB) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, line := range o.lines {
+		tb.Log(line)
+	}
+	o.lines = o.lines[:0]
+}
+
+// tc39TestWriter logs what is written to it to a test, the complete lines of every write as one
+// entry so a table or a JSON object isn't interleaved with the file and line of every line. A
+// partial last line is kept until it's completed or flushed.
+type tc39TestWriter struct {
+	tb      testing.TB
+	mu      sync.Mutex
+	partial []byte
+}
+
+func newTestWriter(tb testing.TB) *tc39TestWriter {
+	return &tc39TestWriter{tb: tb}
+}
+
+func (w *tc39TestWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, b...)
+	if i := bytes.LastIndexByte(w.partial, '\n'); i >= 0 {
+		w.tb.Log(string(w.partial[:i]))
+		w.partial = append(w.partial[:0], w.partial[i+1:]...)
+	}
+	return len(b), nil
+}
+
+func (w *tc39TestWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		w.tb.Log(string(w.partial))
+		w.partial = w.partial[:0]
+	}
+}
+
+func TestPrintCaptureParallel(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	const lines = 200
+	src := fmt.Sprintf(`for (var i = 0; i < %d; i++) { print("line", i, {}); }`, lines)
+	meta := &tc39Meta{}
+
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 32; i++ {
+			name, strict := fmt.Sprintf("test/print-%d.js", i), i%2 == 0
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				ctx.runTC39Test(t, name, src, meta, strict)
+			})
+		}
+	})
+	require.Empty(t, ctx.results.errorsCopy())
+}
+
+// tc39LoggingTB keeps what is logged to it.
+type tc39LoggingTB struct {
+	testing.TB
+	logs []string
+}
+
+func (tb *tc39LoggingTB) Log(args ...interface{}) {
+	tb.logs = append(tb.logs, fmt.Sprint(args...))
+}
+
+func TestTestWriter(t *testing.T) {
+	tb := &tc39LoggingTB{TB: t}
+	w := newTestWriter(tb)
+	fmt.Fprint(w, "a table\nname\t")
+	fmt.Fprintln(w, "time")
+	fmt.Fprint(w, "unterminated")
+	require.Equal(t, []string{"a table", "name\ttime"}, tb.logs)
+	w.flush()
+	w.flush()
+	require.Equal(t, []string{"a table", "name\ttime", "unterminated"}, tb.logs)
+}
+
+// TestReportOutput runs the fixture suites with the output going to a buffer, to check that the
+// report goes to Options.Out, and only once.
+func TestReportOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	var out bytes.Buffer
+	ctx := newFixtureCtx(t)
+	ctx.opts.Out = &out
+	ctx.artifacts = newArtifacts(dir)
+	ctx.extraSuites, err = extraSuites(ctx.base, "testdata/suites/k6tests,testdata/suites/own")
+	require.NoError(t, err)
+	t.Run("tc39", func(t *testing.T) {
+		ctx.t = t
+		for _, s := range ctx.extraSuites {
+			ctx.runSuiteTests(s, "")
+		}
+		ctx.flush()
+	})
+	ctx.results.recordResult("own/new.js-strict:false", TestResult{Category: CategoryNewFailure, Message: "own/new.js: boom"})
+	ctx.report(t, true, "a hook's summary")
+
+	report := out.String()
+	for _, section := range []string{
+		"read 7 harness files from disk\n",
+		"precompiled 5 harness files at init in ",
+		"a hook's summary\n",
+		"0 esids without a single executed test, see ",
+		"\"own/new.js-strict:false\": \"own/new.js: boom\"",
+	} {
+		require.Equal(t, 1, strings.Count(report, section), "%q in:\n%s", section, report)
+	}
+	// the manifest only comes after the report files, which are written once it's done
+	require.NotContains(t, report, "artifacts listed in")
+	ctx.writeManifest(t)
+	require.Contains(t, out.String(), "run "+ctx.artifacts.runID+" in environment ")
+}
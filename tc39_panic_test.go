@@ -0,0 +1,92 @@
+package test262
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Panic is a test variant that panicked, with the Go stack it panicked at.
+type tc39Panic struct {
+	message, stack string
+}
+
+func (r *tc39Results) recordPanic(nameKey, message, stack string) {
+	r.mu.Lock()
+	r.panics[nameKey] = tc39Panic{message: message, stack: stack}
+	r.mu.Unlock()
+}
+
+// writePanics writes the section of the report with the variants that panicked, sorted by their
+// keys, so the bugs of goja aren't mistaken for the errors of the tests.
+func (r *tc39Results) writePanics(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.panics) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(r.panics))
+	for key := range r.panics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(w, "panics (%d test variants):\n", len(keys))
+	for _, key := range keys {
+		p := r.panics[key]
+		fmt.Fprintf(w, "%s %s\n%s\n", key, p.message, strings.TrimRight(p.stack, "\n"))
+	}
+}
+
+// tc39PanicHook gives the tests a host function panicking like a bug of goja would.
+type tc39PanicHook struct{}
+
+func (tc39PanicHook) Before(tc *TestInfo, vm *goja.Runtime) error {
+	vm.Set("hostPanic", func(goja.FunctionCall) goja.Value {
+		var values []goja.Value
+		return values[1]
+	})
+	return nil
+}
+
+func (tc39PanicHook) After(*TestInfo, *TestResult) {}
+
+func TestPanics(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.opts.Hooks = []TestHook{tc39PanicHook{}}
+	tb := &tc39CountingTB{TB: t}
+	for _, name := range []string{"test/panic/host-panic.js", "test/panic/after.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, tb)
+		})
+	}
+
+	// the panic fails its test only, the next one still runs
+	results := ctx.results.resultsCopy()
+	message := "panic: test/panic/host-panic.js: runtime error: index out of range [1] with length 0"
+	for _, strict := range []bool{false, true} {
+		result := results[variantKey("test/panic/host-panic.js", strict)]
+		require.Equal(t, CategoryPanic, result.Category)
+		require.Equal(t, message+" (A host function panicking fails the test.)", result.Message)
+		require.Contains(t, result.Details, "tc39PanicHook")
+		require.Equal(t, CategoryPass, results[variantKey("test/panic/after.js", strict)].Category)
+	}
+	require.Equal(t, 2, tb.errors)
+
+	out := &strings.Builder{}
+	ctx.results.writePanics(out)
+	lines := strings.Split(out.String(), "\n")
+	require.Equal(t, "panics (2 test variants):", lines[0])
+	require.Equal(t, "test/panic/host-panic.js-strict:false "+message+" (A host function panicking fails the test.)", lines[1])
+	require.True(t, strings.HasPrefix(lines[2], "goroutine "), lines[2])
+	require.Contains(t, out.String(), "\ntest/panic/host-panic.js-strict:true "+message)
+
+	out.Reset()
+	newTC39Results().writePanics(out)
+	require.Empty(t, out.String())
+}
@@ -0,0 +1,127 @@
+package test262
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39PatternPrefix starts an expected error that's a regexp instead of the error itself, for the
+// failures that vary between runs or platforms, like the addresses in a panic or a Date string in
+// the local timezone. No error starts with it, they start with the name of the test.
+const tc39PatternPrefix = "~"
+
+// expectedPattern returns the regexp of an expected error, false if it's a literal one.
+func expectedPattern(expected string) (string, bool) {
+	if strings.HasPrefix(expected, tc39PatternPrefix) {
+		return expected[len(tc39PatternPrefix):], true
+	}
+	return "", false
+}
+
+// compileExpectedPatterns compiles the regexps of the expected errors of the files, by name, so an
+// invalid one is found before the tests run.
+func compileExpectedPatterns(files map[string]map[string]string) (map[string]*regexp.Regexp, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	patterns := make(map[string]*regexp.Regexp)
+	for _, name := range names {
+		keys := make([]string, 0, len(files[name]))
+		for nameKey := range files[name] {
+			keys = append(keys, nameKey)
+		}
+		sort.Strings(keys)
+		for _, nameKey := range keys {
+			pattern, ok := expectedPattern(files[name][nameKey])
+			if !ok || patterns[pattern] != nil {
+				continue
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: the expected error of %s isn't a valid regexp: %w", name, nameKey, err)
+			}
+			patterns[pattern] = re
+		}
+	}
+	return patterns, nil
+}
+
+// matchesExpected tells if one of the errors is the expected one, both normalized, or matches it
+// if it's a regexp. A regexp is matched against the normalized error, the form it's printed in.
+func (ctx *tc39TestCtx) matchesExpected(expected string, roots []string, errStrs ...string) bool {
+	pattern, ok := expectedPattern(expected)
+	if !ok {
+		expected = normalizeError(expected, roots)
+		for _, errStr := range errStrs {
+			if normalizeError(errStr, roots) == expected {
+				return true
+			}
+		}
+		return false
+	}
+	re := ctx.expectedPatterns[pattern]
+	if re == nil {
+		// set after init, as by the tests, an invalid one matches nothing
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return false
+		}
+	}
+	for _, errStr := range errStrs {
+		if re.MatchString(normalizeError(errStr, roots)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestExpectedPatterns(t *testing.T) {
+	patterns, err := compileExpectedPatterns(map[string]map[string]string{
+		tc39Baseline: {
+			"test/a.js-strict:false": "test/a.js: Test262Error: literal",
+			"test/b.js-strict:false": `~^test/b\.js: panic: 0x[0-9a-f]+$`,
+			"test/b.js-strict:true":  `~^test/b\.js: panic: 0x[0-9a-f]+$`,
+		},
+		tc39IntlBaseline: {"test/intl402/c.js-strict:false": "~Date: .*"},
+	})
+	require.NoError(t, err)
+	require.Len(t, patterns, 2)
+
+	_, err = compileExpectedPatterns(map[string]map[string]string{
+		tc39Baseline: {"test/a.js-strict:false": "test/a.js: literal (", "test/b.js-strict:true": "~test/b.js: (unclosed"},
+	})
+	require.EqualError(t, err, tc39Baseline+": the expected error of test/b.js-strict:true isn't a valid regexp: "+
+		"error parsing regexp: missing closing ): `test/b.js: (unclosed`")
+
+	ctx := newFixtureCtx(t)
+	ctx.expectedErrors = map[string]string{
+		"test/dump/fail.js-strict:false": `~Test262Error: Expected SameValue\(«a\d», «b»\) to be true`,
+		"test/dump/fail.js-strict:true":  `~SyntaxError`,
+	}
+	ctx.expectedPatterns, err = compileExpectedPatterns(map[string]map[string]string{tc39Baseline: ctx.expectedErrors})
+	require.NoError(t, err)
+	tb := &tc39CountingTB{TB: t}
+	t.Run("test/dump/fail.js", func(t *testing.T) {
+		ctx.runTC39File("test/dump/fail.js", "test/dump/fail.js", tb)
+	})
+	require.Equal(t, 1, tb.errors)
+	results := ctx.results.resultsCopy()
+	require.Equal(t, CategoryExpectedFailure, results[variantKey("test/dump/fail.js", false)].Category)
+	require.Equal(t, CategoryChangedFailure, results[variantKey("test/dump/fail.js", true)].Category)
+
+	// in a baseline with both the literal errors are normalized when it's written, the regexps aren't
+	require.Equal(t, map[string]string{
+		"test/a.js-strict:false": "test/a.js: Test262Error: literal at harness/sta.js",
+		"test/b.js-strict:false": "~test/b.js:   panic at harness/sta.js:22:9(49)",
+	}, ctx.normalizeErrors(map[string]string{
+		"test/a.js-strict:false": "test/a.js: Test262Error: literal at harness/sta.js:22:9(49)",
+		"test/b.js-strict:false": "~test/b.js:   panic at harness/sta.js:22:9(49)",
+	}))
+}
@@ -0,0 +1,133 @@
+package test262
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39HostWork tracks the callbacks the host scheduled for a test, like the timers of a hook
+// adding setTimeout. A test completing with one never fired could be passing only because its
+// assertion never ran, so that fails it. $262.expectPending() marks the work pending so far as
+// expected to stay that way, for the tests of cancellation.
+type tc39HostWork struct {
+	vm *goja.Runtime
+
+	mu      sync.Mutex
+	next    int
+	pending map[int]tc39PendingWork
+}
+
+type tc39PendingWork struct {
+	what, site string
+	expected   bool
+}
+
+func newHostWork(vm *goja.Runtime) *tc39HostWork {
+	w := &tc39HostWork{vm: vm, pending: make(map[int]tc39PendingWork)}
+	if o, ok := vm.Get("$262").(*goja.Object); ok {
+		_ = o.Set("expectPending", func(goja.FunctionCall) goja.Value {
+			w.expectPending()
+			return goja.Undefined()
+		})
+	}
+	return w
+}
+
+// Register records work the host scheduled, at the position of the script calling into the host.
+// done has to be called once it fired or was cancelled. It can only be called from the
+// goroutine running the test, done from any.
+func (w *tc39HostWork) Register(what string) (done func()) {
+	site := "an unknown position"
+	for _, frame := range w.vm.CaptureCallStack(10, nil) {
+		if p := frame.Position(); p.Line > 0 {
+			site = frame.SrcName() + ":" + p.String()
+			break
+		}
+	}
+	w.mu.Lock()
+	id := w.next
+	w.next++
+	w.pending[id] = tc39PendingWork{what: what, site: site}
+	w.mu.Unlock()
+	return func() {
+		w.mu.Lock()
+		delete(w.pending, id)
+		w.mu.Unlock()
+	}
+}
+
+func (w *tc39HostWork) expectPending() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, work := range w.pending {
+		work.expected = true
+		w.pending[id] = work
+	}
+}
+
+// unexpectedPending describes the work still pending that wasn't expected to, in the order it
+// was registered.
+func (w *tc39HostWork) unexpectedPending() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ids := make([]int, 0, len(w.pending))
+	for id, work := range w.pending {
+		if !work.expected {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	described := make([]string, len(ids))
+	for i, id := range ids {
+		described[i] = fmt.Sprintf("%s registered at %s", w.pending[id].what, w.pending[id].site)
+	}
+	return described
+}
+
+// tc39TimersHook gives the tests a setTimeout that never fires, which is what a test would get
+// from a host failing to run its timers.
+type tc39TimersHook struct{}
+
+func (tc39TimersHook) Before(tc *TestInfo, vm *goja.Runtime) error {
+	timers := make(map[int64]func())
+	var next int64
+	vm.Set("setTimeout", func(call goja.FunctionCall) goja.Value {
+		next++
+		timers[next] = tc.HostWork.Register("the setTimeout callback")
+		return vm.ToValue(next)
+	})
+	vm.Set("clearTimeout", func(id int64) {
+		if done, ok := timers[id]; ok {
+			done()
+			delete(timers, id)
+		}
+	})
+	return nil
+}
+
+func (tc39TimersHook) After(*TestInfo, *TestResult) {}
+
+func TestPendingHostWork(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.opts.Hooks = []TestHook{tc39TimersHook{}}
+	tb := &tc39CountingTB{TB: t}
+
+	ctx.runTC39Test(tb, "test/cleared.js", "var id = setTimeout(function() {});\nclearTimeout(id);", &tc39Meta{}, false)
+	ctx.runTC39Test(tb, "test/cancelled.js", "setTimeout(function() {});\n$262.expectPending();", &tc39Meta{}, false)
+	ctx.runTC39Test(tb, "test/never-fired.js", "var x = 1;\n  setTimeout(function() {\n"+
+		"  assert.sameValue(x, 2);\n});", &tc39Meta{}, false)
+
+	results := ctx.results.resultsCopy()
+	require.Equal(t, CategoryPass, results["test/cleared.js-strict:false"].Category)
+	require.Equal(t, CategoryPass, results["test/cancelled.js-strict:false"].Category)
+	result := results["test/never-fired.js-strict:false"]
+	require.Equal(t, CategoryPendingHostWork, result.Category)
+	require.Equal(t, "test/never-fired.js: pending host work: "+
+		"the setTimeout callback registered at test/never-fired.js:2:3", result.Message)
+	require.Equal(t, 1, tb.errors)
+}
@@ -0,0 +1,99 @@
+package test262
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// parallelism is how many tests run at once, each in a runtime of its own.
+var parallelism = runtime.GOMAXPROCS(0) //nolint:gochecknoglobals
+
+// parallelismFromEnv returns TC39_PARALLELISM, def if it's not set.
+func parallelismFromEnv(def int) (int, error) {
+	v := os.Getenv("TC39_PARALLELISM")
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("TC39_PARALLELISM must be a positive number, got %q", v)
+	}
+	return n, nil
+}
+
+// tc39Workers returns how many tests run at once.
+func tc39Workers() int {
+	return parallelism
+}
+
+// runPool runs the tests as subtests of t on a pool of workers, each calling t.Run for the tests it
+// takes, so their failures are theirs as with the tests run one by one. The tests only share what
+// locks itself on tc39TestCtx, the results in particular are kept by variant and not in the order
+// the tests complete.
+func runPool(t *testing.T, tests []tc39Test, workers int) {
+	queue := make(chan tc39Test)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tc := range queue {
+				t.Run(subtestName(tc.name), tc.f)
+			}
+		}()
+	}
+	for _, tc := range tests {
+		queue <- tc
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func TestWorkerPool(t *testing.T) {
+	run := func(workers int) (map[string]TestResult, map[string]string, int) {
+		defer func(old int) { parallelism = old }(parallelism)
+		parallelism = workers
+		ctx := newFixtureCtx(t)
+		var failed int32
+		var tests []tc39Test
+		s := ctx.mainSuite()
+		d, err := discoverTests(s.root, "test/pool")
+		require.NoError(t, err)
+		for _, rel := range d.names {
+			name, file := s.key(rel), d.path(rel)
+			tests = append(tests, tc39Test{name: name, f: func(t *testing.T) {
+				tb := &tc39CountingTB{TB: t}
+				ctx.runTC39File(name, file, tb)
+				atomic.AddInt32(&failed, int32(tb.errors))
+			}})
+		}
+		t.Run(fmt.Sprintf("workers-%d", workers), func(t *testing.T) {
+			runPool(t, tests, workers)
+		})
+		results := ctx.results.resultsCopy()
+		for key, result := range results {
+			// only what the tests did is compared, not how long it took them
+			result.Duration, result.Timings, result.Meta = 0, tc39Timings{}, nil
+			results[key] = result
+		}
+		return results, ctx.results.errorsCopy(), int(failed)
+	}
+
+	results, errs, failed := run(1)
+	require.Len(t, results, 16)
+	require.Len(t, errs, 4)
+	require.Equal(t, 4, failed)
+	for i := 0; i < 3; i++ {
+		parallel, parallelErrs, parallelFailed := run(8)
+		require.Equal(t, results, parallel)
+		require.Equal(t, errs, parallelErrs)
+		require.Equal(t, failed, parallelFailed)
+	}
+}
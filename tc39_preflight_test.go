@@ -0,0 +1,119 @@
+package test262
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39PreflightScript exercises what every test relies on, the harness and the $262 host hooks.
+const tc39PreflightScript = `
+	assert(true);
+	assert.sameValue(1, 1);
+	assert.throws(TypeError, function() { null.x; });
+	var thrown;
+	try {
+		$ERROR("expected");
+	} catch (e) {
+		thrown = e;
+	}
+	if (!(thrown instanceof Test262Error)) {
+		throw new Error("$ERROR didn't throw a Test262Error");
+	}
+	var buffer = new ArrayBuffer(8);
+	$262.detachArrayBuffer(buffer);
+	assert.throws(TypeError, function() { new Uint8Array(buffer); }, "using a detached buffer");
+`
+
+// tc39PreflightNegatives are negative tests, which only pass if their errors are classified right.
+//nolint:gochecknoglobals
+var tc39PreflightNegatives = []struct {
+	src      string
+	negative TC39MetaNegative
+}{
+	{"var = 1;", TC39MetaNegative{Phase: "parse", Type: "SyntaxError"}},
+	{"null.x;", TC39MetaNegative{Phase: "runtime", Type: "TypeError"}},
+	{"throw new Test262Error();", TC39MetaNegative{Phase: "runtime", Type: "Test262Error"}},
+}
+
+// tc39CapturingTB keeps the errors reported to it instead of failing the test.
+type tc39CapturingTB struct {
+	testing.TB
+	mu     sync.Mutex
+	errors []string
+}
+
+func (tb *tc39CapturingTB) Errorf(format string, args ...interface{}) {
+	tb.mu.Lock()
+	tb.errors = append(tb.errors, strings.TrimSpace(fmt.Sprintf(format, args...)))
+	tb.mu.Unlock()
+}
+
+func (tb *tc39CapturingTB) Skip(args ...interface{}) {
+	tb.Errorf("skipped: %s", fmt.Sprint(args...))
+}
+
+// preflight checks that a test can run at all: that the runtime can be set up, the harness
+// loaded and used, and the errors of negative tests classified. Anything broken there would fail
+// every test the same way, so it's better reported once and precisely.
+func (ctx *tc39TestCtx) preflight(tb testing.TB) (err error) {
+	// a context of its own, so nothing from here ends up in the results
+	pre := &tc39TestCtx{base: ctx.base, compiler: ctx.compiler}
+	if err = pre.init(); err != nil {
+		return fmt.Errorf("preflight: %w", err)
+	}
+	step := "setting up the runtime"
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("preflight: %s: panic: %v", step, x)
+		}
+	}()
+
+	out := &tc39Output{}
+	defer out.flush(tb)
+	vm, _, _ := pre.newRuntime("preflight.js", out)
+	timings := &tc39Timings{}
+	for _, file := range []string{"assert.js", "sta.js"} {
+		step = "running harness/" + file
+		if err = pre.runFile(pre.base, path.Join("harness", file), vm, timings); err != nil {
+			return fmt.Errorf("preflight: %s: %w", step, err)
+		}
+	}
+	step = "running the sanity script"
+	prg, _, err := pre.compileTest("preflight.js", tc39PreflightScript, false, lib.CompatibilityModeExtended, nil)
+	if err != nil {
+		return fmt.Errorf("preflight: compiling the sanity script: %w", err)
+	}
+	if _, err = vm.RunProgram(prg); err != nil {
+		return fmt.Errorf("preflight: %s: %w", step, err)
+	}
+
+	for _, negative := range tc39PreflightNegatives {
+		step = fmt.Sprintf("running the negative test %q", negative.src)
+		capture := &tc39CapturingTB{TB: tb}
+		pre.runTC39Test(capture, "preflight.js", negative.src, &tc39Meta{Negative: negative.negative}, false)
+		if len(capture.errors) > 0 {
+			return fmt.Errorf("preflight: the negative test %q expecting a %s %s wasn't classified right: %s",
+				negative.src, negative.negative.Phase, negative.negative.Type, capture.errors[0])
+		}
+	}
+	return nil
+}
+
+// TestTC39Preflight runs the preflight against the harness copy in the fixtures, so the runner
+// itself is checked even in short mode and without a test262 checkout.
+func TestTC39Preflight(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	require.NoError(t, ctx.preflight(t))
+
+	broken := newFixtureCtx(t)
+	broken.base = "testdata/suites/k6tests"
+	err := broken.preflight(t)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "preflight: running harness/assert.js")
+}
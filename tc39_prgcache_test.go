@@ -0,0 +1,109 @@
+package test262
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/compiler"
+	"github.com/loadimpact/k6/lib/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39CacheWorkers is how many goroutines at least hammer the program cache in its race test and
+// benchmark, like the workers of a parallel run.
+const tc39CacheWorkers = 8
+
+// newLazyCacheCtx returns a fixture context without the precompiled harness, so every include goes
+// through prgCache as the ones added to the harness after init do.
+func newLazyCacheCtx(tb testing.TB) *tc39TestCtx {
+	ctx := &tc39TestCtx{base: tc39FixturesBase, compiler: compiler.New(testutils.NewLogger(tb))}
+	require.NoError(tb, ctx.init())
+	ctx.harnessPrgs = map[string]*goja.Program{}
+	return ctx
+}
+
+// TestProgramCacheRace compiles the same includes from many goroutines at once, each of them being
+// compiled once and every goroutine getting that program. It's only meaningful with -race.
+func TestProgramCacheRace(t *testing.T) {
+	ctx := newLazyCacheCtx(t)
+	includes := []string{"harness/assert.js", "harness/sta.js", "harness/compareArray.js"}
+	const rounds = 50
+
+	var mu sync.Mutex
+	compiled := make(map[string]int)
+	prgs := make(map[string]map[*goja.Program]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < tc39CacheWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				for _, include := range includes {
+					prg, cached, err := ctx.compile(ctx.base, include)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					if !cached {
+						compiled[include]++
+					}
+					if prgs[include] == nil {
+						prgs[include] = make(map[*goja.Program]bool)
+					}
+					prgs[include][prg] = true
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, include := range includes {
+		require.Equal(t, 1, compiled[include], include)
+		require.Len(t, prgs[include], 1, include)
+	}
+}
+
+// BenchmarkProgramCache measures the hits of the program cache from at least tc39CacheWorkers
+// goroutines, taking its lock shared as compile does against exclusively as it did before.
+func BenchmarkProgramCache(b *testing.B) {
+	ctx := newLazyCacheCtx(b)
+	includes := []string{"harness/assert.js", "harness/sta.js", "harness/compareArray.js"}
+	files := make([]string, len(includes))
+	for i, include := range includes {
+		if _, _, err := ctx.compile(ctx.base, include); err != nil {
+			b.Fatal(err)
+		}
+		files[i] = osPath(ctx.base, include)
+	}
+	parallelism := (tc39CacheWorkers + runtime.GOMAXPROCS(0) - 1) / runtime.GOMAXPROCS(0)
+
+	b.Run("shared", func(b *testing.B) {
+		b.SetParallelism(parallelism)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				if _, _, err := ctx.compile(ctx.base, includes[i%len(includes)]); err != nil {
+					panic(err)
+				}
+			}
+		})
+	})
+	b.Run("exclusive", func(b *testing.B) {
+		b.SetParallelism(parallelism)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				// the same work as a hit of compile, under the exclusive lock
+				file := osPath(ctx.base, slashPath(includes[i%len(includes)]))
+				ctx.prgCacheLock.Lock()
+				c, ok := ctx.prgCache[file]
+				ctx.prgCacheLock.Unlock()
+				if !ok || c.prg == nil {
+					panic(fmt.Sprintf("%s isn't cached", file))
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,157 @@
+package test262
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// tc39QuarantineFile lists the tests skipped for a while, until a bug in them or in goja is fixed,
+// without putting them in the baseline.
+const tc39QuarantineFile = "./quarantine.yaml"
+
+const tc39QuarantineDateLayout = "2006-01-02"
+
+type tc39QuarantineEntry struct {
+	Test   string `yaml:"test"`
+	Reason string `yaml:"reason"`
+	// Expires is the last day the test is skipped on, it's mandatory so the quarantine doesn't
+	// turn into another permanent skip list.
+	Expires string `yaml:"expires"`
+}
+
+// tc39Quarantine has the entries that didn't expire yet, by their test, and the warnings about
+// the ones that did.
+type tc39Quarantine struct {
+	entries  map[string]tc39QuarantineEntry
+	warnings []string
+
+	mu      sync.Mutex
+	skipped int
+}
+
+// parseQuarantine validates the entries and keeps the ones still in effect today.
+func parseQuarantine(name string, b []byte, today time.Time) (*tc39Quarantine, error) {
+	var entries []tc39QuarantineEntry
+	if err := yaml.UnmarshalStrict(b, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	q := &tc39Quarantine{entries: make(map[string]tc39QuarantineEntry)}
+	seen := make(map[string]bool)
+	for i, e := range entries {
+		switch {
+		case e.Test == "":
+			return nil, fmt.Errorf("%s: entry %d has no test", name, i+1)
+		case e.Reason == "":
+			return nil, fmt.Errorf("%s: %s has no reason", name, e.Test)
+		case e.Expires == "":
+			return nil, fmt.Errorf("%s: %s has no expires, every quarantine has to end", name, e.Test)
+		case seen[slashPath(e.Test)]:
+			return nil, fmt.Errorf("%s: %s is there twice", name, e.Test)
+		}
+		expires, err := time.Parse(tc39QuarantineDateLayout, e.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s expires on %q, which isn't a YYYY-MM-DD date", name, e.Test, e.Expires)
+		}
+		e.Test = slashPath(e.Test)
+		seen[e.Test] = true
+		if today.Format(tc39QuarantineDateLayout) > expires.Format(tc39QuarantineDateLayout) {
+			q.warnings = append(q.warnings, fmt.Sprintf("the quarantine of %s (%s) expired on %s, "+
+				"it runs again: fix it or put its error in the baseline and remove it from %s",
+				e.Test, e.Reason, e.Expires, name))
+			continue
+		}
+		q.entries[e.Test] = e
+	}
+	return q, nil
+}
+
+func loadQuarantine(name string, today time.Time) (*tc39Quarantine, error) {
+	b, err := ioutil.ReadFile(name) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	return parseQuarantine(name, b, today)
+}
+
+// check tells if the test is quarantined, counting it if it is.
+func (q *tc39Quarantine) check(name string) (tc39QuarantineEntry, bool) {
+	e, ok := q.entries[slashPath(name)]
+	if ok {
+		q.mu.Lock()
+		q.skipped++
+		q.mu.Unlock()
+	}
+	return e, ok
+}
+
+// summary is the line with how many tests were quarantined, empty if none was.
+func (q *tc39Quarantine) summary() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.skipped == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d quarantined tests skipped, see %s", q.skipped, tc39QuarantineFile)
+}
+
+func TestQuarantine(t *testing.T) {
+	_, err := loadQuarantine(tc39QuarantineFile, time.Now())
+	require.NoError(t, err)
+
+	today := time.Date(2020, 11, 1, 15, 0, 0, 0, time.UTC)
+	q, err := parseQuarantine("quarantine.yaml", []byte(`
+- test: test/built-ins/Array/a.js
+  reason: upstream bug
+  expires: 2020-11-01
+- test: test/built-ins/Array/b.js
+  reason: goja crash
+  expires: 2020-10-31
+`), today)
+	require.NoError(t, err)
+	require.Equal(t, []string{"the quarantine of test/built-ins/Array/b.js (goja crash) expired on 2020-10-31, " +
+		"it runs again: fix it or put its error in the baseline and remove it from quarantine.yaml"}, q.warnings)
+	e, ok := q.check(`test\built-ins\Array\a.js`)
+	require.True(t, ok)
+	require.Equal(t, "upstream bug", e.Reason)
+	_, ok = q.check("test/built-ins/Array/b.js")
+	require.False(t, ok)
+	require.Equal(t, "1 quarantined tests skipped, see "+tc39QuarantineFile, q.summary())
+
+	for src, expected := range map[string]string{
+		"- test: a.js\n  reason: r\n":                       "a.js has no expires",
+		"- test: a.js\n  expires: 2020-11-01\n":             "a.js has no reason",
+		"- reason: r\n  expires: 2020-11-01\n":              "entry 1 has no test",
+		"- test: a.js\n  reason: r\n  expires: next week\n": `a.js expires on "next week"`,
+		"- test: a.js\n  reason: r\n  expires: 2020-11-01\n" +
+			"- test: ./a.js\n  reason: r\n  expires: 2020-11-01\n": "./a.js is there twice",
+		"- test: a.js\n  reason: r\n  expire: 2020-11-01\n": "field expire not found",
+	} {
+		_, err = parseQuarantine("quarantine.yaml", []byte(src), today)
+		require.Error(t, err, src)
+		require.Contains(t, err.Error(), expected)
+	}
+}
+
+func TestQuarantineSkips(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	var err error
+	ctx.extraSuites, err = extraSuites(ctx.base, "testdata/suites/own")
+	require.NoError(t, err)
+	ctx.quarantine, err = parseQuarantine("quarantine.yaml",
+		[]byte("- test: own/bar.js\n  reason: testing\n  expires: 2999-01-01\n"), time.Now())
+	require.NoError(t, err)
+
+	t.Run("own/bar.js", func(t *testing.T) {
+		ctx.runTC39File("own/bar.js", "bar.js", t)
+	})
+	result := ctx.results.resultsCopy()["own/bar.js-strict:false"]
+	require.Equal(t, CategorySkippedQuarantined, result.Category)
+	require.Equal(t, "Quarantined until 2999-01-01: testing", result.Message)
+	require.NotEmpty(t, ctx.quarantine.summary())
+}
@@ -17,15 +17,19 @@ func (ctx *tc39TestCtx) runTest(name string, f func(t *testing.T)) {
 	}
 }
 
+// flush runs the group straight under the tc39 subtest, a subtest of its own for every group would
+// be renamed tc39#01 and up by go test, which no -run pattern printed beforehand can know.
 func (ctx *tc39TestCtx) flush() {
-	ctx.t.Run("tc39", func(t *testing.T) {
-		for _, tc := range ctx.testQueue {
-			tc := tc
-			t.Run(tc.name, func(t *testing.T) {
-				t.Parallel()
-				tc.f(t)
-			})
-		}
-	})
+	runPool(ctx.t, ctx.testQueue, parallelism)
 	ctx.testQueue = ctx.testQueue[:0]
 }
+
+// tc39SubtestLevels are the names of the subtests between TestTC39 and the tests.
+func tc39SubtestLevels() []string {
+	return []string{"tc39"}
+}
+
+// tc39BuildFlags are the flags of go test building the tests as they are, for rerunCommand.
+func tc39BuildFlags() []string {
+	return []string{"-race"}
+}
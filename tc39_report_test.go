@@ -0,0 +1,237 @@
+package test262
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39ReportSchemaVersion is bumped whenever a field of the TC39_REPORT document changes meaning
+// or goes away.
+const tc39ReportSchemaVersion = 1
+
+// tc39ReportGolden is the report of the fixture run in TestReport, rewritten with
+// TC39_UPDATE_REPORT_GOLDEN=1 after a deliberate change of the schema.
+const tc39ReportGolden = "testdata/report.golden.json"
+
+// tc39ReportFilterEnv are the variables that select which tests run, reported as the filters of a
+// run so a partial report isn't mistaken for a full one.
+//nolint:gochecknoglobals
+var tc39ReportFilterEnv = []string{
+	"TC39_FILTER", "TC39_META_FILTER", "TC39_SHARD", "TC39_INCLUDE_DIRS", "TC39_EXCLUDE_DIRS",
+	"TC39_ONLY_FAILING", "TC39_TEST",
+}
+
+// tc39Report is the document TC39_REPORT writes, everything about a run in one place instead of
+// interleaved with the output of go test.
+type tc39Report struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Run           tc39ReportRun    `json:"run"`
+	Tests         []tc39ReportTest `json:"tests"`
+}
+
+type tc39ReportRun struct {
+	// Base is the test262 checkout the tests ran from, and Test262Commit its commit.
+	Base          string `json:"base"`
+	Test262Commit string `json:"test262Commit"`
+	// K6Version is the version of the k6 module, whose compiler transforms the tests.
+	K6Version   string `json:"k6Version"`
+	GojaVersion string `json:"gojaVersion"`
+	Parallelism int    `json:"parallelism"`
+	// Filters has the variables selecting the tests that were set, and -run if it selects subtests.
+	Filters     map[string]string `json:"filters,omitempty"`
+	Started     string            `json:"started"`
+	WallSeconds float64           `json:"wallSeconds"`
+}
+
+// tc39ReportTest is the result of a test variant. Status is one of pass, fail, skip, timeout and
+// panic, Category the finer result it's derived from.
+type tc39ReportTest struct {
+	Path       string  `json:"path"`
+	Strict     bool    `json:"strict"`
+	Mode       string  `json:"mode"`
+	Status     string  `json:"status"`
+	Category   string  `json:"category"`
+	DurationMS float64 `json:"durationMs"`
+	Error      string  `json:"error,omitempty"`
+	SkipReason string  `json:"skipReason,omitempty"`
+}
+
+// reportStatus returns the coarse status of a result, a failure being any unexpected result other
+// than a timeout or a panic as well as an expected failure.
+func reportStatus(c ResultCategory) string {
+	switch {
+	case c == CategoryPass:
+		return "pass"
+	case c.skipped():
+		return "skip"
+	case c == CategoryTimeout:
+		return "timeout"
+	case c == CategoryPanic:
+		return "panic"
+	default:
+		return "fail"
+	}
+}
+
+// newReport returns the report of the results, sorted by test, strictness and mode.
+func newReport(run tc39ReportRun, results map[string]TestResult) (*tc39Report, error) {
+	report := &tc39Report{SchemaVersion: tc39ReportSchemaVersion, Run: run, Tests: make([]tc39ReportTest, 0, len(results))}
+	for nameKey, result := range results {
+		k, err := parseLegacyKey(nameKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		test := tc39ReportTest{
+			Path: k.Test, Strict: k.Strict, Mode: k.Mode, Status: reportStatus(result.Category),
+			Category: result.Category.String(), DurationMS: float64(result.Duration) / float64(time.Millisecond),
+		}
+		if result.Category.skipped() {
+			test.SkipReason = result.Message
+		} else {
+			test.Error = result.Message
+		}
+		report.Tests = append(report.Tests, test)
+	}
+	sort.Slice(report.Tests, func(i, j int) bool {
+		a, b := report.Tests[i], report.Tests[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Strict != b.Strict {
+			return !a.Strict
+		}
+		return a.Mode < b.Mode
+	})
+	return report, nil
+}
+
+// reportRun returns the metadata of a run that started at started and took wall.
+func (ctx *tc39TestCtx) reportRun(started time.Time, wall time.Duration) tc39ReportRun {
+	run := tc39ReportRun{
+		Base: ctx.base, Test262Commit: test262Commit(ctx.base), K6Version: k6Version(),
+		GojaVersion: moduleVersion("github.com/dop251/goja"), Parallelism: tc39Workers(),
+		Started: started.UTC().Format(time.RFC3339), WallSeconds: wall.Seconds(),
+	}
+	for _, key := range tc39ReportFilterEnv {
+		if v := os.Getenv(key); v != "" {
+			if run.Filters == nil {
+				run.Filters = make(map[string]string)
+			}
+			run.Filters[key] = v
+		}
+	}
+	if runFilterActive() {
+		if run.Filters == nil {
+			run.Filters = make(map[string]string)
+		}
+		run.Filters["-run"] = flag.Lookup("test.run").Value.String()
+	}
+	return run
+}
+
+func writeReport(file string, report *tc39Report) error {
+	return writeArtifact(file, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	})
+}
+
+// writeReport writes the report of the run so far, for TC39_REPORT.
+func (ctx *tc39TestCtx) writeReport(file string, started time.Time) error {
+	report, err := newReport(ctx.reportRun(started, time.Since(started)), ctx.results.resultsCopy())
+	if err != nil {
+		return err
+	}
+	return writeReport(file, report)
+}
+
+func TestReport(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.expectedErrors = map[string]string{
+		"test/dump/fail.js-strict:true": `~Test262Error: Expected SameValue\(«a\d», «b»\) to be true`,
+	}
+	names := []string{"test/compat/es5.js", "test/dump/fail.js"}
+	t.Run("tc39", func(t *testing.T) {
+		for _, name := range names {
+			name := name
+			t.Run(name, func(t *testing.T) {
+				ctx.runTC39File(name, name, &tc39CountingTB{TB: t})
+			})
+		}
+	})
+	results := ctx.results.resultsCopy()
+	// the durations differ from run to run, and so do the timeouts and panics the fixtures don't have
+	for nameKey, result := range results {
+		result.Duration = 1500 * time.Microsecond
+		results[nameKey] = result
+	}
+	results["test/slow.js-strict:false"] = TestResult{
+		Category: CategoryTimeout, Message: "test/slow.js: the test didn't finish in TC39_TEST_TIMEOUT (1s)", Duration: time.Second,
+	}
+	results["test/panic.js-strict:true-mode:base"] = TestResult{Category: CategoryPanic, Message: "panic: test/panic.js: x"}
+	results["test/bigint.js-strict:false"] = TestResult{Category: CategorySkippedFeature, Message: "Blacklisted feature BigInt"}
+	report, err := newReport(tc39ReportRun{
+		Base: tc39FixturesBase, Test262Commit: "1ba3a7c4a93fc93b3d0d7e4146f59934a896837d", K6Version: "v0.29.0",
+		GojaVersion: "v0.0.0-20201022115936-e21ccf39bfce", Parallelism: 4, Filters: map[string]string{"TC39_FILTER": "test/compat"},
+		Started: "2020-01-02T03:04:05Z", WallSeconds: 1.25,
+	}, results)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "tc39-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, "report.json")
+	require.NoError(t, writeReport(file, report))
+	b, err := ioutil.ReadFile(file) //nolint:gosec
+	require.NoError(t, err)
+	if os.Getenv("TC39_UPDATE_REPORT_GOLDEN") != "" {
+		require.NoError(t, ioutil.WriteFile(tc39ReportGolden, b, 0o644))
+	}
+	golden, err := ioutil.ReadFile(tc39ReportGolden)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(b))
+
+	// the metadata of the run itself
+	require.NoError(t, os.Setenv("TC39_SHARD", "1/2"))
+	run := ctx.reportRun(time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600)), 90*time.Second)
+	require.NoError(t, os.Unsetenv("TC39_SHARD"))
+	require.Equal(t, tc39FixturesBase, run.Base)
+	require.Equal(t, "2020-01-02T02:04:05Z", run.Started)
+	require.Equal(t, 90.0, run.WallSeconds)
+	require.Equal(t, tc39Workers(), run.Parallelism)
+	require.Equal(t, map[string]string{"TC39_SHARD": "1/2"}, run.Filters)
+}
+
+func TestWriteReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-reports")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	ctx := newFixtureCtx(t)
+	ctx.opts.Out = ioutil.Discard
+	ctx.artifacts = newArtifacts(dir)
+	ctx.results.recordResult("test/compat/es5.js-strict:false", TestResult{Category: CategoryPass})
+	ctx.reportOut, ctx.junitOut = filepath.Join(dir, "report.json"), filepath.Join(dir, "junit.xml")
+	ctx.tapOut = filepath.Join(dir, "results.tap")
+	ctx.writeReports(t, time.Now())
+
+	// the manifest comes after the reports, and lists them
+	require.Equal(t, []tc39Artifact{
+		{Type: "report", Path: ctx.reportOut}, {Type: "junit", Path: ctx.junitOut}, {Type: "tap", Path: ctx.tapOut},
+	}, ctx.artifacts.entries)
+	b, err := ioutil.ReadFile(filepath.Join(dir, ctx.artifacts.runID, tc39ManifestFile)) //nolint:gosec
+	require.NoError(t, err)
+	var manifest tc39Manifest
+	require.NoError(t, json.Unmarshal(b, &manifest))
+	require.Equal(t, ctx.artifacts.entries, manifest.Artifacts)
+}
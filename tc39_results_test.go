@@ -0,0 +1,311 @@
+package test262
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Results collects everything the (possibly parallel) tests report back. It's the only
+// mutable state on tc39TestCtx that is written from the test goroutines, so all access goes
+// through its methods.
+type tc39Results struct {
+	mu sync.Mutex
+
+	// results has the result of every test variant by its key, the last one for a variant run
+	// multiple times.
+	results           map[string]TestResult
+	benchmark         tc39BenchmarkData
+	benchOnlyFailures int
+
+	// tests has the features of every test that ran and failures the first error of the ones
+	// that failed, expected or not, by name. They are only collected in update mode.
+	tests    map[string][]string
+	failures map[string]string
+
+	// variants has the error of every variant that ran, by its key, empty if it passed. It's
+	// only collected by the passes of the timezone matrix.
+	variants tc39TZPass
+
+	// coverage counts the tests of every esid by why they were skipped, the executed ones
+	// having an empty reason.
+	coverage map[string]map[string]int
+
+	// harness counts the variants that failed by the harness include that failed for them.
+	harness map[string]*tc39HarnessCause
+	// cachedFailures counts the loads of harness files that failed with their cached compile error.
+	cachedFailures int
+
+	// realms has the tests that called $262.createRealm.
+	realms map[string]bool
+
+	// hostFeatures counts the tests skipped for needing each of the hostFeatures.
+	hostFeatures map[string]int
+
+	// sabSkips has the tests skipped by the SharedArrayBuffer stub.
+	sabSkips map[string]bool
+
+	// panics has the variants that panicked by their key.
+	panics map[string]tc39Panic
+}
+
+func newTC39Results() *tc39Results {
+	return &tc39Results{
+		results:  make(map[string]TestResult),
+		tests:    make(map[string][]string),
+		failures: make(map[string]string),
+		variants: make(tc39TZPass),
+		coverage: make(map[string]map[string]int),
+		harness:  make(map[string]*tc39HarnessCause),
+		realms:   make(map[string]bool),
+
+		hostFeatures: make(map[string]int),
+		sabSkips:     make(map[string]bool),
+		panics:       make(map[string]tc39Panic),
+	}
+}
+
+// recordResult records the result of a variant. Its error isn't kept, as a JS one holds on to the
+// whole runtime, the message describes it.
+func (r *tc39Results) recordResult(nameKey string, result TestResult) {
+	result.Err = nil
+	r.mu.Lock()
+	r.results[nameKey] = result
+	r.mu.Unlock()
+}
+
+func (r *tc39Results) recordTest(name string, features []string) {
+	r.mu.Lock()
+	r.tests[name] = features
+	r.mu.Unlock()
+}
+
+func (r *tc39Results) recordFailure(name, errStr string) {
+	r.mu.Lock()
+	if _, ok := r.failures[name]; !ok {
+		r.failures[name] = errStr
+	}
+	r.mu.Unlock()
+}
+
+// testsRun returns copies of the recorded tests and failures.
+func (r *tc39Results) testsRun() (tests map[string][]string, failures map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tests = make(map[string][]string, len(r.tests))
+	for k, v := range r.tests {
+		tests[k] = v
+	}
+	failures = make(map[string]string, len(r.failures))
+	for k, v := range r.failures {
+		failures[k] = v
+	}
+	return tests, failures
+}
+
+// recordVariant records the result of a variant, the first one recorded for it is kept.
+func (r *tc39Results) recordVariant(nameKey, errStr string) {
+	r.mu.Lock()
+	if _, ok := r.variants[nameKey]; !ok {
+		r.variants[nameKey] = errStr
+	}
+	r.mu.Unlock()
+}
+
+func (r *tc39Results) variantsRun() tc39TZPass {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	variants := make(tc39TZPass, len(r.variants))
+	for k, v := range r.variants {
+		variants[k] = v
+	}
+	return variants
+}
+
+func (r *tc39Results) recordRealm(name string) {
+	r.mu.Lock()
+	r.realms[slashPath(name)] = true
+	r.mu.Unlock()
+}
+
+// realmSummary is the line with how many tests created realms and how many of them passed all
+// their variants, empty if none did. All of them were skipped before createRealm was implemented.
+func (r *tc39Results) realmSummary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.realms) == 0 {
+		return ""
+	}
+	passed := 0
+	for name := range r.realms {
+		ran, failed := false, false
+		for _, strict := range []bool{false, true} {
+			if result, ok := r.results[variantKey(name, strict)]; ok {
+				ran, failed = true, failed || result.Category != CategoryPass
+			}
+		}
+		if ran && !failed {
+			passed++
+		}
+	}
+	return fmt.Sprintf("%d tests ran using $262.createRealm, which skipped them before it was implemented, %d of them passed",
+		len(r.realms), passed)
+}
+
+func (r *tc39Results) recordHostFeature(feature string) {
+	r.mu.Lock()
+	r.hostFeatures[feature]++
+	r.mu.Unlock()
+}
+
+// hostFeatureSummary has a line for every host feature some test was skipped for, by feature.
+func (r *tc39Results) hostFeatureSummary() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := make([]string, 0, len(r.hostFeatures))
+	for feature, n := range r.hostFeatures {
+		lines = append(lines, fmt.Sprintf("%d tests skipped needing the host feature %s", n, feature))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func (r *tc39Results) recordSABSkip(name string) {
+	r.mu.Lock()
+	r.sabSkips[slashPath(name)] = true
+	r.mu.Unlock()
+}
+
+// sabSummary is the line with how many tests the SharedArrayBuffer stub skipped, empty if none,
+// which goes down as goja gets closer to having it.
+func (r *tc39Results) sabSummary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sabSkips) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d tests skipped for using SharedArrayBuffer, which goja doesn't have", len(r.sabSkips))
+}
+
+func (r *tc39Results) recordCoverage(esid, skipReason string) {
+	r.mu.Lock()
+	reasons := r.coverage[esid]
+	if reasons == nil {
+		reasons = make(map[string]int)
+		r.coverage[esid] = reasons
+	}
+	reasons[skipReason]++
+	r.mu.Unlock()
+}
+
+func (r *tc39Results) coverageByEsid() map[string]map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	coverage := make(map[string]map[string]int, len(r.coverage))
+	for esid, reasons := range r.coverage {
+		coverage[esid] = make(map[string]int, len(reasons))
+		for reason, n := range reasons {
+			coverage[esid][reason] = n
+		}
+	}
+	return coverage
+}
+
+func (r *tc39Results) addBenchmark(item tc39BenchmarkItem) {
+	r.mu.Lock()
+	r.benchmark = append(r.benchmark, item)
+	r.mu.Unlock()
+}
+
+func (r *tc39Results) countBenchOnlyFailure() {
+	r.mu.Lock()
+	r.benchOnlyFailures++
+	r.mu.Unlock()
+}
+
+// resultsCopy returns a copy of the recorded results.
+func (r *tc39Results) resultsCopy() map[string]TestResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := make(map[string]TestResult, len(r.results))
+	for k, v := range r.results {
+		m[k] = v
+	}
+	return m
+}
+
+// errorsCopy returns the unexpected errors recorded, in the format of breaking_test_errors.json.
+func (r *tc39Results) errorsCopy() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return legacyErrors(r.results)
+}
+
+// benchmarkItems returns a copy of the benchmark items recorded so far.
+func (r *tc39Results) benchmarkItems() tc39BenchmarkData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append(tc39BenchmarkData(nil), r.benchmark...)
+}
+
+func (r *tc39Results) benchOnlyFailureCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.benchOnlyFailures
+}
+
+// tc39CountingTB counts the assertion failures instead of failing the test, so that fail can be
+// driven down the path that records an error.
+type tc39CountingTB struct {
+	testing.TB
+	mu     sync.Mutex
+	errors int
+}
+
+func (tb *tc39CountingTB) Errorf(format string, args ...interface{}) {
+	tb.mu.Lock()
+	tb.errors++
+	tb.mu.Unlock()
+}
+
+// TestResultsRace drives the shared context from many goroutines at once. It's only
+// meaningful with -race.
+func TestResultsRace(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	const goroutines, rounds = 16, 50
+	for i := 0; i < goroutines; i++ {
+		ctx.expectedErrors[fmt.Sprintf("test/race-%d.js-strict:false", i)] = "expected"
+	}
+	tb := &tc39CountingTB{TB: t}
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("test/race-%d.js", i)
+			for j := 0; j < rounds; j++ {
+				for strict, errStr := range map[bool]string{false: "different", true: "unexpected"} {
+					ctx.results.recordResult(variantKey(name, strict), TestResult{
+						Category: ctx.fail(tb, name, strict, lib.CompatibilityModeExtended, errStr, ""), Message: errStr,
+					})
+				}
+				ctx.results.addBenchmark(tc39BenchmarkItem{name: name})
+				if _, _, err := ctx.compile(ctx.base, "harness/assert.js"); err != nil {
+					t.Error(err)
+					return
+				}
+				_ = ctx.results.benchmarkItems()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 2*goroutines*rounds, tb.errors)
+	require.Len(t, ctx.results.errorsCopy(), 2*goroutines)
+	require.Len(t, ctx.results.benchmarkItems(), goroutines*rounds)
+}
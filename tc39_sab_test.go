@@ -0,0 +1,96 @@
+package test262
+
+import (
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39SABStub stands in for the SharedArrayBuffer goja doesn't have, throwing IgnorableTestError
+// the moment a test touches it, which skips the test.
+type tc39SABStub struct {
+	used bool
+}
+
+// stubSharedArrayBuffer installs the stub in vm, only for a test declaring the SharedArrayBuffer
+// feature and if vm has none of its own. It returns nil if it didn't.
+func stubSharedArrayBuffer(vm *goja.Runtime, ignorableTestError goja.Value, meta *tc39Meta) *tc39SABStub {
+	if !meta.hasFeature("SharedArrayBuffer") {
+		return nil
+	}
+	if v := vm.Get("SharedArrayBuffer"); v != nil && !goja.IsUndefined(v) {
+		return nil
+	}
+	stub := &tc39SABStub{}
+	get := vm.ToValue(func(goja.FunctionCall) goja.Value {
+		stub.used = true
+		panic(ignorableTestError)
+	})
+	if err := vm.GlobalObject().DefineAccessorProperty("SharedArrayBuffer", get, nil, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(err)
+	}
+	return stub
+}
+
+// skipped tells if the test threw IgnorableTestError because of the stub.
+func (s *tc39SABStub) skipped() bool {
+	return s != nil && s.used
+}
+
+func TestSharedArrayBufferStub(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	sab := &tc39Meta{Features: []string{"SharedArrayBuffer"}}
+
+	vm, ignorable, _ := ctx.newRuntime("test/sab.js", &tc39Output{})
+	stub := stubSharedArrayBuffer(vm, ignorable, sab)
+	require.NotNil(t, stub)
+	require.False(t, stub.skipped())
+	for _, src := range []string{
+		`SharedArrayBuffer`,
+		`typeof SharedArrayBuffer`,
+		`this.SharedArrayBuffer`,
+		`'use strict'; new SharedArrayBuffer(8)`,
+		// a second access throws as well, the getter isn't a one-off
+		`SharedArrayBuffer`,
+	} {
+		_, err := vm.RunString(src)
+		exc, ok := err.(*goja.Exception)
+		require.True(t, ok, "%s threw %v", src, err)
+		require.Equal(t, ignorable, exc.Value(), src)
+	}
+	require.True(t, stub.skipped())
+	v, err := vm.RunString(`
+		var d = Object.getOwnPropertyDescriptor(this, "SharedArrayBuffer");
+		[typeof d.get, d.set, d.enumerable, d.configurable].join()`)
+	require.NoError(t, err)
+	require.Equal(t, "function,,false,false", v.String())
+
+	// only the tests declaring the feature get it
+	vm, ignorable, _ = ctx.newRuntime("test/other.js", &tc39Output{})
+	require.Nil(t, stubSharedArrayBuffer(vm, ignorable, &tc39Meta{}))
+	v, err = vm.RunString(`typeof SharedArrayBuffer`)
+	require.NoError(t, err)
+	require.Equal(t, "undefined", v.String())
+
+	// and a runtime with one of its own keeps it
+	vm.Set("SharedArrayBuffer", func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	require.Nil(t, stubSharedArrayBuffer(vm, ignorable, sab))
+
+	// the classification relies on the stub, a test using SharedArrayBuffer is skipped and counted
+	t.Run("test/sab.js", func(t *testing.T) {
+		defer func() {
+			require.True(t, t.Skipped())
+			result := ctx.results.resultsCopy()["test/sab.js-strict:false"]
+			require.Equal(t, CategorySkippedIgnorable, result.Category)
+			require.Equal(t, "1 tests skipped for using SharedArrayBuffer, which goja doesn't have", ctx.results.sabSummary())
+		}()
+		ctx.runTC39Test(t, "test/sab.js", `new SharedArrayBuffer(1);`, sab, false)
+	})
+	t.Run("test/undeclared.js", func(t *testing.T) {
+		tb := &tc39CountingTB{TB: t}
+		ctx.runTC39Test(tb, "test/undeclared.js", `new SharedArrayBuffer(1);`, &tc39Meta{}, false)
+		require.Equal(t, CategoryNewFailure, ctx.results.resultsCopy()["test/undeclared.js-strict:false"].Category)
+		require.Equal(t, 1, tb.errors)
+	})
+}
@@ -0,0 +1,96 @@
+package test262
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39RandHook gives every test its own Math.random sequence, seeded from the seed of the run
+// and the name of the test, so a test that fails only with some sequences can be run again with
+// the same one.
+type tc39RandHook struct {
+	runSeed   int64
+	overrides map[string]int64
+}
+
+// randFromEnv reads the seed of the run from TC39_SEED, picking one if it isn't set, and the
+// seeds of single tests from TC39_SEED_OVERRIDE=<test>=<seed>,...
+func randFromEnv() (*tc39RandHook, error) {
+	h := &tc39RandHook{runSeed: time.Now().UnixNano(), overrides: make(map[string]int64)}
+	if v := os.Getenv("TC39_SEED"); v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("TC39_SEED: %w", err)
+		}
+		h.runSeed = seed
+	}
+	if v := os.Getenv("TC39_SEED_OVERRIDE"); v != "" {
+		for _, override := range strings.Split(v, ",") {
+			i := strings.LastIndexByte(override, '=')
+			if i == -1 {
+				return nil, fmt.Errorf("TC39_SEED_OVERRIDE: %q isn't <test>=<seed>", override)
+			}
+			seed, err := strconv.ParseInt(override[i+1:], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("TC39_SEED_OVERRIDE: %w", err)
+			}
+			h.overrides[slashPath(override[:i])] = seed
+		}
+	}
+	return h, nil
+}
+
+// seed returns the seed of the test with the given name.
+func (h *tc39RandHook) seed(name string) int64 {
+	if seed, ok := h.overrides[name]; ok {
+		return seed
+	}
+	f := fnv.New64a()
+	_, _ = f.Write([]byte(name))
+	return h.runSeed ^ int64(f.Sum64())
+}
+
+func (h *tc39RandHook) Before(tc *TestInfo, vm *goja.Runtime) error {
+	vm.SetRandSource(rand.New(rand.NewSource(h.seed(tc.Name))).Float64) //nolint:gosec
+	return nil
+}
+
+func (h *tc39RandHook) After(*TestInfo, *TestResult) {}
+
+func (h *tc39RandHook) summary() string {
+	return fmt.Sprintf("Math.random seed of the run: TC39_SEED=%d", h.runSeed)
+}
+
+func TestRandHook(t *testing.T) {
+	h := &tc39RandHook{runSeed: 42, overrides: map[string]int64{"test/overridden.js": 7}}
+	require.Equal(t, h.seed("test/a.js"), h.seed("test/a.js"))
+	require.NotEqual(t, h.seed("test/a.js"), h.seed("test/b.js"))
+	require.Equal(t, int64(7), h.seed("test/overridden.js"))
+
+	ctx := newFixtureCtx(t)
+	ctx.opts.Hooks = []TestHook{h}
+	sequence := func(name string) string {
+		vm := goja.New()
+		require.NoError(t, h.Before(&TestInfo{Name: name}, vm))
+		v, err := vm.RunString(`[Math.random(), Math.random(), Math.random()].join()`)
+		require.NoError(t, err)
+		return v.String()
+	}
+	require.Equal(t, sequence("test/a.js"), sequence("test/a.js"))
+	require.NotEqual(t, sequence("test/a.js"), sequence("test/b.js"))
+
+	// the same sequence is seen by the test
+	src := fmt.Sprintf(`assert.sameValue([Math.random(), Math.random(), Math.random()].join(), %q);`,
+		sequence("test/a.js"))
+	ctx.runTC39Test(t, "test/a.js", src, &tc39Meta{}, true)
+	require.Empty(t, ctx.results.errorsCopy())
+}
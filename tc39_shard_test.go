@@ -0,0 +1,142 @@
+package test262
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Shard is the part of the tests a CI job runs of TC39_SHARD=index/count, selected by a hash
+// of their paths so a test stays in its shard whatever is added next to it. A nil shard has all the
+// tests.
+type tc39Shard struct {
+	index, count int
+	// tests and covered count the tests seen by the walk and the ones of the shard, only the
+	// walking goroutine touches them
+	tests, covered int
+}
+
+func shardFromEnv() (*tc39Shard, error) {
+	v := os.Getenv("TC39_SHARD")
+	if v == "" {
+		return nil, nil
+	}
+	s := &tc39Shard{}
+	if _, err := fmt.Sscanf(v, "%d/%d", &s.index, &s.count); err != nil || s.count < 1 || s.index < 1 ||
+		s.index > s.count || fmt.Sprintf("%d/%d", s.index, s.count) != v {
+		return nil, fmt.Errorf("TC39_SHARD must be index/count with 1 <= index <= count, got %q", v)
+	}
+	return s, nil
+}
+
+// has tells if the test with the given name is in the shard.
+func (s *tc39Shard) has(name string) bool {
+	if s == nil {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(slashPath(name)))
+	return int(h.Sum32()%uint32(s.count)) == s.index-1
+}
+
+// take counts the test and tells if it's in the shard.
+func (s *tc39Shard) take(name string) bool {
+	if s == nil {
+		return true
+	}
+	s.tests++
+	if !s.has(name) {
+		return false
+	}
+	s.covered++
+	return true
+}
+
+// expected returns the expected errors of the variants of the shard's tests, so the ones of the
+// other shards aren't compared with anything.
+func (s *tc39Shard) expected(errs map[string]string) map[string]string {
+	if s == nil {
+		return errs
+	}
+	own := make(map[string]string)
+	for nameKey, errStr := range errs {
+		if k, err := parseLegacyKey(nameKey, nil); err == nil && s.has(k.Test) {
+			own[nameKey] = errStr
+		}
+	}
+	return own
+}
+
+func (s *tc39Shard) summary() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("shard %d/%d (TC39_SHARD) ran %d of %d tests", s.index, s.count, s.covered, s.tests)
+}
+
+func TestShards(t *testing.T) {
+	for v, ok := range map[string]bool{
+		"1/1": true, "2/5": true, "5/5": true,
+		"0/5": false, "6/5": false, "1/0": false, "-1/5": false, "2": false, "2/5/1": false, "a/b": false, "2/5x": false,
+	} {
+		require.NoError(t, os.Setenv("TC39_SHARD", v))
+		s, err := shardFromEnv()
+		if ok {
+			require.NoError(t, err, v)
+			require.Equal(t, v, fmt.Sprintf("%d/%d", s.index, s.count))
+		} else {
+			require.EqualError(t, err, fmt.Sprintf("TC39_SHARD must be index/count with 1 <= index <= count, got %q", v))
+		}
+	}
+	require.NoError(t, os.Unsetenv("TC39_SHARD"))
+	s, err := shardFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, s)
+	require.True(t, s.has("test/a.js"))
+
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = fmt.Sprintf("test/built-ins/dir-%d/test-%d.js", i%37, i)
+	}
+	const count = 5
+	seen := make(map[string]int)
+	for index := 1; index <= count; index++ {
+		s := &tc39Shard{index: index, count: count}
+		for _, name := range names {
+			if s.take(name) {
+				seen[name]++
+			}
+		}
+		require.Equal(t, len(names), s.tests)
+		// roughly a fifth of them each
+		require.True(t, s.covered > 150 && s.covered < 250, "shard %d has %d tests", index, s.covered)
+		require.Equal(t, fmt.Sprintf("shard %d/5 (TC39_SHARD) ran %d of 1000 tests", index, s.covered), s.summary())
+	}
+	// the shards are disjoint and together have all the tests
+	require.Len(t, seen, len(names))
+	for name, n := range seen {
+		require.Equal(t, 1, n, name)
+	}
+
+	// only the shard's expected errors are compared
+	errs := map[string]string{}
+	for _, name := range names[:100] {
+		errs[variantKey(name, false)], errs[variantKey(name, true)] = "boom", "boom"
+	}
+	union := make(map[string]string)
+	for index := 1; index <= count; index++ {
+		s := &tc39Shard{index: index, count: count}
+		own := s.expected(errs)
+		for nameKey := range own {
+			k, err := parseLegacyKey(nameKey, nil)
+			require.NoError(t, err)
+			require.True(t, s.has(k.Test), nameKey)
+			require.NotContains(t, union, nameKey)
+			union[nameKey] = own[nameKey]
+		}
+	}
+	require.Equal(t, errs, union)
+}
@@ -0,0 +1,131 @@
+package test262
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// singleTest returns the path relative to the root of its suite of the test TC39_TEST names, an
+// error if there is no such test, so a typo doesn't run nothing and pass.
+func (ctx *tc39TestCtx) singleTest(name string) (string, error) {
+	s, rel := ctx.suite(name)
+	info, err := os.Stat(osPath(s.root, rel))
+	switch {
+	case err != nil:
+		return "", fmt.Errorf("TC39_TEST: %s doesn't exist: %w", name, err)
+	case info.IsDir() || path.Ext(rel) != ".js":
+		return "", fmt.Errorf("TC39_TEST: %s isn't a test file", name)
+	}
+	return rel, nil
+}
+
+// writeSingle writes everything about the run of the test of TC39_TEST: its metadata, the harness
+// it ran after, the result of every variant and what the compiler transformed it to.
+func (ctx *tc39TestCtx) writeSingle(w io.Writer, name, file string) {
+	s, _ := ctx.suite(name)
+	meta, src, err := parseTC39File(osPath(s.root, file), s.harness)
+	if err != nil {
+		fmt.Fprintf(w, "TC39_TEST %s can't be parsed: %v\n", name, err)
+		return
+	}
+	fmt.Fprintf(w, "TC39_TEST %s\n", name)
+	if b, err := yaml.Marshal(meta); err == nil {
+		fmt.Fprintf(w, "metadata:\n%s", b)
+	}
+	if harness := harnessFiles(meta); len(harness) > 0 {
+		fmt.Fprintf(w, "harness: %s\n", strings.Join(harness, ", "))
+	} else {
+		fmt.Fprintln(w, "harness: none")
+	}
+	results := ctx.results.resultsCopy()
+	for _, mode := range compatModes {
+		codes := make(map[bool]string)
+		for _, strict := range []bool{false, true} {
+			variant := "sloppy"
+			if strict {
+				variant = "strict"
+			}
+			variant += ", " + mode.String() + " mode"
+			result, ok := results[modeKey(name, strict, mode)]
+			switch {
+			case !ok:
+				fmt.Fprintf(w, "%s: didn't run, the flags of the test exclude it\n", variant)
+				continue
+			case result.Category.skipped():
+				fmt.Fprintf(w, "%s: %s: %s\n", variant, result.Category, result.Message)
+				continue
+			case result.Category == CategoryPass:
+				fmt.Fprintf(w, "%s: pass\n", variant)
+			default:
+				fmt.Fprintf(w, "%s: %s\nerror: %s\n", variant, result.Category, result.Message)
+				if result.Details != "" {
+					fmt.Fprintln(w, result.Details)
+				}
+			}
+			if mode == lib.CompatibilityModeBase {
+				fmt.Fprintln(w, "transformed source: none, the base mode doesn't transform it")
+				continue
+			}
+			_, code, err := ctx.compileJS(src, name, "", "", strict)
+			codes[strict] = code
+			switch {
+			case err != nil:
+				fmt.Fprintf(w, "transformed source: it doesn't compile: %v\n", err)
+			case code == src:
+				fmt.Fprintln(w, "transformed source: the same, it wasn't transformed")
+			case strict && codes[false] == code:
+				fmt.Fprintln(w, "transformed source: the same as the sloppy variant's")
+			default:
+				fmt.Fprintf(w, "transformed source:\n%s\n", strings.TrimSuffix(code, "\n"))
+			}
+		}
+	}
+}
+
+func TestSingleTest(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	for name, expected := range map[string]string{
+		"test/missing.js": "TC39_TEST: test/missing.js doesn't exist: ",
+		"test/compat":     "TC39_TEST: test/compat isn't a test file",
+	} {
+		_, err := ctx.singleTest(name)
+		require.Error(t, err, name)
+		require.Contains(t, err.Error(), expected)
+	}
+
+	for _, name := range []string{"test/dump/fail.js", "test/module/import.js"} {
+		file, err := ctx.singleTest(name)
+		require.NoError(t, err)
+		require.Equal(t, name, file)
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, file, &tc39CountingTB{TB: t})
+		})
+	}
+
+	var b strings.Builder
+	ctx.writeSingle(&b, "test/dump/fail.js", "test/dump/fail.js")
+	out := b.String()
+	require.Contains(t, out, "TC39_TEST test/dump/fail.js\nmetadata:\n")
+	require.Contains(t, out, "harness: assert.js, sta.js\n")
+	require.Contains(t, out, "sloppy, extended mode: new-failure\nerror: ")
+	require.Contains(t, out, "Expected SameValue(«a1», «b») to be true")
+	require.Contains(t, out, "stack:\n")
+	require.Contains(t, out, "strict, extended mode: new-failure\n")
+	require.Contains(t, out, "transformed source:\n")
+	require.Contains(t, out, "transformed source: the same as the sloppy variant's\n")
+
+	// a module only has its strict variant
+	b.Reset()
+	ctx.writeSingle(&b, "test/module/import.js", "test/module/import.js")
+	out = b.String()
+	require.Contains(t, out, "sloppy, extended mode: didn't run, the flags of the test exclude it\n")
+	require.Contains(t, out, "strict, extended mode: pass\n")
+}
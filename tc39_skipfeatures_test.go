@@ -0,0 +1,279 @@
+package test262
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// tc39SkipFeaturesFile has the features and the harness includes whose tests are skipped, with why,
+// and the esids admitting the tests without an es5id or es6id.
+const tc39SkipFeaturesFile = "./skip_features.yaml"
+
+type tc39SkipFeaturesConfig struct {
+	Features map[string]tc39SkipFeature `yaml:"features"`
+	// Includes are the harness files that don't compile or need what the runner stubs out, every
+	// test including them would fail with the same error unrelated to what it tests.
+	Includes     map[string]tc39SkipFeature `yaml:"includes"`
+	EsidPrefixes []string                   `yaml:"esid_prefixes"`
+}
+
+type tc39SkipFeature struct {
+	Reason string `yaml:"reason"`
+	// URL is where the support of the feature or include is tracked, it's optional.
+	URL string `yaml:"url"`
+}
+
+func (e tc39SkipFeature) String() string {
+	if e.URL == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s (%s)", e.Reason, e.URL)
+}
+
+// tc39SkipFeatures has the entries of skip_features.yaml by feature and include and its esid
+// prefixes, with how many tests were skipped for each feature and include and admitted by each
+// prefix.
+type tc39SkipFeatures struct {
+	entries      map[string]tc39SkipFeature
+	includes     map[string]tc39SkipFeature
+	esidPrefixes []string
+
+	mu              sync.Mutex
+	skipped         map[string]int
+	skippedIncludes map[string]int
+	admitted        map[string]int
+}
+
+// parseSkipFeatures validates the entries.
+func parseSkipFeatures(name string, b []byte) (*tc39SkipFeatures, error) {
+	var config tc39SkipFeaturesConfig
+	if err := yaml.UnmarshalStrict(b, &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	for _, entries := range []map[string]tc39SkipFeature{config.Features, config.Includes} {
+		for key, e := range entries {
+			if e.Reason == "" {
+				return nil, fmt.Errorf("%s: %s has no reason", name, key)
+			}
+			if e.URL != "" {
+				if u, err := url.Parse(e.URL); err != nil || !u.IsAbs() || u.Host == "" {
+					return nil, fmt.Errorf("%s: the url of %s, %q, isn't an absolute URL", name, key, e.URL)
+				}
+			}
+		}
+	}
+	seen := make(map[string]bool)
+	for _, prefix := range config.EsidPrefixes {
+		switch {
+		case prefix == "":
+			return nil, fmt.Errorf("%s: an esid prefix is empty", name)
+		case seen[prefix]:
+			return nil, fmt.Errorf("%s: the esid prefix %s is there twice", name, prefix)
+		}
+		seen[prefix] = true
+	}
+	return &tc39SkipFeatures{
+		entries: config.Features, includes: config.Includes, esidPrefixes: config.EsidPrefixes,
+		skipped: make(map[string]int), skippedIncludes: make(map[string]int), admitted: make(map[string]int),
+	}, nil
+}
+
+func loadSkipFeatures(name string) (*tc39SkipFeatures, error) {
+	b, err := ioutil.ReadFile(name) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	return parseSkipFeatures(name, b)
+}
+
+// check returns the first of the features that is skipped and its entry, counting the test for it
+// if there is one.
+func (s *tc39SkipFeatures) check(features []string) (string, tc39SkipFeature, bool) {
+	return s.first(features, s.entries, s.skipped)
+}
+
+// checkIncludes is check for the harness files the test includes.
+func (s *tc39SkipFeatures) checkIncludes(includes []string) (string, tc39SkipFeature, bool) {
+	return s.first(includes, s.includes, s.skippedIncludes)
+}
+
+func (s *tc39SkipFeatures) first(keys []string, entries map[string]tc39SkipFeature, counts map[string]int) (string, tc39SkipFeature, bool) {
+	for _, key := range keys {
+		if e, ok := entries[key]; ok {
+			s.mu.Lock()
+			counts[key]++
+			s.mu.Unlock()
+			return key, e, true
+		}
+	}
+	return "", tc39SkipFeature{}, false
+}
+
+// esidPrefix returns the prefix admitting the esid, the longest one if several do. A prefix has to
+// be followed by the end of the esid or a dot, sec-array doesn't admit sec-arraybuffer.
+func esidPrefix(esid string, prefixes []string) (string, bool) {
+	admitting := ""
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(esid, prefix) && (len(esid) == len(prefix) || esid[len(prefix)] == '.') &&
+			len(prefix) > len(admitting) {
+			admitting = prefix
+		}
+	}
+	return admitting, admitting != ""
+}
+
+// admit tells if the test with the esid runs without an es5id or es6id, counting it for the prefix
+// admitting it if it does.
+func (s *tc39SkipFeatures) admit(esid string) (string, bool) {
+	prefix, ok := esidPrefix(esid, s.esidPrefixes)
+	if ok {
+		s.mu.Lock()
+		s.admitted[prefix]++
+		s.mu.Unlock()
+	}
+	return prefix, ok
+}
+
+// summary has a line for every feature and include some test was skipped for and every esid prefix
+// that admitted some test.
+func (s *tc39SkipFeatures) summary() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := make([]string, 0, len(s.skipped)+len(s.skippedIncludes)+len(s.admitted))
+	for feature, n := range s.skipped {
+		lines = append(lines, fmt.Sprintf("%d tests skipped for the feature %s, see %s", n, feature, tc39SkipFeaturesFile))
+	}
+	for include, n := range s.skippedIncludes {
+		lines = append(lines, fmt.Sprintf("%d tests skipped for including %s, see %s", n, include, tc39SkipFeaturesFile))
+	}
+	for prefix, n := range s.admitted {
+		lines = append(lines, fmt.Sprintf("%d tests without an es5id or es6id admitted by the esid prefix %s, see %s", n, prefix, tc39SkipFeaturesFile))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestSkipFeatures(t *testing.T) {
+	s, err := loadSkipFeatures(tc39SkipFeaturesFile)
+	require.NoError(t, err)
+	require.Contains(t, s.entries, "BigInt")
+
+	s, err = parseSkipFeatures("skip_features.yaml", []byte(`
+features:
+  BigInt:
+    reason: not supported at all
+  Atomics:
+    reason: no agents
+    url: https://example.com/issues/1
+`))
+	require.NoError(t, err)
+	feature, e, ok := s.check([]string{"let", "Atomics", "BigInt"})
+	require.True(t, ok)
+	require.Equal(t, "Atomics", feature)
+	require.Equal(t, "no agents (https://example.com/issues/1)", e.String())
+	_, _, ok = s.check([]string{"let"})
+	require.False(t, ok)
+	_, _, ok = s.check([]string{"BigInt"})
+	require.True(t, ok)
+	require.Equal(t, []string{
+		"1 tests skipped for the feature Atomics, see " + tc39SkipFeaturesFile,
+		"1 tests skipped for the feature BigInt, see " + tc39SkipFeaturesFile,
+	}, s.summary())
+
+	for src, expected := range map[string]string{
+		"features:\n  BigInt:\n    url: https://example.com\n":              "BigInt has no reason",
+		"features:\n  BigInt:\n    reason: r\n    url: example.com/issue\n": `the url of BigInt, "example.com/issue", isn't an absolute URL`,
+		"features:\n  BigInt:\n    reason: r\n    issue: 1\n":               "field issue not found",
+		"features:\n- BigInt\n":                   "cannot unmarshal",
+		"BigInt:\n  reason: r\n":                  "field BigInt not found",
+		"esid_prefixes: [sec-array, sec-array]\n": "the esid prefix sec-array is there twice",
+		"esid_prefixes: ['']\n":                   "an esid prefix is empty",
+	} {
+		_, err = parseSkipFeatures("skip_features.yaml", []byte(src))
+		require.Error(t, err, src)
+		require.Contains(t, err.Error(), expected)
+	}
+
+	// the skip applies to the tests with an es5id or es6id too
+	ctx := newFixtureCtx(t)
+	t.Run("test/skip-features/es6id.js", func(t *testing.T) {
+		ctx.runTC39File("test/skip-features/es6id.js", "test/skip-features/es6id.js", t)
+	})
+	result := ctx.results.resultsCopy()[variantKey("test/skip-features/es6id.js", false)]
+	require.Equal(t, CategorySkippedFeature, result.Category)
+	require.Equal(t, "Skipped feature BigInt: not supported at all", result.Message)
+	require.Equal(t, []string{"1 tests skipped for the feature BigInt, see " + tc39SkipFeaturesFile}, ctx.skipFeatures.summary())
+}
+
+func TestSkipIncludes(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	var err error
+	ctx.skipFeatures, err = parseSkipFeatures("skip_features.yaml", []byte(`
+includes:
+  unsupportedHelper.js:
+    reason: needs a host hook
+    url: https://example.com/issues/2
+`))
+	require.NoError(t, err)
+	for _, name := range []string{"test/skip-features/include-listed.js", "test/skip-features/include-supported.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, t)
+		})
+	}
+	results := ctx.results.resultsCopy()
+	for _, strict := range []bool{false, true} {
+		listed := results[variantKey("test/skip-features/include-listed.js", strict)]
+		require.Equal(t, CategorySkippedFeature, listed.Category)
+		require.Equal(t, "Unsupported include unsupportedHelper.js: needs a host hook (https://example.com/issues/2)", listed.Message)
+		require.Equal(t, CategoryPass, results[variantKey("test/skip-features/include-supported.js", strict)].Category)
+	}
+	require.Equal(t, []string{
+		"1 tests skipped for including unsupportedHelper.js, see " + tc39SkipFeaturesFile,
+	}, ctx.skipFeatures.summary())
+}
+
+func TestEsidPrefixes(t *testing.T) {
+	prefixes := []string{"sec-array", "sec-arraybuffer-length", "sec-%typedarray%"}
+	for esid, expected := range map[string]string{
+		"sec-array":                        "sec-array",
+		"sec-array.prototype.map":          "sec-array",
+		"sec-arraybuffer":                  "",
+		"sec-arraybuffer.prototype.slice":  "",
+		"sec-arraybuffer-length":           "sec-arraybuffer-length",
+		"sec-arraybuffer-length.foo":       "sec-arraybuffer-length",
+		"sec-%typedarray%.prototype.every": "sec-%typedarray%",
+		"sec-arrays":                       "",
+		"sec":                              "",
+		"":                                 "",
+	} {
+		prefix, ok := esidPrefix(esid, prefixes)
+		require.Equal(t, expected, prefix, esid)
+		require.Equal(t, expected != "", ok, esid)
+	}
+	// the longest prefix admits it
+	prefix, _ := esidPrefix("sec-array.prototype.map", []string{"sec-array", "sec-array.prototype"})
+	require.Equal(t, "sec-array.prototype", prefix)
+
+	ctx := newFixtureCtx(t)
+	for _, name := range []string{"test/skip-features/esid.js", "test/skip-features/esid-array.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, t)
+		})
+	}
+	results := ctx.results.resultsCopy()
+	require.Equal(t, CategoryPass, results[variantKey("test/skip-features/esid.js", false)].Category)
+	require.Equal(t, CategorySkippedEsid, results[variantKey("test/skip-features/esid-array.js", false)].Category)
+	require.Equal(t, []string{
+		"1 tests without an es5id or es6id admitted by the esid prefix sec-arraybuffer, see " + tc39SkipFeaturesFile,
+	}, ctx.skipFeatures.summary())
+}
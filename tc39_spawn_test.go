@@ -0,0 +1,135 @@
+package test262
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39DefaultMaxBackground is how many background tasks of the tests can run at once.
+const tc39DefaultMaxBackground = 16
+
+// tc39Spawner runs the background work of the tests on a bounded number of goroutines, so the
+// features wanting a goroutine per test don't multiply with the workers into thousands of them.
+// Once the budget is used up a task runs inline on the goroutine of its test instead, which only
+// slows that test down. The agents of $262.agent are the exception and always get a goroutine of
+// their own: inline, an agent waiting for a broadcast would wait for the very goroutine running it.
+// A test only starts a few and they end with it.
+type tc39Spawner struct {
+	budget int
+	slots  chan struct{}
+	wg     sync.WaitGroup
+
+	mu                    sync.Mutex
+	running, peak, inline int
+}
+
+func newSpawner(budget int) *tc39Spawner {
+	return &tc39Spawner{budget: budget, slots: make(chan struct{}, budget)}
+}
+
+func (s *tc39Spawner) spawn(fn func()) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.mu.Lock()
+		s.inline++
+		s.mu.Unlock()
+		fn()
+		return
+	}
+	s.mu.Lock()
+	if s.running++; s.running > s.peak {
+		s.peak = s.running
+	}
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			s.running--
+			s.mu.Unlock()
+			<-s.slots
+			s.wg.Done()
+		}()
+		fn()
+	}()
+}
+
+// wait returns once all the spawned tasks finished.
+func (s *tc39Spawner) wait() {
+	s.wg.Wait()
+}
+
+// summary warns about the tasks that ran inline, empty if none did.
+func (s *tc39Spawner) summary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inline == 0 {
+		return ""
+	}
+	return fmt.Sprintf("WARNING: %d background tasks ran inline as %d were already running, "+
+		"raise TC39_MAX_BACKGROUND if the tests got slower", s.inline, s.budget)
+}
+
+// spawn runs fn in the background within the budget of the run. All the background work of the
+// tests but the agents has to go through it. Without a spawner, as in the tests of the runner, fn runs inline.
+func (ctx *tc39TestCtx) spawn(fn func()) {
+	if ctx.spawner == nil {
+		fn()
+		return
+	}
+	ctx.spawner.spawn(fn)
+}
+
+func maxBackgroundFromEnv() (int, error) {
+	v := os.Getenv("TC39_MAX_BACKGROUND")
+	if v == "" {
+		return tc39DefaultMaxBackground, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("TC39_MAX_BACKGROUND must be a positive number, got %q", v)
+	}
+	return n, nil
+}
+
+// TestBackgroundBudget runs many tests in parallel with all the hooks enabled and checks the
+// background tasks never went over the budget.
+func TestBackgroundBudget(t *testing.T) {
+	const budget, workers, tests = 2, 8, 4
+	ctx := newFixtureCtx(t)
+	ctx.spawner = newSpawner(budget)
+	ctx.enableBench = true
+	ctx.benchHook = newBenchHook()
+	host, err := newHostHook(1, ctx.base, ctx.spawn)
+	require.NoError(t, err)
+	random, err := randFromEnv()
+	require.NoError(t, err)
+	ctx.opts.Hooks = []TestHook{newIsolationHook(ctx), host, random,
+		&tc39ClockHook{instant: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}}
+
+	t.Run("tc39", func(t *testing.T) {
+		for i := 0; i < workers; i++ {
+			i := i
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				t.Parallel()
+				for j := 0; j < tests; j++ {
+					ctx.runTC39Test(t, fmt.Sprintf("test/%d-%d.js", i, j), `var x = Math.random() + Date.now();`,
+						&tc39Meta{}, j%2 == 0)
+				}
+			})
+		}
+	})
+	ctx.spawner.wait()
+
+	require.Empty(t, host.report())
+	require.Empty(t, ctx.results.errorsCopy())
+	require.True(t, ctx.spawner.peak <= budget, ctx.spawner.peak)
+	require.NotZero(t, ctx.spawner.peak)
+}
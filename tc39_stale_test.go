@@ -0,0 +1,120 @@
+package test262
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Stale has the expected errors nothing needs anymore: the ones of variants that ran and
+// passed, and the ones of tests that don't exist anymore. Only the expected errors of what the run
+// covers are looked at, the shard, TC39_FILTER or the directory selection already left out the
+// others, and a test is gone only once its file is, so a partial run finds no false ones.
+type tc39Stale struct {
+	passed, gone []string
+}
+
+// newStale compares the expected errors with the results of the run.
+func newStale(ctx *tc39TestCtx, errs map[string]string, results map[string]TestResult) *tc39Stale {
+	s := &tc39Stale{}
+	exists := make(map[string]bool)
+	for nameKey := range errs {
+		k, err := parseLegacyKey(nameKey, nil)
+		if err != nil {
+			continue
+		}
+		if result, ok := results[nameKey]; ok && result.Category == CategoryPass {
+			s.passed = append(s.passed, nameKey)
+			continue
+		}
+		found, seen := exists[k.Test]
+		if !seen {
+			found = ctx.testExists(k.Test)
+			exists[k.Test] = found
+		}
+		if !found {
+			s.gone = append(s.gone, nameKey)
+		}
+	}
+	sort.Strings(s.passed)
+	sort.Strings(s.gone)
+	return s
+}
+
+// testExists tells if the file of the test is still there.
+func (ctx *tc39TestCtx) testExists(test string) bool {
+	suite, rel := ctx.suite(test)
+	_, err := os.Stat(osPath(suite.root, rel))
+	return !os.IsNotExist(err)
+}
+
+func (s *tc39Stale) empty() bool {
+	return len(s.passed) == 0 && len(s.gone) == 0
+}
+
+// summary has a line with both counts and one for each of the first max stale expected errors.
+func (s *tc39Stale) summary(max int) []string {
+	if s.empty() {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("%d expected errors are stale, %d of variants that passed and %d of tests that don't exist anymore",
+		len(s.passed)+len(s.gone), len(s.passed), len(s.gone))}
+	for _, key := range s.passed {
+		lines = append(lines, "expected to fail but passed: "+key)
+	}
+	for _, key := range s.gone {
+		lines = append(lines, "expected error of a test that doesn't exist: "+key)
+	}
+	if len(lines) > max+1 {
+		lines = append(lines[:max+1], fmt.Sprintf("and %d more", len(lines)-max-1))
+	}
+	return lines
+}
+
+func TestStaleExpected(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.expectedErrors = map[string]string{
+		"test/compat/es5.js-strict:false": "test/compat/es5.js: Test262Error: fixed",
+		"test/dump/fail.js-strict:true":   "test/dump/fail.js: Test262Error: still failing",
+		// not run, which doesn't make it stale
+		"test/compat/es6.js-strict:false": "test/compat/es6.js: Test262Error: not run",
+		"test/removed.js-strict:false":    "test/removed.js: gone",
+		"test/removed.js-strict:true":     "test/removed.js: gone",
+	}
+	for _, name := range []string{"test/compat/es5.js", "test/dump/fail.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, &tc39CountingTB{TB: t})
+		})
+	}
+	s := newStale(ctx, ctx.expectedErrors, ctx.results.resultsCopy())
+	require.Equal(t, []string{"test/compat/es5.js-strict:false"}, s.passed)
+	require.Equal(t, []string{"test/removed.js-strict:false", "test/removed.js-strict:true"}, s.gone)
+	require.Equal(t, []string{
+		"3 expected errors are stale, 1 of variants that passed and 2 of tests that don't exist anymore",
+		"expected to fail but passed: test/compat/es5.js-strict:false",
+		"expected error of a test that doesn't exist: test/removed.js-strict:false",
+		"expected error of a test that doesn't exist: test/removed.js-strict:true",
+	}, s.summary(3))
+	require.Equal(t, []string{
+		"3 expected errors are stale, 1 of variants that passed and 2 of tests that don't exist anymore",
+		"expected to fail but passed: test/compat/es5.js-strict:false",
+		"and 2 more",
+	}, s.summary(1))
+
+	// a shard only finds the stale expected errors of its own tests, the shards together all of them once
+	var sharded []string
+	for index := 1; index <= 2; index++ {
+		shard := &tc39Shard{index: index, count: 2}
+		s := newStale(ctx, shard.expected(ctx.expectedErrors), ctx.results.resultsCopy())
+		sharded = append(append(sharded, s.passed...), s.gone...)
+	}
+	sort.Strings(sharded)
+	require.Equal(t, []string{
+		"test/compat/es5.js-strict:false", "test/removed.js-strict:false", "test/removed.js-strict:true",
+	}, sharded)
+	require.Empty(t, newStale(ctx, map[string]string{}, ctx.results.resultsCopy()).summary(3))
+}
@@ -0,0 +1,28 @@
+package test262
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStrictLines runs both variants of a test throwing at line 7 of its file, the strict variant
+// being compiled in strict mode and its source left as it is.
+func TestStrictLines(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	tb := &tc39CountingTB{TB: t}
+	ctx.runTC39File("test/strict-lines.js", "test/strict-lines.js", tb)
+	for _, strict := range []bool{false, true} {
+		result := ctx.results.resultsCopy()[variantKey("test/strict-lines.js", strict)]
+		require.Contains(t, result.Message, fmt.Sprintf("Error: strict: %t at test/strict-lines.js:7:7", strict))
+	}
+	require.Equal(t, 2, tb.errors)
+
+	// the strict mode early errors are still raised without the directive, at their lines
+	_, _, err := ctx.compileTest("test/octal.js", "\nvar a = 010;", true, lib.CompatibilityModeExtended, nil)
+	require.EqualError(t, err, "SyntaxError: Octal literals are not allowed in strict mode at 2:9")
+	_, _, err = ctx.compileTest("test/octal.js", "\nvar a = 010;", false, lib.CompatibilityModeExtended, nil)
+	require.NoError(t, err)
+}
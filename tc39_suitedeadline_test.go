@@ -0,0 +1,139 @@
+package test262
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39DeadlineMargin is how long the results of a run take to be written at most, on top of the
+// tests that were running at the deadline finishing.
+const tc39DeadlineMargin = time.Minute
+
+// tc39SuiteDeadline stops a run before the timeout of go test kills it, which would lose all of its
+// results. After the deadline no more tests start, the remaining ones are only recorded as not run,
+// so the run still ends with its results and its errors.
+type tc39SuiteDeadline struct {
+	at time.Time
+
+	mu sync.Mutex
+	// cutoff is the test first not run and when
+	cutoff     string
+	cutoffTime time.Time
+	notRun     int
+}
+
+// newSuiteDeadline returns the deadline of the suite, margin before the one of go test, or nil if
+// the tests have none.
+func newSuiteDeadline(t interface{ Deadline() (time.Time, bool) }, margin time.Duration) *tc39SuiteDeadline {
+	deadline, ok := t.Deadline()
+	if !ok {
+		return nil
+	}
+	return &tc39SuiteDeadline{at: deadline.Add(-margin)}
+}
+
+// passed tells if the tests starting now aren't run anymore, it never does without a deadline.
+func (d *tc39SuiteDeadline) passed() bool {
+	return d != nil && !time.Now().Before(d.at)
+}
+
+// skip counts the test as not run and returns why it wasn't.
+func (d *tc39SuiteDeadline) skip(name string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.notRun == 0 {
+		d.cutoff, d.cutoffTime = slashPath(name), time.Now()
+	}
+	d.notRun++
+	return fmt.Sprintf("Not run: the suite deadline %s passed", d.at.Format(time.RFC3339))
+}
+
+// cut tells if the deadline left tests not run.
+func (d *tc39SuiteDeadline) cut() bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.notRun > 0
+}
+
+// summary returns the line telling that the run is partial, if it is.
+func (d *tc39SuiteDeadline) summary() string {
+	if !d.cut() {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return fmt.Sprintf("PARTIAL RUN: the suite deadline %s cut it off at %s, at %s, %d tests not run",
+		d.at.Format(time.RFC3339), d.cutoffTime.Format(time.RFC3339), d.cutoff, d.notRun)
+}
+
+// recordNotRun records the test as not run without reading it, so with both of the variants a
+// test could have in every mode.
+func (ctx *tc39TestCtx) recordNotRun(name string) {
+	message := ctx.suiteDeadline.skip(name)
+	for _, mode := range compatModes {
+		for _, strict := range []bool{false, true} {
+			ctx.results.recordResult(modeKey(name, strict, mode), TestResult{Category: CategorySkippedDeadline, Message: message})
+		}
+	}
+}
+
+type tc39StaticDeadline struct {
+	deadline time.Time
+	ok       bool
+}
+
+func (d tc39StaticDeadline) Deadline() (time.Time, bool) {
+	return d.deadline, d.ok
+}
+
+func TestSuiteDeadline(t *testing.T) {
+	require.Nil(t, newSuiteDeadline(tc39StaticDeadline{}, time.Minute))
+	var none *tc39SuiteDeadline
+	require.False(t, none.passed())
+	require.Equal(t, "", none.summary())
+
+	now := time.Now()
+	d := newSuiteDeadline(tc39StaticDeadline{deadline: now.Add(time.Hour), ok: true}, time.Minute)
+	require.Equal(t, now.Add(59*time.Minute), d.at)
+	require.False(t, d.passed())
+	require.Equal(t, "", d.summary())
+
+	ctx := newFixtureCtx(t)
+	ctx.suiteDeadline = newSuiteDeadline(tc39StaticDeadline{deadline: now, ok: true}, time.Minute)
+	require.True(t, ctx.suiteDeadline.passed())
+	t.Run("tc39", func(t *testing.T) {
+		ctx.t = t
+		ctx.runSuiteTests(ctx.mainSuite(), "test/module")
+		ctx.flush()
+	})
+	results := ctx.results.resultsCopy()
+	message := "Not run: the suite deadline " + ctx.suiteDeadline.at.Format(time.RFC3339) + " passed"
+	for _, name := range []string{"test/module/import.js", "test/module/missing.js", "test/module/resolution.js"} {
+		for _, strict := range []bool{false, true} {
+			require.Equal(t, TestResult{Category: CategorySkippedDeadline, Message: message}, results[variantKey(name, strict)])
+		}
+	}
+	require.True(t, ctx.suiteDeadline.cut())
+	require.Regexp(t, `^PARTIAL RUN: the suite deadline \S+ cut it off at \S+, at test/module/import.js, 3 tests not run$`,
+		ctx.suiteDeadline.summary())
+
+	// a test queued before the deadline doesn't run once it passed either
+	t.Run("test/module/import.js", func(t *testing.T) {
+		defer func() {
+			require.True(t, t.Skipped())
+			result := ctx.results.resultsCopy()["test/module/import.js-strict:true"]
+			require.Equal(t, CategorySkippedDeadline, result.Category)
+			require.NotContains(t, ctx.results.resultsCopy(), "test/module/import.js-strict:false")
+		}()
+		ctx.results = newTC39Results()
+		ctx.runTC39File("test/module/import.js", "test/module/import.js", t)
+	})
+	require.Contains(t, ctx.suiteDeadline.summary(), "4 tests not run")
+}
@@ -0,0 +1,102 @@
+package test262
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Suite is a tree of tests in the test262 format. The test262 checkout is the suite without
+// a name, the tests of the other suites are named with the suite's name as the first directory,
+// so they can't collide with the test262 ones in the baselines, reports and -run filters.
+type tc39Suite struct {
+	name string
+	root string
+	// harness is the directory whose harness/ the tests use, the suite's own root if it has one
+	// and the test262 checkout otherwise.
+	harness string
+}
+
+func (s tc39Suite) key(rel string) string {
+	return path.Join(s.name, rel)
+}
+
+func (ctx *tc39TestCtx) mainSuite() tc39Suite {
+	return tc39Suite{root: ctx.base, harness: ctx.base}
+}
+
+// suite returns the suite the test with the given name belongs to and the name relative to the
+// suite's root.
+func (ctx *tc39TestCtx) suite(name string) (tc39Suite, string) {
+	for _, s := range ctx.extraSuites {
+		if strings.HasPrefix(name, s.name+"/") {
+			return s, name[len(s.name)+1:]
+		}
+	}
+	return ctx.mainSuite(), name
+}
+
+// extraSuites parses the comma separated list of directories in TC39_EXTRA_SUITES.
+func extraSuites(base, list string) ([]tc39Suite, error) {
+	names := map[string]bool{"test": true, "harness": true}
+	var suites []tc39Suite
+	for _, dir := range strings.Split(list, ",") {
+		if dir = strings.TrimSpace(dir); dir == "" {
+			continue
+		}
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return nil, fmt.Errorf("TC39_EXTRA_SUITES: %w", err)
+		}
+		s := tc39Suite{name: filepath.Base(root), root: root, harness: base}
+		if names[s.name] {
+			return nil, fmt.Errorf("TC39_EXTRA_SUITES: %s would name its tests %s/..., which is already taken", dir, s.name)
+		}
+		names[s.name] = true
+		if fi, err := os.Stat(filepath.Join(root, "harness")); err == nil && fi.IsDir() {
+			s.harness = root
+		}
+		suites = append(suites, s)
+	}
+	return suites, nil
+}
+
+func TestExtraSuites(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	suites, err := extraSuites(ctx.base, "testdata/suites/k6tests, testdata/suites/own")
+	require.NoError(t, err)
+	require.Len(t, suites, 2)
+	require.Equal(t, ctx.base, suites[0].harness)
+	require.Equal(t, suites[1].root, suites[1].harness)
+	ctx.extraSuites = suites
+
+	for _, s := range suites {
+		d, err := discoverTests(s.root, "")
+		require.NoError(t, err)
+		require.Len(t, d.names, 1)
+		name := s.key(d.names[0])
+		require.True(t, strings.HasPrefix(name, s.name+"/"), name)
+		found, rel := ctx.suite(name)
+		require.Equal(t, s, found)
+		require.Equal(t, d.names[0], rel)
+
+		var ran bool
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, d.path(rel), t)
+			ran = true
+		})
+		require.True(t, ran, "%s was skipped", name)
+	}
+	require.Empty(t, ctx.results.errorsCopy())
+
+	_, err = extraSuites(ctx.base, "testdata/suites/own,testdata/suites/own")
+	require.Error(t, err)
+	main, rel := ctx.suite("test/built-ins/Math/abs.js")
+	require.Equal(t, ctx.mainSuite(), main)
+	require.Equal(t, "test/built-ins/Math/abs.js", rel)
+}
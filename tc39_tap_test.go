@@ -0,0 +1,152 @@
+package test262
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// tc39TAPGolden is the TAP of the results in TestTAP, rewritten with TC39_UPDATE_REPORT_GOLDEN=1
+// like the other reports.
+const tc39TAPGolden = "testdata/tap.golden"
+
+//nolint:gochecknoglobals
+var tapEscaper = strings.NewReplacer(`\`, `\\`, "#", `\#`, "\r\n", " ", "\n", " ", "\r", " ")
+
+// tc39TAPDiagnostic is the YAML block of a failure.
+type tc39TAPDiagnostic struct {
+	Type     string `yaml:"type"`
+	Message  string `yaml:"message"`
+	Expected string `yaml:"expected,omitempty"`
+}
+
+// tapName is the description of a variant, its test and strictness, and its mode if it isn't the
+// default one. A '#' or a backslash in it is escaped, as a '#' starts a directive.
+func tapName(k tc39ResultKey) string {
+	strictness := "sloppy"
+	if k.Strict {
+		strictness = "strict"
+	}
+	if k.Mode != tc39DefaultMode {
+		strictness += ", " + k.Mode
+	}
+	return tapEscaper.Replace(k.Test) + " (" + strictness + ")"
+}
+
+// writeTAP writes the results as TAP version 13, sorted by variant, so it's the same whatever
+// order the workers finished the tests in. A skip is ok with a SKIP directive, an expected failure
+// not ok with a TODO one, which doesn't fail the run in TAP either, and every failure has a YAML
+// block with its category and error.
+func writeTAP(w io.Writer, results map[string]TestResult) error {
+	keys := make([]string, 0, len(results))
+	for nameKey := range results {
+		keys = append(keys, nameKey)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	b.WriteString("TAP version 13\n")
+	for i, nameKey := range keys {
+		result := results[nameKey]
+		k, err := parseLegacyKey(nameKey, nil)
+		if err != nil {
+			return err
+		}
+		switch status := reportStatus(result.Category); status {
+		case "pass":
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, tapName(k))
+			continue
+		case "skip":
+			fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", i+1, tapName(k), tapEscaper.Replace(result.Message))
+			continue
+		}
+		if result.Category == CategoryExpectedFailure {
+			fmt.Fprintf(&b, "not ok %d - %s # TODO expected failure\n", i+1, tapName(k))
+		} else {
+			fmt.Fprintf(&b, "not ok %d - %s\n", i+1, tapName(k))
+		}
+		diagnostic, err := yaml.Marshal(tc39TAPDiagnostic{
+			Type: result.Category.String(), Message: result.Message, Expected: result.Expected,
+		})
+		if err != nil {
+			return err
+		}
+		b.WriteString("  ---\n")
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(string(diagnostic), "\n"), "\n") {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n  ...\n")
+	}
+	fmt.Fprintf(&b, "1..%d\n", len(keys))
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeTAPFile writes the TAP of the results to file, "-" being stdout, for TC39_TAP.
+func writeTAPFile(file string, results map[string]TestResult) error {
+	if file == "-" {
+		return writeTAP(os.Stdout, results)
+	}
+	return writeArtifact(file, func(w io.Writer) error {
+		return writeTAP(w, results)
+	})
+}
+
+func TestTAP(t *testing.T) {
+	results := map[string]TestResult{
+		"test/language/comments/hash#bang.js-strict:false": {Category: CategoryPass},
+		"test/built-ins/a.js-strict:true":                  {Category: CategoryPass},
+		"test/built-ins/a.js-strict:false": {
+			Category: CategoryNewFailure, Message: "test/built-ins/a.js: Test262Error: Expected SameValue(«1», «2») to be true",
+		},
+		"test/built-ins/b.js-strict:false": {
+			Category: CategoryChangedFailure, Message: "test/built-ins/b.js: SyntaxError: Unexpected token (1:2)\n> 1 | a b\n    |   ^",
+			Expected: "test/built-ins/b.js: SyntaxError: other",
+		},
+		"test/built-ins/c.js-strict:true":  {Category: CategoryExpectedFailure, Message: "test/built-ins/c.js: TypeError: x # not a directive"},
+		"test/built-ins/d.js-strict:false": {Category: CategorySkippedFeature, Message: "Blacklisted feature BigInt"},
+		"test/built-ins/e.js-strict:true-mode:base": {
+			Category: CategoryTimeout, Message: "test/built-ins/e.js: the test didn't finish in TC39_TEST_TIMEOUT (1s)",
+		},
+		"own/f.js-strict:true": {Category: CategoryPanic, Message: "panic: own/f.js: runtime error: index out of range [3]\nwith length 3"},
+	}
+	var out bytes.Buffer
+	require.NoError(t, writeTAP(&out, results))
+	if os.Getenv("TC39_UPDATE_REPORT_GOLDEN") != "" {
+		require.NoError(t, ioutil.WriteFile(tc39TAPGolden, out.Bytes(), 0o644))
+	}
+	golden, err := ioutil.ReadFile(tc39TAPGolden)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), out.String())
+
+	// the diagnostics are YAML with the messages as they were
+	var diagnostics []tc39TAPDiagnostic
+	for _, block := range strings.Split(out.String(), "  ---\n")[1:] {
+		var d tc39TAPDiagnostic
+		require.NoError(t, yaml.UnmarshalStrict([]byte(block[:strings.Index(block, "  ...\n")]), &d))
+		diagnostics = append(diagnostics, d)
+	}
+	require.Len(t, diagnostics, 5)
+	require.Equal(t, tc39TAPDiagnostic{
+		Type: "panic", Message: "panic: own/f.js: runtime error: index out of range [3]\nwith length 3",
+	}, diagnostics[0])
+	require.Equal(t, tc39TAPDiagnostic{
+		Type: "changed-failure", Message: results["test/built-ins/b.js-strict:false"].Message,
+		Expected: "test/built-ins/b.js: SyntaxError: other",
+	}, diagnostics[2])
+
+	// the same whichever way the results came in
+	for i := 0; i < 5; i++ {
+		var again bytes.Buffer
+		require.NoError(t, writeTAP(&again, results))
+		require.Equal(t, out.String(), again.String())
+	}
+	require.Equal(t, `test/a\#b\\c.js (strict, base)`, tapName(tc39ResultKey{Test: `test/a#b\c.js`, Strict: true, Mode: "base"}))
+}
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
 	"sort"
 	"strings"
 	"sync"
@@ -33,31 +36,10 @@ var (
 
 	// ignorableTestError = newSymbol(stringEmpty)
 
-	sabStub = goja.MustCompile("sabStub.js", `
-		Object.defineProperty(this, "SharedArrayBuffer", {
-			get: function() {
-				throw IgnorableTestError;
-			}
-		});`,
-		false)
-
-	esIdPrefixWhiteList = []string{
-		/*
-			"sec-array",
-		*/
-		"sec-%typedarray%",
-		"sec-string",
-		"sec-date",
-		"sec-number",
-		"sec-math",
-		"sec-arraybuffer-length",
-		"sec-arraybuffer",
-		"sec-regexp",
-	}
-
-	featuresBlackList = []string{
-		"BigInt",    // not supported at all
-		"IsHTMLDDA", // not supported at all
+	// hostFeatures need something of the host goja has no equivalent of, their tests are skipped
+	// whatever their esid, in the extra suites too.
+	hostFeatures = map[string]string{
+		"IsHTMLDDA": "$262.IsHTMLDDA, an object with the [[IsHTMLDDA]] slot of document.all",
 	}
 	skipList = map[string]bool{
 		"test/built-ins/Promise/all/does-not-invoke-array-setters.js": true, // timezone
@@ -69,33 +51,133 @@ type tc39Test struct {
 	f    func(t *testing.T)
 }
 
-type tc39BenchmarkItem struct {
-	name     string
-	duration time.Duration
-}
-
-type tc39BenchmarkData []tc39BenchmarkItem
-
+// tc39TestCtx is shared by all the tests of a run. The fields before t are set
+// up before any test starts and are only read afterwards, so they need no locking.
 type tc39TestCtx struct {
 	compiler       *compiler.Compiler
 	base           string
-	t              *testing.T
-	prgCache       map[string]*goja.Program
-	prgCacheLock   sync.Mutex
 	enableBench    bool
-	benchmark      tc39BenchmarkData
-	benchLock      sync.Mutex
-	testQueue      []tc39Test
 	expectedErrors map[string]string
+	// expectedEntries has the entries of the expected errors, with what's known about them
+	expectedEntries map[string]tc39ExpectedEntry
+	// expectedMaxAge is how old an expected error is listed from, for TC39_EXPECTED_MAX_AGE
+	expectedMaxAge time.Duration
+	// baselineLegacy and baselineDir are where the expected errors are, tc39Baseline and
+	// tc39BaselineDir unless TC39_EXPECTED_ERRORS, and baselineWarning says if there are none
+	baselineLegacy, baselineDir string
+	baselineWarning             string
+
+	benchIterations int
+	benchPhases     bool
+	benchOut        string
+	benchDirDepth   int
+	benchOnly       bool
+	memSampler      *tc39MemSampler
+	dryRun          bool
+
+	intlStub           bool
+	intlExpectedErrors map[string]string
+	// expectedPatterns are the compiled regexps of the expected errors of both, by pattern
+	expectedPatterns map[string]*regexp.Regexp
+
+	extraSuites []tc39Suite
+
+	opts      Options
+	benchHook *tc39BenchHook
+
+	// dirConfigs are the settings of directories.yml, by directory
+	dirConfigs map[string]tc39DirConfig
+	deadlines  *tc39Deadlines  // locks itself
+	quarantine *tc39Quarantine // locks itself
+	// skipFeatures has the features of skip_features.yaml, it locks itself
+	skipFeatures *tc39SkipFeatures
+	// negativeMessages has what the error messages of some negative tests have to be, by test
+	negativeMessages map[string]tc39NegativeMessage
+	artifacts        *tc39Artifacts // locks itself
+	spawner          *tc39Spawner   // locks itself
+	firstNew         *tc39FirstNew  // locks itself, nil unless the run stops at the first new failure
+	// suiteDeadline locks itself, nil if go test has no timeout
+	suiteDeadline *tc39SuiteDeadline
+	// shard is only touched by the goroutine walking the test tree, nil without TC39_SHARD
+	shard *tc39Shard
+	// filter is only touched by the goroutine walking the test tree, nil without TC39_FILTER
+	filter *tc39Filter
+	// dirs is only touched by the goroutine walking the test tree, nil runs all the directories
+	dirs *tc39DirSelection
+	// onlyFailing has the tests of TC39_ONLY_FAILING, run instead of the tree, nil without it
+	onlyFailing *tc39OnlyFailing
+	// metaFilter has the terms of TC39_META_FILTER, it locks itself
+	metaFilter *tc39MetaFilter
+	// babelCache locks itself, nil without TC39_CACHE_DIR
+	babelCache *tc39BabelCache
+	// dump locks itself, nil without TC39_DUMP_DIR
+	dump *tc39Dump
+	// nativeCompare runs the failures again on plain goja, for TC39_NATIVE_COMPARE
+	nativeCompare bool
+	// metaManifest locks itself, nil if the frontmatter of test262 is parsed every time
+	metaManifest *tc39MetaManifest
+	env          tc39Environment
 
-	errorsLock sync.Mutex
-	errors     map[string]string
+	updateExpected bool
+	// strictExpected fails the run for stale expected errors, for TC39_STRICT_EXPECTED
+	strictExpected bool
+	tzPassOut      string
+	// reportOut is where TC39_REPORT writes the report of the run, junitOut where TC39_JUNIT
+	// writes its JUnit XML and tapOut where TC39_TAP writes its TAP, "-" for stdout
+	reportOut, junitOut, tapOut string
+
+	// t and testQueue are only touched by the goroutine walking the test tree and the workers
+	// it runs the queued tests on, never from inside a test.
+	t         *testing.T
+	testQueue []tc39Test
+
+	sources *tc39SourceCache // locks itself
+
+	// harnessPrgs has the harness files compiled by init, keyed like prgCache. It doesn't change
+	// afterwards, so it's read without locking.
+	harnessPrgs map[string]*goja.Program
+	harnessInit tc39HarnessInit
+	// prgCacheLock is only taken exclusively to add to the caches, the hits share it
+	prgCacheLock sync.RWMutex
+	prgCache     map[string]tc39CachedProgram
+	testPrgCache map[tc39TestPrgKey]tc39CompiledTest
+
+	results *tc39Results
 }
 
 type TC39MetaNegative struct {
 	Phase, Type string
 }
 
+// tc39NegativePhases has every phase a negative test can expect its error at, with whether the
+// runner sees it while compiling. "parse" is what newer test262 revisions call "early". The
+// imports are compiled to calls of require, so the "resolution" errors of module tests come when
+// they run, from the modules they import failing to load.
+//nolint:gochecknoglobals
+var tc39NegativePhases = map[string]bool{
+	"parse":      true,
+	"early":      true,
+	"resolution": false,
+	"runtime":    false,
+}
+
+// UnmarshalYAML also accepts the `negative: SyntaxError` shorthand of older test262 snapshots,
+// which predates phases and always meant an early error.
+func (n *TC39MetaNegative) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var typ string
+	if err := unmarshal(&typ); err == nil {
+		*n = TC39MetaNegative{Phase: "early", Type: typ}
+		return nil
+	}
+	type plain TC39MetaNegative
+	var p plain
+	if err := unmarshal(&p); err != nil {
+		return err
+	}
+	*n = TC39MetaNegative(p)
+	return nil
+}
+
 type tc39Meta struct {
 	Negative TC39MetaNegative
 	Includes []string
@@ -104,6 +186,15 @@ type tc39Meta struct {
 	Es5id    string
 	Es6id    string
 	Esid     string
+
+	Description string
+	Info        string
+	Author      string
+	// Locale lists the locales the test needs, only intl402 tests have it.
+	Locale []string
+	// Output is what the test has to print, only in the extra suites, the test262 ones compare
+	// it with a sidecar file if at all.
+	Output *string `yaml:"output,omitempty"`
 }
 
 func (m *tc39Meta) hasFlag(flag string) bool {
@@ -115,19 +206,56 @@ func (m *tc39Meta) hasFlag(flag string) bool {
 	return false
 }
 
-func parseTC39File(name string) (*tc39Meta, string, error) {
-	f, err := os.Open(name) //nolint:gosec
-	if err != nil {
-		return nil, "", err
+func (m *tc39Meta) hasFeature(feature string) bool {
+	for _, f := range m.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *tc39Meta) hasInclude(include string) bool {
+	for _, i := range m.Includes {
+		if i == include {
+			return true
+		}
 	}
-	defer f.Close() //nolint:errcheck,gosec
+	return false
+}
 
-	b, err := ioutil.ReadAll(f)
+// tc39KnownFlags are the flags of the frontmatter the runner knows about, whether it does
+// anything about them or not. A flag not in here is a typo or new upstream and fails the test, as
+// ignoring it could make the test run in a way it was never meant to.
+//nolint:gochecknoglobals
+var tc39KnownFlags = map[string]bool{
+	"onlyStrict":        true,
+	"noStrict":          true,
+	"module":            true,
+	"raw":               true,
+	"async":             true,
+	"generated":         true,
+	"CanBlockIsFalse":   true,
+	"CanBlockIsTrue":    true,
+	"non-deterministic": true,
+}
+
+type unknownFlagError struct {
+	flag string
+}
+
+func (e *unknownFlagError) Error() string {
+	return fmt.Sprintf("unknown flag %q", e.flag)
+}
+
+// parseTC39File reads the test in file name, checking that the harness/ directory of harness has
+// all the files it includes.
+func parseTC39File(name, harness string) (*tc39Meta, string, error) {
+	str, err := readTC39Source(name)
 	if err != nil {
 		return nil, "", err
 	}
 
-	str := string(b)
 	metaStart := strings.Index(str, "/*---")
 	if metaStart == -1 {
 		return nil, "", invalidFormatError
@@ -140,7 +268,8 @@ func parseTC39File(name string) (*tc39Meta, string, error) {
 	}
 
 	var meta tc39Meta
-	err = yaml.Unmarshal([]byte(str[metaStart:metaEnd]), &meta)
+	// CRLF line endings are part of the source, only the YAML can't have them
+	err = yaml.Unmarshal([]byte(strings.ReplaceAll(str[metaStart:metaEnd], "\r\n", "\n")), &meta)
 	if err != nil {
 		return nil, "", err
 	}
@@ -148,89 +277,352 @@ func parseTC39File(name string) (*tc39Meta, string, error) {
 	if meta.Negative.Type != "" && meta.Negative.Phase == "" {
 		return nil, "", errors.New("negative type is set, but phase isn't")
 	}
+	if _, ok := tc39NegativePhases[meta.Negative.Phase]; meta.Negative.Phase != "" && !ok {
+		return nil, "", fmt.Errorf("unknown negative phase %q", meta.Negative.Phase)
+	}
+	for _, flag := range meta.Flags {
+		if !tc39KnownFlags[flag] {
+			return nil, "", &unknownFlagError{flag: flag}
+		}
+	}
+	for _, include := range meta.Includes {
+		file := osPath(harness, path.Join("harness", include))
+		if _, err := os.Stat(file); err != nil {
+			return nil, "", fmt.Errorf("the included %s can't be used: %w", slashPath(file), err)
+		}
+	}
 
 	return &meta, str, nil
 }
 
-func (*tc39TestCtx) detachArrayBuffer(call goja.FunctionCall) goja.Value {
+// readTC39Source reads the source of a test, without the BOM, which isn't part of it.
+func readTC39Source(name string) (string, error) {
+	b, err := readFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(string(b), "\ufeff"), nil
+}
+
+// createRealm sets up a new runtime like the one of a test, the harness included, and returns its
+// $262. goja has no realms, the objects of two runtimes only mix as far as goja doesn't check
+// where they're from, so the tests needing more than reading properties and calling functions
+// of the other realm fail.
+func (ctx *tc39TestCtx) createRealm(name string, out *tc39Output) goja.Value {
+	ctx.results.recordRealm(name)
+	vm, _, _ := ctx.newRuntime(name, out)
+	s, _ := ctx.suite(name)
+	for _, include := range []string{"assert.js", "sta.js"} {
+		if err := ctx.runFile(s.harness, path.Join("harness", include), vm, &tc39Timings{}); err != nil {
+			panic(fmt.Errorf("loading %s in a new realm: %w", include, err))
+		}
+	}
+	return vm.Get("$262")
+}
+
+// compileScript compiles src like the tests are, to be run in the realm of vm from one of its
+// native functions. What the compiler rejects is thrown as a SyntaxError of the realm, the
+// compiler's errors are of its own runtime.
+func (ctx *tc39TestCtx) compileScript(vm *goja.Runtime, name, src string) *goja.Program {
+	prg, _, err := ctx.compileJS(src, name, "", "", false)
+	if err != nil {
+		throwError(vm, "SyntaxError", errorMessage(err))
+	}
+	return prg
+}
+
+// throwError throws a new error of the realm of vm with the named constructor from a native
+// function.
+func throwError(vm *goja.Runtime, constructor, message string) {
+	c, _ := vm.Get(constructor).(*goja.Object)
+	exc, err := vm.New(c, vm.ToValue(message))
+	if err != nil {
+		panic(err)
+	}
+	panic(exc)
+}
+
+// evalScript runs src as another script in the realm of vm. What it throws is thrown to the
+// caller.
+func (ctx *tc39TestCtx) evalScript(vm *goja.Runtime, name, src string) goja.Value {
+	v, err := vm.RunProgram(ctx.compileScript(vm, name+" (evalScript)", src))
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// detachArrayBuffer is DetachArrayBuffer(buffer[, key]) for vm, throwing its TypeErrors. The
+// buffers of the tests have an undefined [[ArrayBufferDetachKey]], so any other key throws.
+func (*tc39TestCtx) detachArrayBuffer(vm *goja.Runtime, call goja.FunctionCall) goja.Value {
+	if n := len(call.Arguments); n < 1 || n > 2 {
+		panic(vm.NewTypeError("detachArrayBuffer() takes the buffer and optionally its key, got %d arguments", n))
+	}
+	if !goja.IsUndefined(call.Argument(1)) {
+		panic(vm.NewTypeError("detachArrayBuffer() is called with a key, the buffer has none"))
+	}
 	if obj, ok := call.Argument(0).(*goja.Object); ok {
-		var buf goja.ArrayBuffer
-		if goja.New().ExportTo(obj, &buf) == nil {
-			// if buf, ok := obj.Export().(goja.ArrayBuffer); ok {
+		// ExportTo succeeds for any object, leaving buf without a buffer to detach
+		if buf, ok := obj.Export().(goja.ArrayBuffer); ok {
 			buf.Detach()
 			return goja.Undefined()
 		}
 	}
-	panic(goja.New().NewTypeError("detachArrayBuffer() is called with incompatible argument"))
+	panic(vm.NewTypeError("detachArrayBuffer() is called with incompatible argument"))
 }
 
-func (ctx *tc39TestCtx) fail(t testing.TB, name string, strict bool, errStr string) {
-	nameKey := fmt.Sprintf("%s-strict:%v", name, strict)
-	expected, ok := ctx.expectedErrors[nameKey]
-	if ok {
-		if !assert.Equal(t, expected, errStr) {
-			ctx.errorsLock.Lock()
-			fmt.Println("different")
-			fmt.Println(expected)
-			fmt.Println(errStr)
-			ctx.errors[nameKey] = errStr
-			ctx.errorsLock.Unlock()
-		}
-	} else {
-		assert.Empty(t, errStr)
-		ctx.errorsLock.Lock()
-		fmt.Println("no error", name)
-		ctx.errors[nameKey] = errStr
-		ctx.errorsLock.Unlock()
+// withDescription appends what the test checks to its error, so the baseline tells it without
+// opening the test.
+func withDescription(errStr, description string) string {
+	if description = strings.Join(strings.Fields(description), " "); description != "" {
+		return errStr + " (" + description + ")"
 	}
+	return errStr
 }
 
-func (ctx *tc39TestCtx) runTC39Test(t testing.TB, name, src string, meta *tc39Meta, strict bool) {
-	if skipList[name] {
-		t.Skip("Excluded")
+// expectedError returns the expected error of the variant, from the baseline of the intl402
+// smoke tests for one of them.
+func (ctx *tc39TestCtx) expectedError(name, nameKey string) (string, bool) {
+	expectedErrors := ctx.expectedErrors
+	if ctx.intlSmoke(name) {
+		expectedErrors = ctx.intlExpectedErrors
 	}
-	failf := func(str string, args ...interface{}) {
-		str = fmt.Sprintf(str, args)
-		ctx.fail(t, name, strict, str)
+	expected, ok := expectedErrors[nameKey]
+	return expected, ok
+}
+
+// fail checks the error of a failed test variant, with the description of the test appended,
+// against the expected ones and returns how it compares to them.
+func (ctx *tc39TestCtx) fail(
+	t testing.TB, name string, strict bool, mode lib.CompatibilityMode, errStr, description string,
+) ResultCategory {
+	nameKey := modeKey(name, strict, mode)
+	// a baseline from before the descriptions has the error by itself
+	legacy := errStr
+	errStr = withDescription(errStr, description)
+	expected, ok := ctx.expectedError(name, nameKey)
+	category := CategoryNewFailure
+	// both are normalized, so a moved line or another checkout doesn't change how they compare
+	roots := ctx.errorRoots()
+	switch {
+	case ok && ctx.matchesExpected(expected, roots, errStr, legacy):
+		category = CategoryExpectedFailure
+	case ok:
+		category = CategoryChangedFailure
 	}
-	defer func() {
-		if x := recover(); x != nil {
-			failf("panic while running %s: %v", name, x)
+
+	if ctx.benchOnly {
+		ctx.results.countBenchOnlyFailure()
+		return category
+	}
+	if ctx.updateExpected && mode == lib.CompatibilityModeExtended {
+		ctx.results.recordFailure(slashPath(name), errStr)
+	}
+	if ctx.tzPassOut != "" {
+		// the expected errors only hold for one timezone, the matrix compares the passes instead
+		ctx.results.recordVariant(nameKey, errStr)
+		return category
+	}
+	switch category {
+	case CategoryExpectedFailure:
+	case CategoryChangedFailure:
+		if pattern, isPattern := expectedPattern(expected); isPattern {
+			assert.Regexp(t, pattern, normalizeError(errStr, roots))
+		} else {
+			assert.Equal(t, expected, errStr)
 		}
-	}()
+		fmt.Fprintln(ctx.out(), "different")
+		fmt.Fprintln(ctx.out(), expected)
+		fmt.Fprintln(ctx.out(), errStr)
+	default:
+		assert.Empty(t, errStr)
+		fmt.Fprintln(ctx.out(), "no error", name)
+	}
+	if category != CategoryExpectedFailure {
+		t.Logf("%s runs as the subtest %s, only rerun it with: %s", slashPath(name), subtestName(name), rerunCommand(name))
+	}
+	return category
+}
+
+// newRuntime sets up the runtime for a test, up to the harness files, returning it with its
+// IgnorableTestError and job queue. It panics if any of the preambles fails.
+func (ctx *tc39TestCtx) newRuntime(name string, out *tc39Output) (*goja.Runtime, *goja.Object, *tc39Jobs) {
 	vm := goja.New()
 	_262 := vm.NewObject()
 	ignorableTestError := vm.NewGoError(fmt.Errorf(""))
 	vm.Set("IgnorableTestError", ignorableTestError)
-	_ = _262.Set("detachArrayBuffer", ctx.detachArrayBuffer)
+	_ = _262.Set("detachArrayBuffer", func(call goja.FunctionCall) goja.Value {
+		return ctx.detachArrayBuffer(vm, call)
+	})
+	_ = _262.Set("evalScript", func(call goja.FunctionCall) goja.Value {
+		return ctx.evalScript(vm, name, call.Argument(0).String())
+	})
 	_ = _262.Set("createRealm", func(goja.FunctionCall) goja.Value {
-		panic(ignorableTestError)
+		return ctx.createRealm(name, out)
 	})
+	_ = _262.Set("global", vm.GlobalObject())
 	vm.Set("$262", _262)
-	vm.Set("print", t.Log)
+	vm.Set("print", out.print)
+	// core-js keeps the Dispatch it found, the tests don't get to see it
+	jobs := &tc39Jobs{}
+	dispatch := vm.NewObject()
+	_ = dispatch.Set("now", jobs.now)
+	vm.Set("Dispatch", dispatch)
 	if _, err := vm.RunProgram(jslib.GetCoreJS()); err != nil {
 		panic(err)
 	}
-	_, err := vm.RunProgram(sabStub)
-	if err != nil {
-		panic(err)
+	_ = vm.GlobalObject().Delete("Dispatch")
+	jobs.trackRejections(vm)
+	if ctx.intlSmoke(name) {
+		if _, err := vm.RunProgram(intlStub); err != nil {
+			panic(err)
+		}
+	}
+	return vm, ignorableTestError, jobs
+}
+
+// runTC39Test runs a variant of a test in the extended mode and records its result.
+func (ctx *tc39TestCtx) runTC39Test(t testing.TB, name, src string, meta *tc39Meta, strict bool) {
+	ctx.runTC39TestIn(t, name, src, meta, strict, lib.CompatibilityModeExtended)
+}
+
+// runTC39TestIn runs a variant of a test in the given compatibility mode and records its result.
+// The hooks' After methods only get it once it's classified.
+func (ctx *tc39TestCtx) runTC39TestIn(t testing.TB, name, src string, meta *tc39Meta, strict bool, mode lib.CompatibilityMode) {
+	result := &TestResult{Meta: meta}
+	start := time.Now()
+	var after func(*TestResult)
+	defer func() {
+		result.Duration = time.Since(start)
+		if ctx.compareNative(meta, mode, result.Category) {
+			result.Native = ctx.runNative(name, src, meta, strict, result.Err)
+		}
+		if after != nil {
+			after(result)
+		}
+		ctx.results.recordResult(modeKey(name, strict, mode), *result)
+		if ctx.firstNew != nil && result.Category.unexpected() {
+			ctx.firstNew.add(tc39NewFailure{
+				name: name, strict: strict, mode: mode, category: result.Category, message: result.Message, meta: meta, src: src,
+			})
+		}
+	}()
+	skip := func(category ResultCategory, reason string) {
+		result.Category, result.Message = category, reason
+		t.Skip(reason)
+	}
+	infraErrorf := func(err error) {
+		result.Category, result.Message = CategoryInfrastructure, err.Error()
+		t.Errorf("infrastructure error: %v", err)
+	}
+	if skipList[name] {
+		skip(CategorySkippedExcluded, "Excluded")
 	}
-	if strict {
-		src = "'use strict';\n" + src
+	var vm *goja.Runtime
+	// code is what the test compiled to
+	var code string
+	failWith := func(str string) {
+		if exc := (*goja.Exception)(nil); errors.As(result.Err, &exc) && vm != nil {
+			result.Details = errorDetails(vm, exc)
+		}
+		result.Category = ctx.fail(t, name, strict, mode, str, meta.Description)
+		result.Message = withDescription(str, meta.Description)
+		// kept whatever the category ends up as, a panic of a variant expected to fail differently
+		// still changed its error rather than newly failing
+		if expected, ok := ctx.expectedError(name, modeKey(name, strict, mode)); ok && result.Category != CategoryExpectedFailure {
+			result.Expected = expected
+		}
+		if result.Category.unexpected() {
+			ctx.dump.write(name, strict, mode, src, code, result.Message)
+		}
+		if result.Details != "" && result.Category.unexpected() && !ctx.benchOnly {
+			fmt.Fprintln(ctx.out(), result.Details)
+		}
 	}
-	early, err := ctx.runTC39Script(name, src, meta.Includes, vm)
+	// the messages of failf format args as one slice, the baseline depends on them as they are
+	failf := func(str string, args ...interface{}) {
+		str = fmt.Sprintf(str, args)
+		failWith(str)
+	}
+	defer func() {
+		if x := recover(); x != nil {
+			stack := string(debug.Stack())
+			failWith(fmt.Sprintf("panic: %s: %v", name, x))
+			if result.Category != CategoryExpectedFailure {
+				result.Category = CategoryPanic
+			}
+			// not printed with the failure, the panics have their own section of the report
+			result.Details = stack
+			ctx.results.recordPanic(modeKey(name, strict, mode), result.Message, stack)
+		}
+	}()
+	out := &tc39Output{}
+	defer out.flush(t)
+	vm, ignorableTestError, jobs := ctx.newRuntime(name, out)
+	sab := stubSharedArrayBuffer(vm, ignorableTestError, meta)
+	skipIgnorable := func(reason string) {
+		if sab.skipped() {
+			ctx.results.recordSABSkip(name)
+		}
+		skip(CategorySkippedIgnorable, reason)
+	}
+	blocking, _ := canBlock(meta)
+	agents := newAgents(ctx, name, blocking, out)
+	agents.install(vm)
+	defer agents.stop()
+	tc := &TestInfo{Name: name, Strict: strict, Mode: mode, Meta: meta, HostWork: newHostWork(vm)}
+	after, err := ctx.runHooks(tc, vm)
+	if err != nil {
+		result.Err, result.Early = err, true
+		infraErrorf(err)
+		return
+	}
+	// stopping the agents as well unblocks a main thread waiting for them
+	stopTimer := interruptAfter(vm, testTimeout, testTimeoutError{testTimeout}, func() { agents.stop() })
+	result.Early, code, result.Err = ctx.runTC39Script(name, src, meta, strict, mode, vm, jobs, &result.Timings)
+	stopTimer()
+	early, err := result.Early, result.Err
 
+	var tooLarge *fileTooLargeError
+	if errors.As(err, &tooLarge) {
+		infraErrorf(err)
+		return
+	}
+	var harness *harnessError
+	if errors.As(err, &harness) {
+		// the test didn't even start, so it's neither of the phases of a negative test
+		if exc, ok := harness.err.(*goja.Exception); ok && exc.Value() == ignorableTestError {
+			skipIgnorable("Harness threw IgnorableTestError")
+		}
+		ctx.results.recordHarnessFailure(harness)
+		failWith(fmt.Sprintf("%s: %v", name, harness))
+		if result.Category != CategoryExpectedFailure {
+			result.Category = CategoryHarness
+		}
+		return
+	}
+	if timeout := timeoutError(err); timeout != nil {
+		failWith(fmt.Sprintf("%s: %v", name, timeout))
+		if result.Category != CategoryExpectedFailure {
+			result.Category = CategoryTimeout
+		}
+		return
+	}
+	var resolution *moduleResolutionError
+	if errors.As(err, &resolution) {
+		err = resolution.err
+	}
 	if err != nil {
 		if meta.Negative.Type == "" {
 			if err, ok := err.(*goja.Exception); ok {
 				if err.Value() == ignorableTestError {
-					t.Skip("Test threw IgnorableTestError")
+					skipIgnorable("Test threw IgnorableTestError")
 				}
 			}
 			failf("%s: %v", name, err)
 			return
 		} else {
-			if meta.Negative.Phase == "early" && !early || meta.Negative.Phase == "runtime" && early {
+			if tc39NegativePhases[meta.Negative.Phase] != early || (meta.Negative.Phase == "resolution") != (resolution != nil) {
 				failf("%s: error %v happened at the wrong phase (expected %s)", name, err, meta.Negative.Phase)
 				return
 			}
@@ -264,6 +656,13 @@ func (ctx *tc39TestCtx) runTC39Test(t testing.TB, name, src string, meta *tc39Me
 			}
 
 			_ = errType
+			if expected, ok := ctx.negativeMessages[name]; ok {
+				if message := errorMessage(err); errType != meta.Negative.Type || !expected.matches(message) {
+					failWith(fmt.Sprintf("%s: unexpected error %s: %q, expected %s %s",
+						name, errType, message, meta.Negative.Type, expected))
+					return
+				}
+			}
 			if errType != meta.Negative.Type {
 				// vm.vm.prg.dumpCode(t.Logf)
 				failf("%s: unexpected error type (%s), expected (%s)", name, errType, meta.Negative.Type)
@@ -276,6 +675,33 @@ func (ctx *tc39TestCtx) runTC39Test(t testing.TB, name, src string, meta *tc39Me
 			failf("%s: Expected error: %v", name, err)
 			return
 		}
+		if meta.hasFlag("async") {
+			if failure := asyncFailure(out.captured()); failure != "" {
+				failWith(fmt.Sprintf("%s: %s", name, failure))
+				return
+			}
+		}
+		if unhandled := jobs.unhandledRejections(); len(unhandled) > 0 && !unhandledRejectionTests[name] {
+			failWith(fmt.Sprintf("%s: unhandled promise rejections: %s", name, strings.Join(unhandled, ", ")))
+			return
+		}
+		if meta.Output != nil {
+			if diff := outputDiff(*meta.Output, out.captured()); diff != "" {
+				failWith(fmt.Sprintf("%s: unexpected output:\n%s", name, diff))
+				return
+			}
+		}
+	}
+
+	if errs := agents.stop(); len(errs) > 0 {
+		failWith(fmt.Sprintf("%s: %s", name, strings.Join(errs, ", ")))
+		return
+	}
+	if pending := tc.HostWork.unexpectedPending(); len(pending) > 0 {
+		failWith(fmt.Sprintf("%s: pending host work: %s", name, strings.Join(pending, ", ")))
+		if result.Category != CategoryExpectedFailure {
+			result.Category = CategoryPendingHostWork
+		}
 	}
 
 	/*
@@ -289,190 +715,369 @@ func (ctx *tc39TestCtx) runTC39Test(t testing.TB, name, src string, meta *tc39Me
 	*/
 }
 
-func (ctx *tc39TestCtx) runTC39File(name string, t testing.TB) {
-	p := path.Join(ctx.base, name)
-	meta, src, err := parseTC39File(p)
+// testVariants returns which of the sloppy (false) and strict (true) variants of a test run.
+func testVariants(meta *tc39Meta) []bool {
+	if meta.hasFlag("module") {
+		// module code is always strict
+		return []bool{true}
+	}
+	hasRaw := meta.hasFlag("raw")
+	var variants []bool
+	if hasRaw || !meta.hasFlag("onlyStrict") {
+		variants = append(variants, false)
+	}
+	if !hasRaw && !meta.hasFlag("noStrict") {
+		variants = append(variants, true)
+	}
+	return variants
+}
+
+// runTC39File runs the test with the given name, read from file, the two only being different if
+// the name had to be normalized.
+func (ctx *tc39TestCtx) runTC39File(name, file string, t testing.TB) {
+	if ctx.firstNew.stopped() {
+		t.SkipNow()
+	}
+	s, _ := ctx.suite(name)
+	meta, src, err := ctx.readMeta(s, file)
 	if err != nil {
+		var tooLarge *fileTooLargeError
+		if errors.As(err, &tooLarge) {
+			t.Errorf("infrastructure error: %v", err)
+			return
+		}
 		// t.Fatalf("Could not parse %s: %v", name, err)
 		t.Errorf("Could not parse %s: %v", name, err)
 		return
 	}
-	// if meta.Es6id == "" && meta.Es5id == "" {
-	if meta.Es6id == "" && meta.Es5id == "" {
-		skip := true
-		/*
-			// t.Logf("%s: Not ES5, skipped", name)
-			if es6WhiteList[name] {
-				skip = false
-			} else {
-				if meta.Es6id != "" {
-					for _, prefix := range es6IdWhiteList {
-						if strings.HasPrefix(meta.Es6id, prefix) &&
-							(len(meta.Es6id) == len(prefix) || meta.Es6id[len(prefix)] == '.') {
-
-							skip = false
-							break
-						}
-					}
-				}
+	if s.name == "" {
+		meta.Output = nil
+	}
+	expected, err := expectedOutput(osPath(s.root, file))
+	if err != nil {
+		t.Errorf("infrastructure error: %v", err)
+		return
+	}
+	if expected != nil {
+		meta.Output = expected
+	}
+	if !ctx.metaFilter.take(meta) {
+		// like the tests TC39_FILTER leaves out, it's not part of the run
+		t.SkipNow()
+	}
+	skipReason := "skipped while running"
+	if meta.Esid != "" {
+		defer func() {
+			if !t.Skipped() {
+				skipReason = ""
 			}
-		*/
-
-		if skip {
-			if meta.Esid != "" {
-				for _, prefix := range esIdPrefixWhiteList {
-					if strings.HasPrefix(meta.Esid, prefix) &&
-						(len(meta.Esid) == len(prefix) || meta.Esid[len(prefix)] == '.') {
-						skip = false
-					}
-				}
+			ctx.results.recordCoverage(meta.Esid, skipReason)
+		}()
+	}
+	skipf := func(category ResultCategory, format string, args ...interface{}) {
+		skipReason = fmt.Sprintf(format, args...)
+		for _, mode := range compatModes {
+			for _, strict := range testVariants(meta) {
+				ctx.results.recordResult(modeKey(name, strict, mode), TestResult{
+					Category: category, Message: skipReason, Meta: meta,
+				})
 			}
 		}
-		for _, feature := range meta.Features {
-			for _, bl := range featuresBlackList {
-				if feature == bl {
-					t.Skipf("Blacklisted feature %s", feature)
-				}
-			}
+		t.Skip(skipReason)
+	}
+	if ctx.suiteDeadline.passed() {
+		skipf(CategorySkippedDeadline, "%s", ctx.suiteDeadline.skip(name))
+	}
+	if e, ok := ctx.quarantine.check(name); ok {
+		skipf(CategorySkippedQuarantined, "Quarantined until %s: %s", e.Expires, e.Reason)
+	}
+	done, err := ctx.deadlines.start(name)
+	if err != nil {
+		skipf(CategorySkippedDeadline, "%v", err)
+	}
+	defer done()
+	for _, feature := range meta.Features {
+		if needs, ok := hostFeatures[feature]; ok {
+			ctx.results.recordHostFeature(feature)
+			skipf(CategorySkippedFeature, "Host feature %s needs %s", feature, needs)
 		}
-		if skip {
-			t.Skipf("Not ES6 or ES5 esid: %s", meta.Esid)
+	}
+	if feature, e, ok := ctx.skipFeatures.check(meta.Features); ok {
+		skipf(CategorySkippedFeature, "Skipped feature %s: %s", feature, e)
+	}
+	if include, e, ok := ctx.skipFeatures.checkIncludes(meta.Includes); ok {
+		skipf(CategorySkippedFeature, "Unsupported include %s: %s", include, e)
+	}
+	// the extra suites are ours, so all of their tests are expected to work
+	if s.name == "" && meta.Es6id == "" && meta.Es5id == "" && !ctx.intlSmoke(name) {
+		if _, ok := ctx.skipFeatures.admit(meta.Esid); !ok {
+			skipf(CategorySkippedEsid, "Not ES6 or ES5 esid: %s", meta.Esid)
 		}
 	}
-
-	var startTime time.Time
-	if ctx.enableBench {
-		startTime = time.Now()
+	if _, err := canBlock(meta); err != nil {
+		skipf(CategorySkippedFeature, "Unsupported blocking mode: %v", err)
 	}
 
-	hasRaw := meta.hasFlag("raw")
-
-	if hasRaw || !meta.hasFlag("onlyStrict") {
-		// log.Printf("Running normal test: %s", name)
-		// t.Logf("Running normal test: %s", name)
-		ctx.runTC39Test(t, name, src, meta, false)
+	if src == "" {
+		// the frontmatter came from the manifest, the source is only needed now the test runs
+		if src, err = readTC39Source(osPath(s.root, file)); err != nil {
+			t.Errorf("infrastructure error: %v", err)
+			return
+		}
 	}
-
-	if !hasRaw && !meta.hasFlag("noStrict") {
-		// log.Printf("Running strict test: %s", name)
-		// t.Logf("Running strict test: %s", name)
-		ctx.runTC39Test(t, name, src, meta, true)
+	item := tc39BenchmarkItem{name: name, features: meta.Features}
+	for _, mode := range compatModes {
+		if reason := babelOnly(meta, mode); reason != "" {
+			// only this mode is skipped, the others still run
+			for _, strict := range testVariants(meta) {
+				ctx.results.recordResult(modeKey(name, strict, mode), TestResult{
+					Category: CategorySkippedFeature, Message: reason, Meta: meta,
+				})
+			}
+			continue
+		}
+		for _, strict := range testVariants(meta) {
+			if ctx.enableBench {
+				item.variants = append(item.variants, ctx.benchTC39Test(t, name, src, meta, strict))
+			} else {
+				ctx.runTC39TestIn(t, name, src, meta, strict, mode)
+			}
+			if ctx.tzPassOut != "" {
+				// a variant that failed was already recorded with its error
+				ctx.results.recordVariant(modeKey(name, strict, mode), "")
+			}
+		}
 	}
 
+	if ctx.updateExpected {
+		ctx.results.recordTest(name, meta.Features)
+	}
 	if ctx.enableBench {
-		ctx.benchLock.Lock()
-		ctx.benchmark = append(ctx.benchmark, tc39BenchmarkItem{
-			name:     name,
-			duration: time.Since(startTime),
-		})
-		ctx.benchLock.Unlock()
+		ctx.forgetTestPrograms(name)
+		ctx.results.addBenchmark(item)
 	}
 }
 
-func (ctx *tc39TestCtx) init() {
-	ctx.prgCache = make(map[string]*goja.Program)
-	ctx.errors = make(map[string]string)
+func (ctx *tc39TestCtx) init() error {
+	ctx.sources = newSourceCache()
+	ctx.prgCache = make(map[string]tc39CachedProgram)
+	ctx.testPrgCache = make(map[tc39TestPrgKey]tc39CompiledTest)
+	ctx.results = newTC39Results()
 
-	b, err := ioutil.ReadFile("./breaking_test_errors.json")
+	if ctx.baselineLegacy == "" && ctx.baselineDir == "" {
+		ctx.baselineLegacy, ctx.baselineDir = tc39Baseline, tc39BaselineDir
+	}
+	files, err := baselineFiles(ctx.baselineLegacy, ctx.baselineDir)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if len(files) == 0 {
+		ctx.baselineWarning = missingBaseline(ctx.baselineLegacy, ctx.baselineDir)
+	}
+	if ctx.expectedEntries, err = readBaseline(files); err != nil {
+		return err
 	}
-	ctx.expectedErrors = make(map[string]string, 1000)
-	err = json.Unmarshal(b, &ctx.expectedErrors)
+	ctx.expectedErrors = errorsOf(ctx.expectedEntries)
+	if ctx.intlStub {
+		if err = ctx.loadIntlBaseline(); err != nil {
+			return err
+		}
+	}
+	ctx.expectedPatterns, err = compileExpectedPatterns(map[string]map[string]string{
+		ctx.baselinePath(): ctx.expectedErrors, tc39IntlBaseline: ctx.intlExpectedErrors,
+	})
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if ctx.dirConfigs, err = loadDirConfigs(tc39DirConfigFile); err != nil {
+		return err
 	}
+	ctx.deadlines = newDeadlines(ctx.dirConfigs)
+	if ctx.quarantine, err = loadQuarantine(tc39QuarantineFile, time.Now()); err != nil {
+		return err
+	}
+	if ctx.negativeMessages, err = loadNegativeMessages(tc39NegativeMessagesFile); err != nil {
+		return err
+	}
+	if ctx.skipFeatures, err = loadSkipFeatures(tc39SkipFeaturesFile); err != nil {
+		return err
+	}
+	ctx.artifacts = newArtifacts(tc39ArtifactsDir)
+	ctx.precompileHarness()
+	return nil
 }
 
-func (ctx *tc39TestCtx) compile(base, name string) (*goja.Program, error) {
-	ctx.prgCacheLock.Lock()
-	defer ctx.prgCacheLock.Unlock()
-
-	prg := ctx.prgCache[name]
-	if prg == nil {
-		fname := path.Join(base, name)
-		f, err := os.Open(fname) //nolint:gosec
-		if err != nil {
-			return nil, err
-		}
-		defer f.Close() //nolint:gosec,errcheck
+// tc39CachedProgram is a compiled harness file, or the error it didn't compile with.
+type tc39CachedProgram struct {
+	prg *goja.Program
+	err error
+}
 
-		b, err := ioutil.ReadAll(f)
-		if err != nil {
-			return nil, err
-		}
+// compile returns the program of the harness file, the one init compiled if there is one. The files
+// added to the harness since are compiled once they're needed, and a file that didn't compile
+// fails with the same error for every test including it.
+func (ctx *tc39TestCtx) compile(base, name string) (prg *goja.Program, cached bool, err error) {
+	name = slashPath(name)
+	// keyed by the file, the harness of an extra suite can have the same names as the main one
+	file := osPath(base, name)
+	if prg = ctx.harnessPrgs[file]; prg != nil {
+		return prg, true, nil
+	}
 
-		str := string(b)
-		prg, _, err = ctx.compiler.Compile(str, name, "", "", false, lib.CompatibilityModeExtended)
-		if err != nil {
-			return nil, err
+	ctx.prgCacheLock.RLock()
+	c, ok := ctx.prgCache[file]
+	ctx.prgCacheLock.RUnlock()
+	if !ok {
+		ctx.prgCacheLock.Lock()
+		defer ctx.prgCacheLock.Unlock()
+		// another test may have compiled it meanwhile
+		c, ok = ctx.prgCache[file]
+	}
+	if ok {
+		if c.err != nil {
+			ctx.results.countCachedFailure()
 		}
-		ctx.prgCache[name] = prg
+		return c.prg, true, c.err
+	}
+	b, err := ctx.sources.read(base, name)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s isn't one of the %d harness files precompiled at init, and it can't be read: %w",
+			name, ctx.harnessInit.files, err)
 	}
 
-	return prg, nil
+	str := string(b)
+	prg, _, err = ctx.compileJS(str, name, "", "", false)
+	ctx.prgCache[file] = tc39CachedProgram{prg: prg, err: err}
+	if err != nil {
+		return nil, false, err
+	}
+	return prg, false, nil
 }
 
-func (ctx *tc39TestCtx) runFile(base, name string, vm *goja.Runtime) error {
-	prg, err := ctx.compile(base, name)
+func (ctx *tc39TestCtx) runFile(base, name string, vm *goja.Runtime, timings *tc39Timings) error {
+	prg, cached, err := ctx.compile(base, name)
 	if err != nil {
 		return err
 	}
+	timings.harnessCache.record(cached)
 	_, err = vm.RunProgram(prg)
 	return err
 }
 
-func (ctx *tc39TestCtx) runTC39Script(name, src string, includes []string, vm *goja.Runtime) (early bool, err error) {
-	early = true
-	err = ctx.runFile(ctx.base, path.Join("harness", "assert.js"), vm)
-	if err != nil {
-		return
+// harnessFiles are the files of the harness the test runs after, in order.
+func harnessFiles(meta *tc39Meta) []string {
+	harness := meta.Includes
+	if !meta.hasFlag("raw") {
+		harness = append([]string{"assert.js", "sta.js"}, harness...)
 	}
-
-	err = ctx.runFile(ctx.base, path.Join("harness", "sta.js"), vm)
-	if err != nil {
-		return
+	if meta.hasFlag("async") && !meta.hasInclude("doneprintHandle.js") {
+		harness = append(harness, "doneprintHandle.js")
 	}
+	return harness
+}
 
-	for _, include := range includes {
-		err = ctx.runFile(ctx.base, path.Join("harness", include), vm)
-		if err != nil {
+// runTC39Script runs the harness and then the test, a raw test only gets the files it includes, as
+// it has to run in a pristine global. It returns the code the test compiled to, empty if it didn't
+// get that far.
+func (ctx *tc39TestCtx) runTC39Script(
+	name, src string, meta *tc39Meta, strict bool, mode lib.CompatibilityMode, vm *goja.Runtime, jobs *tc39Jobs,
+	timings *tc39Timings,
+) (early bool, code string, err error) {
+	early = true
+	s, _ := ctx.suite(name)
+	startTime := time.Now()
+	for _, include := range harnessFiles(meta) {
+		if err = ctx.runFile(s.harness, path.Join("harness", include), vm, timings); err != nil {
+			err = &harnessError{include: include, err: err}
 			return
 		}
 	}
 
+	timings.harness = time.Since(startTime)
+
 	var p *goja.Program
-	p, _, err = ctx.compiler.Compile(src, name, "", "", false, lib.CompatibilityModeExtended)
+	startTime = time.Now()
+	module := meta.hasFlag("module")
+	if module {
+		p, code, err = ctx.compileModule(name, src, &timings.transformCache)
+	} else {
+		p, code, err = ctx.compileTest(name, src, strict, mode, &timings.transformCache)
+	}
+	timings.compile = time.Since(startTime)
 
 	if err != nil {
 		return
 	}
 
 	early = false
-	_, err = vm.RunProgram(p)
+	startTime = time.Now()
+	err = runJobs(meta, vm, jobs, func() error {
+		if module {
+			return ctx.runModule(name, p, vm)
+		}
+		_, err := vm.RunProgram(p)
+		return err
+	})
+	timings.run = time.Since(startTime)
 
 	return
 }
 
 func (ctx *tc39TestCtx) runTC39Tests(name string) {
-	files, err := ioutil.ReadDir(path.Join(ctx.base, name))
+	ctx.runSuiteTests(ctx.mainSuite(), name)
+}
+
+// selected tells if the test of the suite is in the directories selected to run, the extra suites
+// and the intl402 tests of TC39_INTL_STUB always are.
+func (ctx *tc39TestCtx) selected(s tc39Suite, rel string) bool {
+	return s.name != "" || ctx.intlSmoke(s.key(rel)) || ctx.dirs.take(s.key(rel))
+}
+
+// runSuiteTests runs the tests below dir, relative to the suite's root.
+func (ctx *tc39TestCtx) runSuiteTests(s tc39Suite, dir string) {
+	var keep func(dir string) bool
+	if ctx.filter != nil {
+		keep = func(dir string) bool { return ctx.filter.mayMatchBelow(s.key(dir)) }
+	}
+	d, err := discoverTestsIn(s.root, dir, keep)
 	if err != nil {
 		ctx.t.Fatal(err)
 	}
-
-	for _, file := range files {
-		if file.Name()[0] == '.' {
-			continue
+	for _, warning := range d.warnings {
+		fmt.Fprintln(ctx.out(), "infrastructure warning:", warning)
+	}
+	for i, rel := range d.names {
+		if ctx.firstNew.stopped() {
+			break
 		}
-		if file.IsDir() {
-			ctx.runTC39Tests(path.Join(name, file.Name()))
-		} else {
-			if strings.HasSuffix(file.Name(), ".js") && !strings.HasSuffix(file.Name(), "_FIXTURE.js") {
-				name := path.Join(name, file.Name())
-				ctx.runTest(name, func(t *testing.T) {
-					ctx.runTC39File(name, t)
-				})
+		if !ctx.dryRun && ctx.suiteDeadline.passed() {
+			for _, rel := range d.names[i:] {
+				if ctx.selected(s, rel) && ctx.filter.take(s.key(rel)) && ctx.shard.take(s.key(rel)) {
+					ctx.recordNotRun(s.key(rel))
+				}
 			}
+			break
+		}
+		name, file := s.key(rel), d.path(rel)
+		if !ctx.selected(s, rel) || !ctx.filter.take(name) || !ctx.shard.take(name) {
+			continue
+		}
+		if ctx.dryRun {
+			fmt.Fprintln(ctx.out(), name)
+			continue
+		}
+		ctx.runTest(name, func(t *testing.T) {
+			ctx.runTC39File(name, file, t)
+		})
+	}
+	if ctx.dryRun {
+		reasons := make([]string, 0, len(d.ignored))
+		for reason := range d.ignored {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			fmt.Fprintf(ctx.out(), "ignored %s: %d\n", reason, d.ignored[reason])
 		}
 	}
 }
@@ -486,16 +1091,225 @@ func TestTC39(t *testing.T) {
 		t.Skipf("If you want to run tc39 tests, download them from https://github.com/tc39/test262 and put into %s. The last working commit is 1ba3a7c4a93fc93b3d0d7e4146f59934a896837d. (%v)", tc39BASE, err)
 	}
 
+	// resolved once so the walk can tell which directories it already visited through symlinks
+	base, err := filepath.EvalSymlinks(tc39BASE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = checkCheckout(base); err != nil {
+		t.Fatal(err)
+	}
+	out := newTestWriter(t)
+	defer out.flush()
 	ctx := &tc39TestCtx{
-		base:     tc39BASE,
+		base:     base,
 		compiler: compiler.New(testutils.NewLogger(t)),
+		intlStub: os.Getenv("TC39_INTL_STUB") != "",
+		opts:     Options{Out: out},
+	}
+	if ctx.extraSuites, err = extraSuites(base, os.Getenv("TC39_EXTRA_SUITES")); err != nil {
+		t.Fatal(err)
+	}
+	// the package variables with the settings, like maxFileSize, are all set in here before any test
+	// runs, and never change afterwards
+	if maxFileSize, err = maxFileSizeFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if maxDetails, err = maxDetailsFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if asyncTimeout, err = asyncTimeoutFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if testTimeout, err = testTimeoutFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if compatModes, err = compatModesFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	maxOpenFiles, err := maxOpenFilesFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	openFiles = newFileLimiter(maxOpenFiles)
+	maxBackground, err := maxBackgroundFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.spawner = newSpawner(maxBackground)
+	ctx.baselineLegacy, ctx.baselineDir = baselinePathsFromEnv()
+	if err = ctx.init(); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.baselineWarning != "" {
+		fmt.Fprintln(out, "WARNING:", ctx.baselineWarning)
+	}
+	if ctx.shard, err = shardFromEnv(); err != nil {
+		t.Fatal(err)
 	}
-	ctx.init()
-	// ctx.enableBench = true
+	ctx.expectedErrors, ctx.intlExpectedErrors = ctx.shard.expected(ctx.expectedErrors), ctx.shard.expected(ctx.intlExpectedErrors)
+	if ctx.filter, err = filterFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	ctx.expectedErrors, ctx.intlExpectedErrors = ctx.filter.expected(ctx.expectedErrors), ctx.filter.expected(ctx.intlExpectedErrors)
+	if ctx.metaFilter, err = metaFilterFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.dirs, err = dirSelectionFromEnv(ctx.dirConfigs); err != nil {
+		t.Fatal(err)
+	}
+	ctx.expectedErrors = ctx.dirs.expected(ctx.expectedErrors)
+	if ctx.babelCache, err = babelCacheFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.dump, err = dumpFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	manifest, note, err := loadMetaManifest(tc39MetaManifestFile, base, os.Getenv("TC39_REBUILD_MANIFEST") != "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.metaManifest = manifest; note != "" {
+		fmt.Fprintln(out, note)
+	}
+	artifacts, note, err := writableArtifacts(tc39ArtifactsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.artifacts = artifacts; note != "" {
+		fmt.Fprintln(out, note)
+	}
+	for _, warning := range ctx.quarantine.warnings {
+		fmt.Fprintln(out, "WARNING:", warning)
+	}
+	if err = ctx.preflight(t); err != nil {
+		t.Fatal(err)
+	}
+	// a dry run only lists the tests that would run
+	ctx.dryRun = os.Getenv("TC39_DRY_RUN") != ""
+	if err := ctx.initBench(); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.enableBench && (len(compatModes) != 1 || compatModes[0] != lib.CompatibilityModeExtended) {
+		t.Fatal("TC39_BENCH only measures the extended mode, so it can't be combined with TC39_COMPAT")
+	}
+	// the timings of a benchmark are only comparable with the tests run one by one
+	defaultParallelism := runtime.GOMAXPROCS(0)
+	if ctx.enableBench {
+		defaultParallelism = 1
+	}
+	if parallelism, err = parallelismFromEnv(defaultParallelism); err != nil {
+		t.Fatal(err)
+	}
+	clock, err := clockFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clock != nil {
+		ctx.opts.Hooks = append(ctx.opts.Hooks, clock)
+	}
+	random, err := randFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.opts.Hooks = append(ctx.opts.Hooks, random)
+	annotations, err := githubAnnotationsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var audit *tc39IsolationHook
+	if os.Getenv("TC39_ISOLATION_AUDIT") != "" {
+		audit = newIsolationHook(ctx)
+		ctx.opts.Hooks = append(ctx.opts.Hooks, audit)
+	}
+	hostAuditEvery, err := hostAuditFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hostAudit *tc39HostHook
+	if hostAuditEvery > 0 {
+		if hostAudit, err = newHostHook(hostAuditEvery, base, ctx.spawn); err != nil {
+			t.Fatal(err)
+		}
+		ctx.opts.Hooks = append(ctx.opts.Hooks, hostAudit)
+	}
+	// set by TestTC39TZMatrix for the pass it runs in a timezone
+	ctx.tzPassOut = os.Getenv("TC39_TZ_PASS_OUT")
+	ctx.nativeCompare = os.Getenv("TC39_NATIVE_COMPARE") != ""
+	// update mode regenerates the files derived from the results of a whole run
+	ctx.updateExpected = os.Getenv("TC39_UPDATE_EXPECTED") != ""
+	if err = ctx.checkUpdateExpected(); err != nil {
+		t.Fatal(err)
+	}
+	ctx.strictExpected = os.Getenv("TC39_STRICT_EXPECTED") != ""
+	ctx.reportOut, ctx.junitOut, ctx.tapOut = os.Getenv("TC39_REPORT"), os.Getenv("TC39_JUNIT"), os.Getenv("TC39_TAP")
+	if ctx.expectedMaxAge, err = expectedMaxAgeFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("TC39_STOP_ON_FIRST_NEW") != "" {
+		if ctx.updateExpected || ctx.benchOnly {
+			t.Fatal("TC39_STOP_ON_FIRST_NEW stops before all the tests ran, so it can't be combined with TC39_UPDATE_EXPECTED or TC39_BENCH_ONLY")
+		}
+		ctx.firstNew = &tc39FirstNew{}
+	}
+	if os.Getenv("TC39_ONLY_FAILING") != "" {
+		if ctx.updateExpected {
+			t.Fatal("TC39_ONLY_FAILING only runs the tests with an expected error, so it can't be combined with TC39_UPDATE_EXPECTED")
+		}
+		ctx.onlyFailing = newOnlyFailing(ctx, ctx.expectedErrors)
+	}
+	// a single test to debug, with everything about it printed instead of the reports
+	single, singleFile := path.Clean(slashPath(os.Getenv("TC39_TEST"))), ""
+	if single != "." {
+		if ctx.updateExpected || ctx.shard != nil || ctx.filter != nil || ctx.onlyFailing != nil {
+			t.Fatal("TC39_TEST runs a single test, so it can't be combined with TC39_UPDATE_EXPECTED, TC39_SHARD, TC39_FILTER or TC39_ONLY_FAILING")
+		}
+		if singleFile, err = ctx.singleTest(single); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ctx.checkBenchIterations(singleFile != ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = ctx.checkDestinations(); err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	// deferred, so a run that fails, even one that stops with t.Fatal, still has its reports
+	defer ctx.writeReports(t, started)
+	// after all the settings, as the fingerprint includes the number of workers
+	ctx.env = newEnvironment(os.Environ())
+	ctx.artifacts.env = ctx.env
+	if ctx.enableBench {
+		ctx.memSampler = startMemSampler(tc39MemSampleInterval)
+		defer ctx.memSampler.Stop()
+	}
+	traceOpts, err := traceOptionsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if traceOpts.threshold > 0 && !ctx.enableBench {
+		t.Fatal("TC39_TRACE_SLOW requires bench mode (TC39_BENCH=1) to find the slow tests")
+	}
+	var traces []string
+	// the tests running at the deadline may still take testTimeout
+	ctx.suiteDeadline = newSuiteDeadline(t, testTimeout+tc39DeadlineMargin)
 
 	t.Run("tc39", func(t *testing.T) {
 		ctx.t = t
-		ctx.runTC39Tests("test")
+		switch {
+		case singleFile != "":
+			ctx.runTest(single, func(t *testing.T) {
+				ctx.runTC39File(single, singleFile, t)
+			})
+		case ctx.onlyFailing != nil:
+			ctx.onlyFailing.run(ctx)
+		default:
+			ctx.runTC39Tests("test")
+			for _, s := range ctx.extraSuites {
+				ctx.runSuiteTests(s, "")
+			}
+		}
 		/*
 			// ctx.runTC39File("test/language/types/number/8.5.1.js", t)
 			// ctx.runTC39Tests("test/language")
@@ -524,22 +1338,263 @@ func TestTC39(t *testing.T) {
 
 		ctx.flush()
 	})
+	if traceOpts.threshold > 0 {
+		traces = ctx.traceSlowTests(t, traceOpts)
+	}
+	if err = ctx.metaManifest.write(tc39MetaManifestFile); err != nil {
+		// only a cache, the next run parses the tests again
+		fmt.Fprintln(ctx.out(), "WARNING:", err)
+	}
+
+	if singleFile != "" {
+		ctx.writeSingle(ctx.out(), single, singleFile)
+		return
+	}
+	if ctx.firstNew != nil {
+		// skipping all the reports, the failure is all that's wanted
+		ctx.writeFirstNew(ctx.out())
+		return
+	}
+	if traceOpts.threshold > 0 {
+		fmt.Fprintf(ctx.out(), "captured %d execution traces of tests slower than %s:\n", len(traces), traceOpts.threshold)
+		for _, file := range traces {
+			fmt.Fprintln(ctx.out(), file)
+		}
+	}
+
+	ctx.spawner.wait()
+	if audit != nil {
+		for _, polluter := range audit.report() {
+			t.Errorf("isolation audit: %s", polluter)
+		}
+	}
+	if hostAudit != nil {
+		for _, problem := range hostAudit.report() {
+			t.Errorf("host audit: %s", problem)
+		}
+	}
+	ctx.report(t, !ctx.subset(), clockSummary(clock), random.summary(), ctx.spawner.summary())
+	if annotations > 0 {
+		// straight to stdout, GitHub doesn't see the commands inside the lines of the test log
+		if err = ctx.writeAnnotations(os.Stdout, annotations); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// checkUpdateExpected refuses to update the expected errors from a run that didn't check all the
+// tests, it has to be called once the tests are selected.
+func (ctx *tc39TestCtx) checkUpdateExpected() error {
+	if ctx.updateExpected && (ctx.benchOnly || ctx.subset()) {
+		return errors.New("TC39_UPDATE_EXPECTED needs the results of all tests, so it can't be combined with TC39_BENCH_ONLY, -run, TC39_SHARD, TC39_FILTER or TC39_META_FILTER")
+	}
+	return nil
+}
 
+// report writes the summary of a run to the output and the artifacts once all its tests ran,
+// with the summaries of the hooks after the numbers of the run itself. The by-esid coverage
+// only means something for a fullRun of all tests.
+// writeReports writes the files of TC39_REPORT, TC39_JUNIT and TC39_TAP, and then the manifest
+// listing them.
+func (ctx *tc39TestCtx) writeReports(t testing.TB, started time.Time) {
+	if ctx.reportOut != "" {
+		if err := ctx.writeReport(ctx.reportOut, started); err != nil {
+			t.Errorf("TC39_REPORT: %v", err)
+		} else {
+			ctx.artifacts.add("report", ctx.reportOut)
+		}
+	}
+	if ctx.junitOut != "" {
+		if err := writeJUnit(ctx.junitOut, newJUnit(ctx.results.resultsCopy())); err != nil {
+			t.Errorf("TC39_JUNIT: %v", err)
+		} else {
+			ctx.artifacts.add("junit", ctx.junitOut)
+		}
+	}
+	if ctx.tapOut != "" {
+		if err := writeTAPFile(ctx.tapOut, ctx.results.resultsCopy()); err != nil {
+			t.Errorf("TC39_TAP: %v", err)
+		} else if ctx.tapOut != "-" {
+			ctx.artifacts.add("tap", ctx.tapOut)
+		}
+	}
+	ctx.writeManifest(t)
+}
+
+// writeManifest writes the manifest of the artifacts, last as it lists everything written before it.
+func (ctx *tc39TestCtx) writeManifest(t testing.TB) {
+	if ctx.dryRun {
+		return
+	}
+	if file, err := ctx.artifacts.flush(); err != nil {
+		t.Error(err)
+	} else {
+		fmt.Fprintf(ctx.out(), "run %s in environment %s, artifacts listed in %s\n", ctx.artifacts.runID, ctx.env.Hash, file)
+	}
+}
+
+func (ctx *tc39TestCtx) report(t testing.TB, fullRun bool, hookSummaries ...string) {
+	w := ctx.out()
+	if !ctx.dryRun {
+		fmt.Fprintf(w, "read %d harness files from disk\n", ctx.sources.diskReads())
+		fmt.Fprintln(w, ctx.harnessInit.summary())
+		for _, line := range ctx.results.harnessSummary() {
+			fmt.Fprintln(w, line)
+		}
+		if line := ctx.babelCache.summary(); line != "" {
+			fmt.Fprintln(w, line)
+		}
+		if line := ctx.dump.summary(); line != "" {
+			fmt.Fprintln(w, line)
+		}
+		if line := ctx.results.nativeSummary(); line != "" {
+			fmt.Fprintln(w, line)
+		}
+		if line := ctx.results.cachedFailureSummary(); line != "" {
+			fmt.Fprintln(w, line)
+		}
+		if line := ctx.results.realmSummary(); line != "" {
+			fmt.Fprintln(w, line)
+		}
+		if line := ctx.results.sabSummary(); line != "" {
+			fmt.Fprintln(w, line)
+		}
+		for _, line := range ctx.results.hostFeatureSummary() {
+			fmt.Fprintln(w, line)
+		}
+		for _, line := range ctx.results.compatSummary(compatModes) {
+			fmt.Fprintln(w, line)
+		}
+		ctx.results.writePanics(w)
+		for _, summary := range hookSummaries {
+			fmt.Fprintln(w, summary)
+		}
+	}
+	for _, line := range ctx.deadlines.summary() {
+		fmt.Fprintln(w, line)
+	}
+	if line := ctx.suiteDeadline.summary(); line != "" {
+		fmt.Fprintln(w, line)
+	}
+	for _, line := range ctx.dirs.summary() {
+		fmt.Fprintln(w, line)
+	}
+	if line := ctx.filter.summary(); line != "" {
+		fmt.Fprintln(w, line)
+	}
+	if line := ctx.metaFilter.summary(); line != "" {
+		fmt.Fprintln(w, line)
+	}
+	if line := ctx.shard.summary(); line != "" {
+		fmt.Fprintln(w, line)
+	}
+	if line := ctx.quarantine.summary(); line != "" {
+		fmt.Fprintln(w, line)
+	}
+	for _, line := range ctx.skipFeatures.summary() {
+		fmt.Fprintln(w, line)
+	}
+	for _, line := range ctx.onlyFailing.summary(ctx.expectedErrors, ctx.results.resultsCopy()) {
+		fmt.Fprintln(w, line)
+	}
+	if !ctx.benchOnly && !ctx.dryRun && ctx.tzPassOut == "" {
+		for _, line := range ctx.transitions(ctx.results.resultsCopy()).summary(50) {
+			fmt.Fprintln(w, line)
+		}
+	}
+	// TC39_ONLY_FAILING lists what can be removed itself, and a bench-only run doesn't check the results
+	if ctx.onlyFailing == nil && !ctx.benchOnly && !ctx.dryRun {
+		stale := newStale(ctx, ctx.expectedErrors, ctx.results.resultsCopy())
+		for _, line := range stale.summary(50) {
+			fmt.Fprintln(w, line)
+		}
+		if ctx.strictExpected && !stale.empty() {
+			t.Errorf("TC39_STRICT_EXPECTED: %d expected errors are stale", len(stale.passed)+len(stale.gone))
+		}
+	}
+	for _, line := range ctx.expectedSummary(time.Now(), ctx.expectedMaxAge, 50) {
+		fmt.Fprintln(w, line)
+	}
+	if !ctx.dryRun && fullRun {
+		gaps := coverageGaps(ctx.results.coverageByEsid())
+		if file, err := ctx.artifacts.path(tc39CoverageFile); err != nil {
+			t.Error(err)
+		} else if err = writeCoverageGaps(file, gaps); err != nil {
+			t.Error(err)
+		} else {
+			ctx.artifacts.add("esid-coverage", file)
+			fmt.Fprintf(w, "%d esids without a single executed test, see %s\n", len(gaps), file)
+		}
+	}
+	if ctx.updateExpected && ctx.suiteDeadline.cut() {
+		t.Error("TC39_UPDATE_EXPECTED: the suite deadline left tests not run, the baseline wasn't updated")
+	} else if ctx.updateExpected && !ctx.dryRun {
+		if err := ctx.writeKnownLimitations(tc39KnownLimitationsFile); err != nil {
+			t.Error(err)
+		} else {
+			ctx.artifacts.add("known-limitations", tc39KnownLimitationsFile)
+		}
+		if changes, err := ctx.writeBaseline(ctx.baselineLegacy, ctx.baselineDir); err != nil {
+			t.Error(err)
+		} else {
+			ctx.artifacts.add("baseline", ctx.baselinePath())
+			if !changes.empty() {
+				// the run still fails, so that what changed is looked at before the diff is committed
+				t.Errorf("TC39_UPDATE_EXPECTED: %s was updated, %d errors added, %d updated and %d removed",
+					ctx.baselinePath(), changes.added, changes.updated, changes.removed)
+			}
+		}
+	}
+	if !ctx.dryRun {
+		if file, err := ctx.artifacts.path(tc39ResultsFile); err != nil {
+			t.Error(err)
+		} else if err = ctx.writeResults(file); err != nil {
+			t.Error(err)
+		} else {
+			ctx.artifacts.add("results", file)
+		}
+	}
 	if ctx.enableBench {
-		sort.Slice(ctx.benchmark, func(i, j int) bool {
-			return ctx.benchmark[i].duration > ctx.benchmark[j].duration
-		})
-		bench := ctx.benchmark
-		if len(bench) > 50 {
-			bench = bench[:50]
+		ctx.printBenchmark(w, 50)
+		if ctx.benchOut != "" {
+			if err := ctx.writeBenchmarkFile(ctx.benchOut); err != nil {
+				t.Error(err)
+			} else {
+				ctx.artifacts.add("bench-report", ctx.benchOut)
+			}
 		}
-		for _, item := range bench {
-			fmt.Printf("%s\t%d\n", item.name, item.duration/time.Millisecond)
+	}
+	if ctx.tzPassOut != "" {
+		if err := ctx.writeTZPass(ctx.tzPassOut); err != nil {
+			t.Error(err)
 		}
+		return
+	}
+	if ctx.benchOnly {
+		// the errors aren't collected, so there is nothing that could be put in the expected errors
+		fmt.Fprintf(w, "BENCH-ONLY RUN, correctness was not checked: %d test variants failed\n", ctx.results.benchOnlyFailureCount())
+		return
+	}
+	// the errors of the intl402 smoke tests go to their own baseline
+	errs, intlErrs := ctx.results.errorsCopy(), make(map[string]string)
+	for name := range intl402SmokeList {
+		for _, strict := range []bool{false, true} {
+			nameKey := variantKey(name, strict)
+			if errStr, ok := errs[nameKey]; ok && ctx.intlStub {
+				intlErrs[nameKey] = errStr
+				delete(errs, nameKey)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(ctx.normalizeErrors(errs))
 	}
-	if len(ctx.errors) > 0 {
-		enc := json.NewEncoder(os.Stdout)
+	if len(intlErrs) > 0 {
+		fmt.Fprintln(w, "intl402 smoke tests, expected in "+tc39IntlBaseline+":")
+		enc := json.NewEncoder(w)
 		enc.SetIndent("", "  ")
-		_ = enc.Encode(ctx.errors)
+		_ = enc.Encode(ctx.normalizeErrors(intlErrs))
 	}
 }
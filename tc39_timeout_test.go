@@ -0,0 +1,187 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+const tc39DefaultTestTimeout = 30 * time.Second
+
+// testTimeout is how long a test variant has to run, its harness included.
+var testTimeout = tc39DefaultTestTimeout //nolint:gochecknoglobals
+
+func testTimeoutFromEnv() (time.Duration, error) {
+	v := os.Getenv("TC39_TEST_TIMEOUT")
+	if v == "" {
+		return tc39DefaultTestTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("TC39_TEST_TIMEOUT must be a positive duration, got %q", v)
+	}
+	return d, nil
+}
+
+// testTimeoutError interrupts a test variant that is still running after testTimeout.
+type testTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e testTimeoutError) Error() string {
+	return fmt.Sprintf("the test didn't finish in TC39_TEST_TIMEOUT (%s)", e.timeout)
+}
+
+// tc39Interrupt is the interrupt of a call of interruptAfter whose timer fired.
+type tc39Interrupt struct {
+	v interface{}
+}
+
+// firedInterrupts has the interrupts that fired and weren't stopped yet, by runtime, in the order
+// they fired. A runtime only has one interrupt, the one of the timer that fired last.
+//nolint:gochecknoglobals
+var firedInterrupts = struct {
+	sync.Mutex
+	byVM map[*goja.Runtime][]*tc39Interrupt
+}{byVM: make(map[*goja.Runtime][]*tc39Interrupt)}
+
+// interruptAfter interrupts vm with v once timeout passed, calling then each of also, and returns
+// what stops it. Once stop returned vm is no longer interrupted by this call, even if the timer
+// fired meanwhile, but the interrupts of the other calls on vm that fired are kept, as the async
+// deadline of a test is nested in its test timeout.
+func interruptAfter(vm *goja.Runtime, timeout time.Duration, v interface{}, also ...func()) (stop func()) {
+	interrupt := &tc39Interrupt{v: v}
+	fired := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() {
+		defer close(fired)
+		firedInterrupts.Lock()
+		firedInterrupts.byVM[vm] = append(firedInterrupts.byVM[vm], interrupt)
+		vm.Interrupt(v)
+		firedInterrupts.Unlock()
+		for _, fn := range also {
+			fn()
+		}
+	})
+	return func() {
+		if timer.Stop() {
+			return
+		}
+		<-fired
+		firedInterrupts.Lock()
+		defer firedInterrupts.Unlock()
+		var rest []*tc39Interrupt
+		for _, other := range firedInterrupts.byVM[vm] {
+			if other != interrupt {
+				rest = append(rest, other)
+			}
+		}
+		if len(rest) == 0 {
+			delete(firedInterrupts.byVM, vm)
+			vm.ClearInterrupt()
+			return
+		}
+		firedInterrupts.byVM[vm] = rest
+		// this call's may have replaced the interrupt of another one
+		vm.Interrupt(rest[len(rest)-1].v)
+	}
+}
+
+// timeoutError returns the timeout a test variant was interrupted for, if it was.
+func timeoutError(err error) error {
+	var async asyncTimeoutError
+	if errors.As(err, &async) {
+		return async
+	}
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if timeout, ok := interrupted.Value().(testTimeoutError); ok {
+			return timeout
+		}
+	}
+	return nil
+}
+
+func TestTestTimeout(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	defer func(old time.Duration) { testTimeout = old }(testTimeout)
+	testTimeout = 50 * time.Millisecond
+
+	tb := &tc39CountingTB{TB: t}
+	ctx.runTC39File("test/infinite-loop.js", "test/infinite-loop.js", tb)
+	for _, strict := range []bool{false, true} {
+		result := ctx.results.resultsCopy()[variantKey("test/infinite-loop.js", strict)]
+		require.Equal(t, CategoryTimeout, result.Category)
+		require.Equal(t, "test/infinite-loop.js: the test didn't finish in TC39_TEST_TIMEOUT (50ms) "+
+			"(A test that never finishes is interrupted once TC39_TEST_TIMEOUT passed.)", result.Message)
+		require.True(t, result.Category.unexpected())
+	}
+	require.Equal(t, 2, tb.errors)
+
+	// the timer is stopped with the test, it doesn't interrupt the runtime afterwards
+	vm, _, _ := ctx.newRuntime("test/after.js", &tc39Output{})
+	stop := interruptAfter(vm, testTimeout, testTimeoutError{testTimeout})
+	stop()
+	time.Sleep(2 * testTimeout)
+	_, err := vm.RunString(`for (var i = 0; i < 1000; i++) {}`)
+	require.NoError(t, err)
+
+	// nor does one that fired
+	stop = interruptAfter(vm, time.Millisecond, testTimeoutError{time.Millisecond})
+	time.Sleep(10 * time.Millisecond)
+	stop()
+	_, err = vm.RunString(`for (var i = 0; i < 1000; i++) {}`)
+	require.NoError(t, err)
+}
+
+func TestNestedInterrupts(t *testing.T) {
+	testTimeout, asyncTimeout := testTimeoutError{time.Millisecond}, asyncTimeoutError{time.Millisecond}
+	interrupted := func(vm *goja.Runtime) interface{} {
+		_, err := vm.RunString(`for (var i = 0; i < 1000; i++) {}`)
+		if err == nil {
+			return nil
+		}
+		var interrupted *goja.InterruptedError
+		require.True(t, errors.As(err, &interrupted), err)
+		return interrupted.Value()
+	}
+
+	// the test timeout fired while the async deadline didn't
+	vm := goja.New()
+	stopTest := interruptAfter(vm, time.Millisecond, testTimeout)
+	stopAsync := interruptAfter(vm, time.Hour, asyncTimeout)
+	time.Sleep(10 * time.Millisecond)
+	stopAsync()
+	require.Equal(t, testTimeout, interrupted(vm))
+	stopTest()
+	require.Nil(t, interrupted(vm))
+
+	// both fired, in either order
+	for _, asyncFirst := range []bool{true, false} {
+		vm = goja.New()
+		if asyncFirst {
+			stopAsync = interruptAfter(vm, time.Millisecond, asyncTimeout)
+			time.Sleep(10 * time.Millisecond)
+			stopTest = interruptAfter(vm, time.Millisecond, testTimeout)
+		} else {
+			stopTest = interruptAfter(vm, time.Millisecond, testTimeout)
+			time.Sleep(10 * time.Millisecond)
+			stopAsync = interruptAfter(vm, time.Millisecond, asyncTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+		stopAsync()
+		require.Equal(t, testTimeout, interrupted(vm), "async first: %v", asyncFirst)
+		// the run took the interrupt
+		require.Nil(t, interrupted(vm))
+		stopTest()
+		require.Nil(t, interrupted(vm))
+	}
+	firedInterrupts.Lock()
+	require.Empty(t, firedInterrupts.byVM)
+	firedInterrupts.Unlock()
+}
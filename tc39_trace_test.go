@@ -0,0 +1,264 @@
+package test262
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	tc39DefaultMaxTraces   = 10
+	tc39DefaultMaxTraceLen = 64 << 20
+)
+
+// tc39TraceOptions configures re-running the slowest tests with runtime/trace enabled.
+type tc39TraceOptions struct {
+	threshold time.Duration
+	maxTraces int
+	maxBytes  int64
+}
+
+func traceOptionsFromEnv() (opts tc39TraceOptions, err error) {
+	v := os.Getenv("TC39_TRACE_SLOW")
+	if v == "" {
+		return opts, nil
+	}
+	// a threshold of zero or less would turn the tracing off again without a word
+	if opts.threshold, err = time.ParseDuration(v); err != nil || opts.threshold <= 0 {
+		return opts, fmt.Errorf("TC39_TRACE_SLOW must be a positive duration, got %q", v)
+	}
+	opts.maxTraces = tc39DefaultMaxTraces
+	if v := os.Getenv("TC39_TRACE_MAX"); v != "" {
+		if opts.maxTraces, err = strconv.Atoi(v); err != nil || opts.maxTraces < 1 {
+			return opts, fmt.Errorf("TC39_TRACE_MAX must be a positive integer, got %q", v)
+		}
+	}
+	opts.maxBytes = tc39DefaultMaxTraceLen
+	if v := os.Getenv("TC39_TRACE_MAX_BYTES"); v != "" {
+		if opts.maxBytes, err = strconv.ParseInt(v, 10, 64); err != nil || opts.maxBytes < 1 {
+			return opts, fmt.Errorf("TC39_TRACE_MAX_BYTES must be a positive number of bytes, got %q", v)
+		}
+	}
+	return opts, nil
+}
+
+// limitedFile writes up to limit bytes to w, silently dropping the rest.
+type limitedFile struct {
+	w         io.Writer
+	limit     int64
+	written   int64
+	truncated bool
+}
+
+// Write reports all of b as written even when some of it was dropped, an io.Writer writing less
+// than it was given has to fail.
+func (l *limitedFile) Write(b []byte) (int, error) {
+	kept := b
+	if rest := l.limit - l.written; int64(len(b)) > rest {
+		l.truncated = true
+		kept = b[:rest]
+	}
+	n, err := l.w.Write(kept)
+	l.written += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return len(b), nil
+}
+
+// slowTests returns the names of the tests that took longer than threshold, slowest first.
+func (ctx *tc39TestCtx) slowTests(threshold time.Duration) []string {
+	items := make([]tc39BenchmarkItem, 0)
+	for _, item := range ctx.results.benchmarkItems() {
+		if item.total() > threshold {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].total() > items[j].total()
+	})
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.name
+	}
+	return names
+}
+
+// traceSlowTests runs each test slower than the threshold again with an execution trace enabled,
+// returning the written trace files. They run under a trace subtest of their own, as the names of
+// the run itself are taken, and only after all of it, as their results are thrown away.
+func (ctx *tc39TestCtx) traceSlowTests(t *testing.T, opts tc39TraceOptions) []string {
+	names := ctx.slowTests(opts.threshold)
+	if len(names) > opts.maxTraces {
+		names = names[:opts.maxTraces]
+	}
+	defer ctx.discardResults()()
+	var traces []string
+	t.Run("trace", func(t *testing.T) {
+		for _, name := range names {
+			name := name
+			t.Run(subtestName(name), func(t *testing.T) {
+				file, err := ctx.traceTest(t, name, opts.maxBytes)
+				if err != nil {
+					t.Error(err)
+				}
+				if file != "" {
+					ctx.artifacts.add("trace", file)
+					traces = append(traces, file)
+				}
+			})
+		}
+	})
+	return traces
+}
+
+// discardResults has the tests run until the returned func is called record nowhere, so running
+// one again neither counts it twice nor dumps it or stops the run at its failure another time.
+func (ctx *tc39TestCtx) discardResults() (restore func()) {
+	results, firstNew, dump := ctx.results, ctx.firstNew, ctx.dump
+	ctx.results, ctx.firstNew, ctx.dump = newTC39Results(), nil, nil
+	return func() {
+		ctx.results, ctx.firstNew, ctx.dump = results, firstNew, dump
+	}
+}
+
+func (ctx *tc39TestCtx) traceTest(t *testing.T, name string, maxBytes int64) (string, error) {
+	s, rel := ctx.suite(name)
+	meta, src, err := parseTC39File(osPath(s.root, rel), s.harness)
+	if err != nil {
+		return "", err
+	}
+	file, err := ctx.artifacts.path("traces/" + name + ".trace")
+	if err != nil {
+		return "", err
+	}
+	var truncated bool
+	err = writeArtifact(file, func(f io.Writer) error {
+		w := &limitedFile{w: f, limit: maxBytes}
+		if err := trace.Start(w); err != nil {
+			return err
+		}
+		func() {
+			defer trace.Stop()
+			for _, strict := range testVariants(meta) {
+				ctx.runTC39Test(t, name, src, meta, strict)
+			}
+		}()
+		truncated = w.truncated
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if truncated {
+		t.Logf("trace of %s was truncated at %d bytes", name, maxBytes)
+	}
+	return file, nil
+}
+
+func TestTraceSlowTests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-trace")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	ctx := newFixtureCtx(t)
+	ctx.artifacts = newArtifacts(dir)
+	for i, name := range []string{"test/pool/pass-1.js", "test/pool/pass-2.js", "test/pool/pass-3.js"} {
+		ctx.results.addBenchmark(tc39BenchmarkItem{name: name, variants: []tc39BenchmarkVariant{{median: time.Duration(i+1) * time.Millisecond}}})
+	}
+	// faster than the threshold
+	ctx.results.addBenchmark(tc39BenchmarkItem{name: "test/pool/pass-4.js", variants: []tc39BenchmarkVariant{{median: time.Nanosecond}}})
+	require.Equal(t, []string{"test/pool/pass-3.js", "test/pool/pass-2.js", "test/pool/pass-1.js"}, ctx.slowTests(time.Microsecond))
+
+	traces := ctx.traceSlowTests(t, tc39TraceOptions{threshold: time.Microsecond, maxTraces: 2, maxBytes: 100})
+	// only the slowest ones, each cut at the limit
+	require.Equal(t, []string{
+		filepath.Join(ctx.artifacts.dir, "traces", "test", "pool", "pass-3.js.trace"),
+		filepath.Join(ctx.artifacts.dir, "traces", "test", "pool", "pass-2.js.trace"),
+	}, traces)
+	for _, file := range traces {
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		require.Equal(t, int64(100), info.Size(), file)
+	}
+	require.Equal(t, []tc39Artifact{{Type: "trace", Path: traces[0]}, {Type: "trace", Path: traces[1]}}, ctx.artifacts.entries)
+	// the tests ran again, but only the benchmarks they were picked from are recorded
+	require.Empty(t, ctx.results.resultsCopy())
+	require.Len(t, ctx.results.benchmarkItems(), 4)
+
+	// a trace under the limit is written whole
+	traces = ctx.traceSlowTests(t, tc39TraceOptions{threshold: 2500 * time.Microsecond, maxTraces: 2, maxBytes: tc39DefaultMaxTraceLen})
+	require.Len(t, traces, 1)
+	info, err := os.Stat(traces[0])
+	require.NoError(t, err)
+	require.True(t, info.Size() > 100, info.Size())
+
+	// dropping the bytes past the limit still takes all of them
+	var buf bytes.Buffer
+	w := &limitedFile{w: &buf, limit: 3}
+	n, err := w.Write([]byte("abcde"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, "abc", buf.String())
+	require.True(t, w.truncated)
+
+	// the settings that would panic or turn the tracing off are rejected before any of it
+	defer func() {
+		for _, key := range []string{"TC39_TRACE_SLOW", "TC39_TRACE_MAX", "TC39_TRACE_MAX_BYTES"} {
+			require.NoError(t, os.Unsetenv(key))
+		}
+	}()
+	for _, c := range []struct{ key, value, want string }{
+		{"TC39_TRACE_SLOW", "0s", "a positive duration"},
+		{"TC39_TRACE_SLOW", "-1s", "a positive duration"},
+		{"TC39_TRACE_MAX", "-1", "a positive integer"},
+		{"TC39_TRACE_MAX", "0", "a positive integer"},
+		{"TC39_TRACE_MAX_BYTES", "-1", "a positive number of bytes"},
+		{"TC39_TRACE_MAX_BYTES", "0", "a positive number of bytes"},
+	} {
+		require.NoError(t, os.Setenv("TC39_TRACE_SLOW", "1ms"))
+		require.NoError(t, os.Setenv(c.key, c.value))
+		_, err = traceOptionsFromEnv()
+		require.EqualError(t, err, fmt.Sprintf("%s must be %s, got %q", c.key, c.want, c.value))
+		require.NoError(t, os.Unsetenv(c.key))
+	}
+}
+
+func TestTraceOptions(t *testing.T) {
+	defer func() {
+		for _, key := range []string{"TC39_TRACE_SLOW", "TC39_TRACE_MAX", "TC39_TRACE_MAX_BYTES"} {
+			require.NoError(t, os.Unsetenv(key))
+		}
+	}()
+	opts, err := traceOptionsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, tc39TraceOptions{}, opts)
+
+	require.NoError(t, os.Setenv("TC39_TRACE_SLOW", "50ms"))
+	opts, err = traceOptionsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, tc39TraceOptions{threshold: 50 * time.Millisecond, maxTraces: tc39DefaultMaxTraces, maxBytes: tc39DefaultMaxTraceLen}, opts)
+	for _, c := range []struct{ key, invalid, valid string }{
+		{"TC39_TRACE_SLOW", "50", "50ms"}, {"TC39_TRACE_MAX", "a", "1"}, {"TC39_TRACE_MAX_BYTES", "1MB", "1024"},
+	} {
+		require.NoError(t, os.Setenv(c.key, c.invalid))
+		_, err = traceOptionsFromEnv()
+		require.Error(t, err, c.key)
+		require.True(t, strings.HasPrefix(err.Error(), c.key+" must be a positive "), err)
+		require.NoError(t, os.Setenv(c.key, c.valid))
+	}
+	opts, err = traceOptionsFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, tc39TraceOptions{threshold: 50 * time.Millisecond, maxTraces: 1, maxBytes: 1024}, opts)
+}
@@ -0,0 +1,210 @@
+package test262
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/require"
+)
+
+// tc39Transitions sorts the variants whose result isn't the expected one by how it moved: the
+// new failures of variants expected to pass, the changed ones of variants expected to fail with
+// another error, usually a drift of the message, and the fixed ones that pass despite an expected
+// error. They're triaged differently, a new failure is a regression where a changed one rarely is.
+type tc39Transitions struct {
+	newFailures []string
+	changed     []tc39ChangedFailure
+	fixed       []string
+}
+
+// tc39ChangedFailure is a variant that failed with another error than the expected one.
+type tc39ChangedFailure struct {
+	nameKey, expected, actual string
+}
+
+// transitions compares the results with the expected errors of their variants.
+func (ctx *tc39TestCtx) transitions(results map[string]TestResult) *tc39Transitions {
+	tr := &tc39Transitions{}
+	for nameKey, result := range results {
+		switch {
+		case result.Category == CategoryPass:
+			if _, ok := ctx.expectedError(variantName(nameKey), nameKey); ok {
+				tr.fixed = append(tr.fixed, nameKey)
+			}
+		case !result.Category.unexpected():
+		case result.Expected != "":
+			tr.changed = append(tr.changed, tc39ChangedFailure{nameKey: nameKey, expected: result.Expected, actual: result.Message})
+		default:
+			tr.newFailures = append(tr.newFailures, nameKey)
+		}
+	}
+	sort.Strings(tr.newFailures)
+	sort.Slice(tr.changed, func(i, j int) bool { return tr.changed[i].nameKey < tr.changed[j].nameKey })
+	sort.Strings(tr.fixed)
+	return tr
+}
+
+func (tr *tc39Transitions) empty() bool {
+	return len(tr.newFailures) == 0 && len(tr.changed) == 0 && len(tr.fixed) == 0
+}
+
+// summary has a line with the counts and a section for each of the transitions, listing the first
+// max of its variants, the changed ones with the expected and the actual error below each other.
+func (tr *tc39Transitions) summary(max int) []string {
+	if tr.empty() {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("%d new failures, %d changed failures and %d fixed tests",
+		len(tr.newFailures), len(tr.changed), len(tr.fixed))}
+	section := func(title string, n int, line func(int) []string) {
+		if n == 0 {
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s (%d):", title, n))
+		for i := 0; i < n && i < max; i++ {
+			lines = append(lines, line(i)...)
+		}
+		if n > max {
+			lines = append(lines, fmt.Sprintf("  and %d more", n-max))
+		}
+	}
+	section("new failures", len(tr.newFailures), func(i int) []string {
+		return []string{"  " + tr.newFailures[i]}
+	})
+	section("changed failures", len(tr.changed), func(i int) []string {
+		c := tr.changed[i]
+		return []string{"  " + c.nameKey, "    expected: " + c.expected, "    actual:   " + c.actual}
+	})
+	section("fixed tests", len(tr.fixed), func(i int) []string {
+		return []string{"  " + tr.fixed[i]}
+	})
+	return lines
+}
+
+// tc39TransitionsDoc is the section of the results with the transitions.
+type tc39TransitionsDoc struct {
+	NewFailures     int                 `json:"newFailures"`
+	ChangedFailures int                 `json:"changedFailures"`
+	Fixed           int                 `json:"fixed"`
+	New             []tc39ResultKey     `json:"new,omitempty"`
+	Changed         []tc39ChangedRecord `json:"changed,omitempty"`
+	FixedTests      []tc39ResultKey     `json:"fixedTests,omitempty"`
+}
+
+type tc39ChangedRecord struct {
+	Key      tc39ResultKey `json:"key"`
+	Expected string        `json:"expected"`
+	Message  string        `json:"message"`
+}
+
+// doc returns the transitions with structured keys.
+func (tr *tc39Transitions) doc(suites []string) (*tc39TransitionsDoc, error) {
+	doc := &tc39TransitionsDoc{NewFailures: len(tr.newFailures), ChangedFailures: len(tr.changed), Fixed: len(tr.fixed)}
+	keys := func(nameKeys []string) ([]tc39ResultKey, error) {
+		var ks []tc39ResultKey
+		for _, nameKey := range nameKeys {
+			k, err := parseLegacyKey(nameKey, suites)
+			if err != nil {
+				return nil, err
+			}
+			ks = append(ks, k)
+		}
+		return ks, nil
+	}
+	var err error
+	if doc.New, err = keys(tr.newFailures); err != nil {
+		return nil, err
+	}
+	if doc.FixedTests, err = keys(tr.fixed); err != nil {
+		return nil, err
+	}
+	for _, c := range tr.changed {
+		k, err := parseLegacyKey(c.nameKey, suites)
+		if err != nil {
+			return nil, err
+		}
+		doc.Changed = append(doc.Changed, tc39ChangedRecord{Key: k, Expected: c.expected, Message: c.actual})
+	}
+	return doc, nil
+}
+
+func TestTransitions(t *testing.T) {
+	ctx := newFixtureCtx(t)
+	ctx.expectedErrors = map[string]string{
+		// fails with another error
+		"test/dump/fail.js-strict:false": "test/dump/fail.js: Test262Error: the old message",
+		// fails as expected
+		"test/dump/fail.js-strict:true": `~Test262Error: Expected SameValue\(«a\d», «b»\) to be true`,
+		// passes
+		"test/compat/es5.js-strict:true": "test/compat/es5.js: Test262Error: fixed",
+	}
+	tb := &tc39CountingTB{TB: t}
+	for _, name := range []string{"test/compat/es5.js", "test/dump/fail.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, tb)
+		})
+	}
+	// a new failure, of a variant expected to pass
+	ctx.results.recordResult("test/new.js-strict:false", TestResult{Category: CategoryNewFailure, Message: "test/new.js: boom"})
+	results := ctx.results.resultsCopy()
+	require.Equal(t, "test/dump/fail.js: Test262Error: the old message", results["test/dump/fail.js-strict:false"].Expected)
+	require.Empty(t, results["test/dump/fail.js-strict:true"].Expected)
+
+	tr := ctx.transitions(results)
+	require.Equal(t, []string{"test/new.js-strict:false"}, tr.newFailures)
+	require.Len(t, tr.changed, 1)
+	actual := results["test/dump/fail.js-strict:false"].Message
+	require.Equal(t, tc39ChangedFailure{
+		nameKey: "test/dump/fail.js-strict:false", expected: "test/dump/fail.js: Test262Error: the old message", actual: actual,
+	}, tr.changed[0])
+	require.Equal(t, []string{"test/compat/es5.js-strict:true"}, tr.fixed)
+	require.Equal(t, []string{
+		"1 new failures, 1 changed failures and 1 fixed tests",
+		"new failures (1):",
+		"  test/new.js-strict:false",
+		"changed failures (1):",
+		"  test/dump/fail.js-strict:false",
+		"    expected: test/dump/fail.js: Test262Error: the old message",
+		"    actual:   " + actual,
+		"fixed tests (1):",
+		"  test/compat/es5.js-strict:true",
+	}, tr.summary(5))
+
+	// a panic of a variant expected to fail differently is still a changed failure
+	results["test/panic.js-strict:true"] = TestResult{Category: CategoryPanic, Message: "panic: test/panic.js: x", Expected: "test/panic.js: y"}
+	results["test/panic.js-strict:false"] = TestResult{Category: CategoryPanic, Message: "panic: test/panic.js: x"}
+	tr = ctx.transitions(results)
+	require.Equal(t, []string{"test/new.js-strict:false", "test/panic.js-strict:false"}, tr.newFailures)
+	require.Len(t, tr.changed, 2)
+	require.Equal(t, []string{
+		"2 new failures, 2 changed failures and 1 fixed tests",
+		"new failures (2):",
+		"  test/new.js-strict:false",
+		"  and 1 more",
+		"changed failures (2):",
+		"  test/dump/fail.js-strict:false",
+		"    expected: test/dump/fail.js: Test262Error: the old message",
+		"    actual:   " + actual,
+		"  and 1 more",
+		"fixed tests (1):",
+		"  test/compat/es5.js-strict:true",
+	}, tr.summary(1))
+
+	doc, err := tr.doc(nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, doc.NewFailures)
+	require.Equal(t, tc39ChangedRecord{
+		Key:      tc39ResultKey{Test: "test/dump/fail.js", Mode: lib.CompatibilityModeExtended.String()},
+		Expected: "test/dump/fail.js: Test262Error: the old message", Message: actual,
+	}, doc.Changed[0])
+	require.Equal(t, []tc39ResultKey{{Test: "test/compat/es5.js", Strict: true, Mode: lib.CompatibilityModeExtended.String()}}, doc.FixedTests)
+
+	// nothing moved, nothing to tell
+	require.Empty(t, ctx.transitions(map[string]TestResult{
+		"test/dump/fail.js-strict:true":  {Category: CategoryExpectedFailure},
+		"test/compat/es6.js-strict:true": {Category: CategoryPass},
+	}).summary(5))
+}
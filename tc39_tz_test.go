@@ -0,0 +1,178 @@
+package test262
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39TZDir is the subtree whose results depend on the timezone.
+const tc39TZDir = "test/built-ins/Date"
+
+// tc39TZPass are the results of every variant that ran in one timezone, by the key used in
+// breaking_test_errors.json, with an empty error for the ones that passed.
+type tc39TZPass map[string]string
+
+// tzRunPattern is the -run selecting the tests of tc39TZDir.
+func tzRunPattern() string {
+	elems := append([]string{"TestTC39"}, tc39SubtestLevels()...)
+	for i, elem := range elems {
+		elems[i] = "^" + elem + "$"
+	}
+	return strings.Join(elems, "/") + "/^" + regexp.QuoteMeta(subtestName(tc39TZDir)+tc39NameSeparator)
+}
+
+func (ctx *tc39TestCtx) writeTZPass(name string) error {
+	b, err := json.MarshalIndent(ctx.results.variantsRun(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeArtifact(name, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+func readTZPass(name string) (tc39TZPass, error) {
+	b, err := ioutil.ReadFile(name) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	var pass tc39TZPass
+	if err = json.Unmarshal(b, &pass); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return pass, nil
+}
+
+// tc39TZKey is a variant of a test run in a timezone.
+type tc39TZKey struct {
+	variant, tz string
+}
+
+// tzDivergences returns the variants whose results weren't the same in all timezones, sorted,
+// with their results by timezone.
+func tzDivergences(results map[tc39TZKey]string, tzs []string) (variants []string, byTZ map[string][]string) {
+	all := make(map[string]bool)
+	for key := range results {
+		all[key.variant] = true
+	}
+	byTZ = make(map[string][]string)
+	for variant := range all {
+		var diverges bool
+		outcomes := make([]string, len(tzs))
+		for i, tz := range tzs {
+			errStr, ran := results[tc39TZKey{variant: variant, tz: tz}]
+			switch {
+			case !ran:
+				outcomes[i] = "didn't run"
+			case errStr == "":
+				outcomes[i] = "passed"
+			default:
+				outcomes[i] = errStr
+			}
+			diverges = diverges || outcomes[i] != outcomes[0]
+		}
+		if diverges {
+			variants = append(variants, variant)
+			byTZ[variant] = outcomes
+		}
+	}
+	sort.Strings(variants)
+	return variants, byTZ
+}
+
+// TestTC39TZMatrix runs the tests of tc39TZDir once in each timezone of TC39_TZ_MATRIX, each in
+// its own process as the timezone of a process can't be changed once it was used, and reports
+// the tests which results depend on it.
+func TestTC39TZMatrix(t *testing.T) {
+	v := os.Getenv("TC39_TZ_MATRIX")
+	if v == "" {
+		t.Skip("set TC39_TZ_MATRIX=UTC,America/New_York,... to run " + tc39TZDir + " in each of those timezones")
+	}
+	if _, err := os.Stat(tc39BASE); err != nil {
+		t.Skipf("the tc39 tests need to be in %s (%v)", tc39BASE, err)
+	}
+	dir, err := ioutil.TempDir("", "tc39-tz")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	out := newTestWriter(t)
+	defer out.flush()
+
+	var tzs []string
+	results := make(map[tc39TZKey]string)
+	for _, tz := range strings.Split(v, ",") {
+		if tz = strings.TrimSpace(tz); tz == "" {
+			continue
+		}
+		if _, err = time.LoadLocation(tz); err != nil {
+			t.Fatalf("TC39_TZ_MATRIX: %v", err)
+		}
+		passFile := filepath.Join(dir, fmt.Sprintf("%d.json", len(tzs)))
+		cmd := exec.Command(os.Args[0], "-test.run", tzRunPattern(), "-test.count=1") //nolint:gosec
+		cmd.Env = append(os.Environ(), "TZ="+tz, "TC39_TZ_PASS_OUT="+passFile)
+		output, runErr := cmd.CombinedOutput()
+		pass, err := readTZPass(passFile)
+		if err != nil {
+			t.Errorf("the pass with TZ=%s didn't write its results: %v (%v)\n%s", tz, err, runErr, output)
+			continue
+		}
+		failing := 0
+		for variant, errStr := range pass {
+			results[tc39TZKey{variant: variant, tz: tz}] = errStr
+			if errStr != "" {
+				failing++
+			}
+		}
+		tzs = append(tzs, tz)
+		fmt.Fprintf(out, "TZ=%s: %d test variants, %d failing\n", tz, len(pass), failing)
+	}
+
+	variants, byTZ := tzDivergences(results, tzs)
+	fmt.Fprintf(out, "timezone divergences (%d):\n", len(variants))
+	for _, variant := range variants {
+		fmt.Fprintln(out, variant)
+		for i, tz := range tzs {
+			fmt.Fprintf(out, "\t%s: %s\n", tz, byTZ[variant][i])
+		}
+	}
+}
+
+func TestTZDivergences(t *testing.T) {
+	results := map[tc39TZKey]string{
+		{"test/same.js-strict:false", "UTC"}:              "",
+		{"test/same.js-strict:false", "Europe/Sofia"}:     "",
+		{"test/same-err.js-strict:true", "UTC"}:           "Test262Error: no",
+		{"test/same-err.js-strict:true", "Europe/Sofia"}:  "Test262Error: no",
+		{"test/differs.js-strict:true", "UTC"}:            "",
+		{"test/differs.js-strict:true", "Europe/Sofia"}:   "Test262Error: DST",
+		{"test/only-utc.js-strict:false", "UTC"}:          "",
+		{"test/differs.js-strict:false", "UTC"}:           "Test262Error: a",
+		{"test/differs.js-strict:false", "Europe/Sofia"}:  "Test262Error: b",
+		{"test/differs.js-strict:false", "Asia/Kolkata"}:  "Test262Error: a",
+		{"test/same.js-strict:false", "Asia/Kolkata"}:     "",
+		{"test/same-err.js-strict:true", "Asia/Kolkata"}:  "Test262Error: no",
+		{"test/differs.js-strict:true", "Asia/Kolkata"}:   "",
+		{"test/only-utc.js-strict:false", "Asia/Kolkata"}: "",
+	}
+	variants, byTZ := tzDivergences(results, []string{"UTC", "Europe/Sofia", "Asia/Kolkata"})
+	require.Equal(t, []string{
+		"test/differs.js-strict:false", "test/differs.js-strict:true", "test/only-utc.js-strict:false",
+	}, variants)
+	require.Equal(t, []string{"passed", "Test262Error: DST", "passed"}, byTZ["test/differs.js-strict:true"])
+	require.Equal(t, []string{"passed", "didn't run", "passed"}, byTZ["test/only-utc.js-strict:false"])
+
+	require.Equal(t, "^TestTC39$/"+strings.Repeat("^tc39$/", len(tc39SubtestLevels()))+"^test:built-ins:Date:",
+		tzRunPattern())
+}
@@ -0,0 +1,232 @@
+package test262
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tc39BaselineChanges counts how updating the expected errors changed them.
+type tc39BaselineChanges struct {
+	added, updated, removed int
+}
+
+func (c tc39BaselineChanges) empty() bool {
+	return c.added == 0 && c.updated == 0 && c.removed == 0
+}
+
+// mergeBaseline returns the expected errors updated with the results of the run: the variants that
+// failed unexpectedly with the error they failed with, and without the ones that passed or whose
+// test doesn't exist anymore. The variants out of the scope of the run, the ones it skipped and
+// the ones of an existing test without a result, like one whose frontmatter doesn't parse, keep
+// what was expected of them. A new entry has the category of its failure and today as its since,
+// an updated one keeps its since and issue.
+func mergeBaseline(
+	baseline map[string]tc39ExpectedEntry, results map[string]TestResult,
+	inScope, exists func(nameKey string) bool, today string,
+) (map[string]tc39ExpectedEntry, tc39BaselineChanges) {
+	var changes tc39BaselineChanges
+	merged := make(map[string]tc39ExpectedEntry, len(baseline))
+	for nameKey, e := range baseline {
+		result, ran := results[nameKey]
+		switch {
+		case !inScope(nameKey), !ran && exists(nameKey):
+			merged[nameKey] = e
+		case !ran, result.Category == CategoryPass:
+			changes.removed++
+		case result.Category.unexpected():
+			if result.Message != e.Error {
+				changes.updated++
+			}
+			e.Error = result.Message
+			if e.Category != "" {
+				e.Category = failureKind(result)
+			}
+			merged[nameKey] = e
+		default:
+			merged[nameKey] = e
+		}
+	}
+	for nameKey, result := range results {
+		if _, ok := baseline[nameKey]; !ok && result.Category.unexpected() && inScope(nameKey) {
+			merged[nameKey] = tc39ExpectedEntry{Error: result.Message, Category: failureKind(result), Since: today}
+			changes.added++
+		}
+	}
+	return merged, changes
+}
+
+// inBaselineScope tells if the run had the variant of the key to run, the others aren't changed in
+// the expected errors: the directories it excluded, the modes it didn't run in, the extra suites it
+// wasn't given and the intl402 smoke tests, which have their own baseline with TC39_INTL_STUB.
+func (ctx *tc39TestCtx) inBaselineScope(nameKey string) bool {
+	k, err := parseLegacyKey(nameKey, nil)
+	if err != nil {
+		return false
+	}
+	ran := false
+	for _, mode := range compatModes {
+		ran = ran || mode.String() == k.Mode
+	}
+	switch s, _ := ctx.suite(k.Test); {
+	case !ran:
+		return false
+	case s.name != "":
+		return true
+	case ctx.intlSmoke(k.Test):
+		return !ctx.intlStub
+	default:
+		return ctx.dirs.has(k.Test)
+	}
+}
+
+// writeBaseline merges the results of the run into the expected errors, which it rewrites
+// normalized and with sorted keys, as they're indented by the encoder, so their diff only has what
+// changed. They go back to the legacy file or the directory, see writeBaselineFiles.
+func (ctx *tc39TestCtx) writeBaseline(legacy, dir string) (tc39BaselineChanges, error) {
+	files, err := baselineFiles(legacy, dir)
+	if err != nil {
+		return tc39BaselineChanges{}, err
+	}
+	baseline, err := readBaseline(files)
+	if err != nil {
+		return tc39BaselineChanges{}, err
+	}
+	today := time.Now().Format(tc39SinceLayout)
+	// the variants of a test have the same file, it's only looked for once
+	found := make(map[string]bool)
+	exists := func(nameKey string) bool {
+		test := variantName(nameKey)
+		ok, seen := found[test]
+		if !seen {
+			ok = ctx.testExists(test)
+			found[test] = ok
+		}
+		return ok
+	}
+	merged, changes := mergeBaseline(baseline, ctx.results.resultsCopy(), ctx.inBaselineScope, exists, today)
+	return changes, writeBaselineFiles(legacy, dir, ctx.normalizeEntries(merged))
+}
+
+func TestMergeBaseline(t *testing.T) {
+	baseline := map[string]tc39ExpectedEntry{
+		"test/expected.js-strict:false": {Error: "test/expected.js: Test262Error: a", Since: "2020-01-01"},
+		"test/changed.js-strict:true": {
+			Error: "test/changed.js: Test262Error: old", Category: kindRuntime, Since: "2020-01-02",
+			Issue: "https://github.com/dop251/goja/issues/3",
+		},
+		"test/passes.js-strict:false":    {Error: "test/passes.js: Test262Error: b"},
+		"test/gone.js-strict:true":       {Error: "test/gone.js: Test262Error: c"},
+		"test/unparsable.js-strict:true": {Error: "test/unparsable.js: Test262Error: i"},
+		"test/skipped.js-strict:false":   {Error: "test/skipped.js: Test262Error: d"},
+		"test/other.js-strict:false":     {Error: "test/other.js: Test262Error: e"},
+	}
+	// test/gone.js and test/unparsable.js have no result, the first was removed and the
+	// frontmatter of the second doesn't parse
+	results := map[string]TestResult{
+		"test/expected.js-strict:false": {Category: CategoryExpectedFailure, Message: "test/expected.js: Test262Error: a"},
+		"test/changed.js-strict:true":   {Category: CategoryChangedFailure, Message: "test/changed.js: SyntaxError: new", Early: true},
+		"test/passes.js-strict:false":   {Category: CategoryPass},
+		"test/skipped.js-strict:false":  {Category: CategorySkippedQuarantined, Message: "Quarantined"},
+		"test/new.js-strict:true":       {Category: CategoryNewFailure, Message: "test/new.js: Test262Error: f"},
+		"test/timeout.js-strict:false":  {Category: CategoryTimeout, Message: "test/timeout.js: g"},
+		"test/fine.js-strict:false":     {Category: CategoryPass},
+		"test/other.js-strict:true":     {Category: CategoryNewFailure, Message: "test/other.js: Test262Error: h"},
+	}
+	// test/other.js is in a directory the run excluded
+	inScope := func(nameKey string) bool { return !strings.HasPrefix(nameKey, "test/other.js-") }
+	exists := func(nameKey string) bool { return !strings.HasPrefix(nameKey, "test/gone.js-") }
+	merged, changes := mergeBaseline(baseline, results, inScope, exists, "2020-02-03")
+	require.Equal(t, map[string]tc39ExpectedEntry{
+		"test/expected.js-strict:false": {Error: "test/expected.js: Test262Error: a", Since: "2020-01-01"},
+		"test/changed.js-strict:true": {
+			Error: "test/changed.js: SyntaxError: new", Category: kindCompile, Since: "2020-01-02",
+			Issue: "https://github.com/dop251/goja/issues/3",
+		},
+		"test/unparsable.js-strict:true": {Error: "test/unparsable.js: Test262Error: i"},
+		"test/skipped.js-strict:false":   {Error: "test/skipped.js: Test262Error: d"},
+		"test/other.js-strict:false":     {Error: "test/other.js: Test262Error: e"},
+		"test/new.js-strict:true":        {Error: "test/new.js: Test262Error: f", Category: kindRuntime, Since: "2020-02-03"},
+		"test/timeout.js-strict:false":   {Error: "test/timeout.js: g", Category: kindTimeout, Since: "2020-02-03"},
+	}, merged)
+	require.Equal(t, tc39BaselineChanges{added: 2, updated: 1, removed: 2}, changes)
+
+	// written and read back it's the same, and merging it with the same results changes nothing
+	dir, err := ioutil.TempDir("", "tc39-baseline")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	file := filepath.Join(dir, tc39Baseline)
+	require.NoError(t, writeExpectedEntries(file, merged))
+	first, err := ioutil.ReadFile(file) //nolint:gosec
+	require.NoError(t, err)
+	read, err := readExpectedEntries(first)
+	require.NoError(t, err)
+	require.Equal(t, merged, read)
+	again, changes := mergeBaseline(read, results, inScope, exists, "2020-02-04")
+	require.True(t, changes.empty())
+	require.NoError(t, writeExpectedEntries(file, again))
+	b, err := ioutil.ReadFile(file) //nolint:gosec
+	require.NoError(t, err)
+	require.Equal(t, string(first), string(b))
+
+	// the baselines of the repository are written exactly as they are
+	files, err := baselineFiles(tc39Baseline, tc39BaselineDir)
+	require.NoError(t, err)
+	for _, name := range files {
+		first, err = ioutil.ReadFile(name) //nolint:gosec
+		require.NoError(t, err)
+		read, err = readExpectedEntries(first)
+		require.NoError(t, err)
+		require.NoError(t, writeExpectedEntries(file, read))
+		b, err = ioutil.ReadFile(file) //nolint:gosec
+		require.NoError(t, err)
+		require.Equal(t, string(first), string(b), name)
+	}
+}
+
+func TestUpdateBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-baseline")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	legacy, areas := filepath.Join(dir, tc39Baseline), filepath.Join(dir, tc39BaselineDir)
+	require.NoError(t, writeBaselineDir(areas, entriesOf(map[string]string{
+		"test/compat/es5.js-strict:false":      "test/compat/es5.js: Test262Error: it failed once",
+		"test/dump/fail.js-strict:true":        "test/dump/fail.js: Test262Error: another error",
+		"test/intl402/excluded.js-strict:true": "test/intl402/excluded.js: Test262Error: not run",
+	})))
+
+	ctx := newFixtureCtx(t)
+	ctx.dirs, err = newDirSelection(map[string]tc39DirConfig{"test/intl402": {Select: tc39SelectExclude}}, "", "")
+	require.NoError(t, err)
+	tb := &tc39CountingTB{TB: t}
+	for _, name := range []string{"test/dump/fail.js", "test/compat/es5.js"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			ctx.runTC39File(name, name, tb)
+		})
+	}
+	require.Equal(t, 2, tb.errors)
+	changes, err := ctx.writeBaseline(legacy, areas)
+	require.NoError(t, err)
+	require.Equal(t, tc39BaselineChanges{added: 1, updated: 1, removed: 1}, changes)
+	files, err := baselineFiles(legacy, areas)
+	require.NoError(t, err)
+	// the only error of compat.json is gone, and it with it
+	require.Equal(t, []string{filepath.Join(areas, "dump.json"), filepath.Join(areas, "intl402.json")}, files)
+	entries, err := readBaseline(files)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	added := entries["test/dump/fail.js-strict:false"]
+	require.Contains(t, added.Error, "Expected SameValue(«a1», «b») to be true")
+	require.Equal(t, kindRuntime, added.Category)
+	require.Equal(t, time.Now().Format(tc39SinceLayout), added.Since)
+	updated := entries["test/dump/fail.js-strict:true"]
+	require.Contains(t, updated.Error, "Expected SameValue(«a1», «b») to be true")
+	require.Empty(t, updated.Category)
+	require.Equal(t, tc39ExpectedEntry{Error: "test/intl402/excluded.js: Test262Error: not run"}, entries["test/intl402/excluded.js-strict:true"])
+}
@@ -0,0 +1,278 @@
+package test262
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+)
+
+//nolint:gochecknoglobals
+var (
+	// ignoredTopLevelDirs are directories directly below the checkout that never contain tests,
+	// src has the inputs of the test generator, some of which look like tests.
+	ignoredTopLevelDirs = map[string]bool{
+		"harness": true,
+		"src":     true,
+		"tools":   true,
+	}
+	// ignoredExtensions are file types found next to tests that aren't tests, anything else that
+	// isn't .js is ignored as well, but counted as "other".
+	ignoredExtensions = map[string]bool{
+		".case":     true,
+		".template": true,
+		".md":       true,
+		".json":     true,
+		".py":       true,
+		".yml":      true,
+		// the expected output of the test next to it
+		tc39ExpectedOutputExt: true,
+	}
+)
+
+// tc39Discovery is the result of walking the test tree.
+type tc39Discovery struct {
+	// names are the NFC normalized, slash separated paths of the tests relative to the base,
+	// sorted byte-wise.
+	names []string
+	// paths has the paths on disk of the tests whose names had to be normalized.
+	paths map[string]string
+	// ignored counts the files and directories that were ignored by reason.
+	ignored map[string]int
+	// warnings are infrastructure issues that didn't stop the discovery.
+	warnings []string
+}
+
+// discoverTests finds every test file below dir. Symlinked directories are followed, but every
+// directory is only visited once by its resolved path, so a cycle or two links to the same
+// directory don't make it recurse forever or run tests twice.
+//
+// The names are normalized to NFC, as macOS stores file names decomposed, and sorted byte-wise,
+// so both the keys and the order are the same on every OS regardless of how the filesystem
+// orders or compares names.
+func discoverTests(base, dir string) (*tc39Discovery, error) {
+	return discoverTestsIn(base, dir, nil)
+}
+
+// discoverTestsIn is discoverTests not descending into the directories keep returns false for, all
+// of them are walked if it's nil.
+func discoverTestsIn(base, dir string, keep func(dir string) bool) (*tc39Discovery, error) {
+	d := &tc39Discovery{paths: make(map[string]string), ignored: make(map[string]int)}
+	w := &tc39Walker{base: base, keep: keep, visited: make(map[string]bool), ignored: d.ignored, warnings: &d.warnings}
+	err := w.walk(dir, func(file string) {
+		name := file
+		if !utf8.ValidString(file) {
+			d.warnings = append(d.warnings, fmt.Sprintf("%q is not valid UTF-8 and can't be normalized", file))
+		} else if name = norm.NFC.String(file); name != file {
+			d.paths[name] = file
+		}
+		d.names = append(d.names, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(d.names)
+	return d, nil
+}
+
+// path returns the path on disk of the test with the given name.
+func (d *tc39Discovery) path(name string) string {
+	if file, ok := d.paths[name]; ok {
+		return file
+	}
+	return name
+}
+
+type tc39Walker struct {
+	base    string
+	keep    func(dir string) bool
+	visited map[string]bool
+	ignored map[string]int
+	// warnings are the discovery's, for the links that can't be followed
+	warnings *[]string
+}
+
+func (w *tc39Walker) walk(dir string, fn func(name string)) error {
+	resolved, err := filepath.EvalSymlinks(filepath.Join(w.base, dir))
+	if err != nil {
+		return err
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return err
+	}
+	if w.visited[resolved] {
+		return nil
+	}
+	w.visited[resolved] = true
+
+	release := openFiles.acquire()
+	files, err := ioutil.ReadDir(resolved)
+	release()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.Name()[0] == '.' {
+			continue
+		}
+		name := path.Join(dir, file.Name())
+		if file.Mode()&os.ModeSymlink != 0 {
+			// ReadDir doesn't follow symlinks, so see what the link points to
+			target, err := os.Stat(filepath.Join(resolved, file.Name()))
+			if err != nil {
+				// a dangling link is a broken checkout, but only of what it pointed to
+				*w.warnings = append(*w.warnings, fmt.Sprintf("the symlink %s can't be followed: %v", name, err))
+				w.ignored["broken symlink"]++
+				continue
+			}
+			file = target
+		}
+		if file.IsDir() {
+			if dir == "" && ignoredTopLevelDirs[file.Name()] {
+				w.ignored["directory "+file.Name()+"/"]++
+				continue
+			}
+			if w.keep != nil && !w.keep(name) {
+				w.ignored["pruned directory"]++
+				continue
+			}
+			if err = w.walk(name, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		switch ext := path.Ext(file.Name()); {
+		case ext == ".js" && strings.HasSuffix(file.Name(), "_FIXTURE.js"):
+			w.ignored["fixture"]++
+		case ext == ".js":
+			fn(name)
+		case ignoredExtensions[ext]:
+			w.ignored["extension "+ext]++
+		default:
+			w.ignored["other"]++
+		}
+	}
+	return nil
+}
+
+func TestDiscoverTestsSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39walk")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	base := filepath.Join(dir, "test262")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{"test/a", "test/b"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(base, filepath.FromSlash(d)), 0o755))
+	}
+	require.NoError(t, os.MkdirAll(outside, 0o755))
+	for _, f := range []string{"test/a/x.js", "test/b/y.js", "test/b/y_FIXTURE.js"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(base, filepath.FromSlash(f)), nil, 0o644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outside, "z.js"), nil, 0o644))
+
+	if err = os.Symlink(outside, filepath.Join(base, "test", "linked")); err != nil {
+		t.Skipf("symlinks aren't supported: %v", err)
+	}
+	// a cycle back to the root and a second link to an already visited directory
+	require.NoError(t, os.Symlink(filepath.Join(base, "test"), filepath.Join(base, "test", "b", "cycle")))
+	require.NoError(t, os.Symlink(filepath.Join(base, "test", "a"), filepath.Join(base, "test", "c")))
+
+	// the base itself being a symlink is how CI usually sets it up
+	linkedBase := filepath.Join(dir, "base")
+	require.NoError(t, os.Symlink(base, linkedBase))
+
+	d, err := discoverTests(linkedBase, "test")
+	require.NoError(t, err)
+	require.Equal(t, []string{"test/a/x.js", "test/b/y.js", "test/linked/z.js"}, d.names)
+	require.Empty(t, d.warnings)
+
+	// a dangling link is skipped with a warning, the rest is still found
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing"), filepath.Join(base, "test", "a", "gone.js")))
+	d, err = discoverTests(linkedBase, "test")
+	require.NoError(t, err)
+	require.Equal(t, []string{"test/a/x.js", "test/b/y.js", "test/linked/z.js"}, d.names)
+	require.Len(t, d.warnings, 1)
+	require.True(t, strings.HasPrefix(d.warnings[0], "the symlink test/a/gone.js can't be followed: "), d.warnings[0])
+	require.Equal(t, 1, d.ignored["broken symlink"])
+}
+
+func TestDiscoverTestsIgnored(t *testing.T) {
+	base, err := ioutil.TempDir("", "tc39walk")
+	require.NoError(t, err)
+	defer os.RemoveAll(base) //nolint:errcheck
+
+	for _, f := range []string{
+		"test/a/x.js", "test/a/x_FIXTURE.js", "test/a/README.md", "test/a/notes.txt",
+		"src/dstr/default.case", "src/dstr/templates/x.template", "src/dstr/generated.js",
+		"harness/assert.js",
+	} {
+		f = filepath.Join(base, filepath.FromSlash(f))
+		require.NoError(t, os.MkdirAll(filepath.Dir(f), 0o755))
+		require.NoError(t, ioutil.WriteFile(f, nil, 0o644))
+	}
+
+	d, err := discoverTests(base, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"test/a/x.js"}, d.names)
+	require.Equal(t, map[string]int{
+		"directory harness/": 1,
+		"directory src/":     1,
+		"extension .md":      1,
+		"fixture":            1,
+		"other":              1,
+	}, d.ignored)
+}
+
+func TestDiscoverTestsOrder(t *testing.T) {
+	base, err := ioutil.TempDir("", "tc39walk")
+	require.NoError(t, err)
+	defer os.RemoveAll(base) //nolint:errcheck
+
+	// case insensitively these would sort as a, B, c-d, c/y, c/Z and walking the directories
+	// one by one would put c/ before c-d.js
+	for _, f := range []string{"test/a.js", "test/B.js", "test/c/y.js", "test/c/Z.js", "test/c-d.js"} {
+		f = filepath.Join(base, filepath.FromSlash(f))
+		require.NoError(t, os.MkdirAll(filepath.Dir(f), 0o755))
+		require.NoError(t, ioutil.WriteFile(f, nil, 0o644))
+	}
+
+	d, err := discoverTests(base, "test")
+	require.NoError(t, err)
+	require.Equal(t, []string{"test/B.js", "test/a.js", "test/c-d.js", "test/c/Z.js", "test/c/y.js"}, d.names)
+}
+
+func TestDiscoverTestsUnicode(t *testing.T) {
+	const (
+		composed   = "caf\u00e9.js"  // é as a single code point, as Linux keeps it
+		decomposed = "cafe\u0301.js" // e followed by a combining acute accent, as macOS stores it
+	)
+	for _, file := range []string{composed, decomposed} {
+		file := file
+		t.Run(fmt.Sprintf("%+q", file), func(t *testing.T) {
+			base, err := ioutil.TempDir("", "tc39walk")
+			require.NoError(t, err)
+			defer os.RemoveAll(base) //nolint:errcheck
+
+			require.NoError(t, os.MkdirAll(filepath.Join(base, "test"), 0o755))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(base, "test", file), nil, 0o644))
+
+			d, err := discoverTests(base, "test")
+			require.NoError(t, err)
+			require.Equal(t, []string{"test/" + composed}, d.names)
+			require.Empty(t, d.warnings)
+			// the on disk name is still needed to open it on filesystems that don't normalize
+			found, err := os.Stat(osPath(base, d.path("test/"+composed)))
+			require.NoError(t, err)
+			require.False(t, found.IsDir())
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package test262
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// checkWritableDir tells if a file can be created in dir, by creating and removing one.
+func checkWritableDir(dir string) error {
+	release := openFiles.acquire()
+	defer release()
+	f, err := ioutil.TempFile(dir, ".tc39-write-check")
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkWritableFile tells if the file name can be written, without changing it if it exists.
+func checkWritableFile(name string) error {
+	release := openFiles.acquire()
+	f, err := os.OpenFile(name, os.O_WRONLY, 0) //nolint:gosec
+	release()
+	if err == nil {
+		return f.Close()
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	return checkWritableDir(filepath.Dir(name))
+}
+
+// writableArtifacts returns the artifacts of a run below base or, if base can't be written to as
+// on CI images with a read-only checkout, below a temporary directory, along with the line
+// telling where they went instead.
+func writableArtifacts(base string) (*tc39Artifacts, string, error) {
+	err := os.MkdirAll(base, 0o755)
+	if err == nil {
+		err = checkWritableDir(base)
+	}
+	if err == nil {
+		return newArtifacts(base), "", nil
+	}
+	tmp, tmpErr := ioutil.TempDir("", "tc39-artifacts")
+	if tmpErr != nil {
+		return nil, "", fmt.Errorf("%s is not writable (%v) and neither is the temporary directory: %w", base, err, tmpErr)
+	}
+	return newArtifacts(tmp), fmt.Sprintf("%s is not writable (%v), the artifacts go to %s instead", base, err, tmp), nil
+}
+
+// checkDestinations checks up front that the files the run was asked to write can be written,
+// so it doesn't find out only after running all the tests.
+func (ctx *tc39TestCtx) checkDestinations() error {
+	if ctx.updateExpected {
+		if err := checkWritableBaseline(ctx.baselineLegacy, ctx.baselineDir); err != nil {
+			return fmt.Errorf("TC39_UPDATE_EXPECTED: the baseline is not writable: %w", err)
+		}
+		if err := checkWritableFile(tc39KnownLimitationsFile); err != nil {
+			return fmt.Errorf("TC39_UPDATE_EXPECTED: the baseline is not writable: %w", err)
+		}
+	}
+	if ctx.benchOut != "" {
+		if err := checkWritableFile(ctx.benchOut); err != nil {
+			return fmt.Errorf("TC39_BENCH_OUT is not writable: %w", err)
+		}
+	}
+	if ctx.reportOut != "" {
+		if err := checkWritableFile(ctx.reportOut); err != nil {
+			return fmt.Errorf("TC39_REPORT is not writable: %w", err)
+		}
+	}
+	if ctx.junitOut != "" {
+		if err := checkWritableFile(ctx.junitOut); err != nil {
+			return fmt.Errorf("TC39_JUNIT is not writable: %w", err)
+		}
+	}
+	if ctx.tapOut != "" && ctx.tapOut != "-" {
+		if err := checkWritableFile(ctx.tapOut); err != nil {
+			return fmt.Errorf("TC39_TAP is not writable: %w", err)
+		}
+	}
+	if ctx.tzPassOut != "" {
+		if err := checkWritableFile(ctx.tzPassOut); err != nil {
+			return fmt.Errorf("TC39_TZ_PASS_OUT is not writable: %w", err)
+		}
+	}
+	return nil
+}
+
+func TestReadOnlyDestinations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc39-read-only")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) //nolint:errcheck
+	readOnly := filepath.Join(dir, "read-only")
+	require.NoError(t, os.Mkdir(readOnly, 0o555))
+	defer os.Chmod(readOnly, 0o755) //nolint:errcheck
+	if checkWritableDir(readOnly) == nil {
+		t.Skip("the permissions aren't enforced, as when running as root")
+	}
+
+	a, note, err := writableArtifacts(filepath.Join(dir, "artifacts"))
+	require.NoError(t, err)
+	require.Empty(t, note)
+	require.Equal(t, filepath.Join(dir, "artifacts", a.runID), a.dir)
+
+	a, note, err = writableArtifacts(filepath.Join(readOnly, "artifacts"))
+	require.NoError(t, err)
+	require.Contains(t, note, "read-only/artifacts is not writable")
+	defer os.RemoveAll(filepath.Dir(a.dir)) //nolint:errcheck
+	file, err := a.path(tc39CoverageFile)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(file, nil, 0o644))
+
+	require.NoError(t, checkWritableFile(filepath.Join(dir, "bench.json")))
+	ctx := &tc39TestCtx{benchOut: filepath.Join(readOnly, "bench.json")}
+	err = ctx.checkDestinations()
+	require.True(t, errors.Is(err, os.ErrPermission), err)
+	require.Contains(t, err.Error(), "TC39_BENCH_OUT is not writable")
+}
@@ -0,0 +1,23 @@
+// Copyright (C) 2017 Ecma International.  All rights reserved.
+// This code is governed by the BSD license found in the LICENSE file.
+/*---
+description: |
+    Defines the $DONE function for asynchronous tests, printing how they completed.
+defines: [$DONE]
+---*/
+
+function __consolePrintHandle__(msg) {
+  print(msg);
+}
+
+function $DONE(error) {
+  if (error) {
+    if (typeof error === 'object' && error !== null && 'name' in error) {
+      __consolePrintHandle__('Test262:AsyncTestFailure:' + error.name + ': ' + error.message);
+    } else {
+      __consolePrintHandle__('Test262:AsyncTestFailure:Test262Error: ' + error);
+    }
+  } else {
+    __consolePrintHandle__('Test262:AsyncTestComplete');
+  }
+}
@@ -0,0 +1,3 @@
+// A harness file relying on something the runner doesn't have, like the ones listed in the
+// includes of skip_features.yaml.
+throw new Test262Error("unsupportedHelper.js needs what this host doesn't have");
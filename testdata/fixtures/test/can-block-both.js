@@ -0,0 +1,5 @@
+/*---
+es6id: 24.4.11
+description: A test that can and can't block at the same time.
+flags: [CanBlockIsFalse, CanBlockIsTrue]
+---*/
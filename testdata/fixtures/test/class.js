@@ -0,0 +1,11 @@
+/*---
+es6id: 14.5
+description: goja can't parse a class, so Babel transforms the test before it's compiled.
+---*/
+
+class Point {
+  constructor(x) {
+    this.x = x;
+  }
+}
+assert.sameValue(new Point(1).x, 1);
@@ -0,0 +1,6 @@
+/*---
+es5id: 15.4.4.19
+description: ES5 runs the same in both compatibility modes.
+---*/
+
+assert.sameValue([1, 2].map(function(v) { return v * 2; }).join(), "2,4");
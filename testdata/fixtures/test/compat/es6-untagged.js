@@ -0,0 +1,6 @@
+/*---
+es6id: 12.2.9
+description: A template literal needs Babel to parse, even if the test doesn't say so.
+---*/
+
+assert.sameValue(`a${1}`, "a1");
@@ -0,0 +1,8 @@
+/*---
+es6id: 14.2
+description: An arrow function needs Babel to parse.
+features: [arrow-function]
+---*/
+
+var double = (v) => v * 2;
+assert.sameValue(double(2), 4);
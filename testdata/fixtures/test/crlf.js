@@ -0,0 +1,11 @@
+// Copyright (C) 2020 the k6 authors. All rights reserved.
+// This code is governed by the BSD license found in the LICENSE file.
+
+/*---
+es6id: 1.1
+description: |
+  Saved with Windows line endings.
+flags: [noStrict]
+---*/
+
+var x = 1;
@@ -0,0 +1,6 @@
+/*---
+es6id: 12.2.9
+description: A failing test Babel transforms.
+---*/
+
+assert.sameValue(`a${1}`, "b");
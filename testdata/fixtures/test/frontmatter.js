@@ -0,0 +1,24 @@
+// Copyright (C) 2020 the k6 authors. All rights reserved.
+// This code is governed by the BSD license found in the LICENSE file.
+
+/*---
+esid: sec-intl.collator
+es6id: 10.1.1
+es5id: 10.1.1_1
+description: >
+  A test with every field
+  of the frontmatter.
+info: |
+  The info block, kept
+  as it is.
+author: Jane Doe
+locale: [en-US, de]
+includes: [compareArray.js]
+flags: [noStrict]
+features: [Symbol]
+negative:
+  phase: runtime
+  type: TypeError
+---*/
+
+throw new TypeError();
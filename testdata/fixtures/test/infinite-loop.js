@@ -0,0 +1,6 @@
+/*---
+es6id: 13.7.3
+description: A test that never finishes is interrupted once TC39_TEST_TIMEOUT passed.
+---*/
+
+while (true) {}
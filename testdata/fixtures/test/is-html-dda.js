@@ -0,0 +1,7 @@
+/*---
+es6id: 12.5.6
+description: A test of the [[IsHTMLDDA]] slot needs the host to have an object with it.
+features: [IsHTMLDDA]
+---*/
+
+assert.sameValue(typeof $262.IsHTMLDDA, "undefined");
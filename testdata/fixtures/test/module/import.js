@@ -0,0 +1,17 @@
+/*---
+es6id: 15.2.1.16.4
+description: A module test gets the modules it imports from its directory, itself included.
+flags: [module]
+---*/
+
+import { value, twice } from './import_FIXTURE.js';
+import * as self from './import.js';
+import other from './sub/default_FIXTURE.js';
+
+export var own = 'own';
+
+assert.sameValue(value, 21);
+assert.sameValue(twice(value), 42);
+assert.sameValue(other, 'default');
+assert.sameValue(self.own, 'own');
+assert.sameValue(this, undefined, 'module code is strict');
@@ -0,0 +1,4 @@
+export var value = 21;
+export function twice(x) {
+  return 2 * x;
+}
@@ -0,0 +1,7 @@
+/*---
+es6id: 15.2.1.16.4
+description: A missing module fails the test importing it at the resolution phase.
+flags: [module]
+---*/
+
+import './missing_FIXTURE.js';
@@ -0,0 +1,12 @@
+/*---
+es6id: 15.2.1.16.4
+description: A module that can't be parsed fails the test importing it at the resolution phase.
+negative:
+  phase: resolution
+  type: SyntaxError
+flags: [module]
+---*/
+
+throw "the imports are resolved before anything runs";
+
+import './resolution_FIXTURE.js';
@@ -0,0 +1 @@
+export var 1;
@@ -0,0 +1,3 @@
+import { value } from '../import_FIXTURE.js';
+
+export default value === 21 ? 'default' : 'wrong';
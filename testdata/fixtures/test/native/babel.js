@@ -0,0 +1,6 @@
+/*---
+es6id: 12.2.9
+description: A failure of a test only Babel makes goja parse.
+---*/
+
+assert.sameValue(`a${1}`, "b");
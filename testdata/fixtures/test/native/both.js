@@ -0,0 +1,6 @@
+/*---
+es5id: 15.8.2.11
+description: A failure of goja itself.
+---*/
+
+assert.sameValue(Math.max(1, 2), 1);
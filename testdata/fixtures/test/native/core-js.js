@@ -0,0 +1,6 @@
+/*---
+es6id: 18.1
+description: The global object has no non-standard asap function.
+---*/
+
+assert.sameValue(typeof asap, "undefined");
@@ -0,0 +1,6 @@
+/*---
+es6id: 17
+description: The tests after a panicking one still run.
+---*/
+
+assert.sameValue(1 + 1, 2);
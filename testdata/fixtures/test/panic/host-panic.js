@@ -0,0 +1,6 @@
+/*---
+es6id: 17
+description: A host function panicking fails the test.
+---*/
+
+hostPanic();
@@ -0,0 +1,6 @@
+/*---
+es6id: 15.4.4.11
+description: One of the tests of the worker pool that fail.
+---*/
+
+assert.sameValue("b", "pool");
@@ -0,0 +1,9 @@
+/*---
+es6id: 12.5.6
+description: A negative test of the worker pool.
+negative:
+  phase: runtime
+  type: TypeError
+---*/
+
+null.x;
@@ -0,0 +1,13 @@
+/*---
+es6id: 15.4.4.11
+description: Sorts an array, one of the tests of the worker pool that pass.
+---*/
+
+var a = [];
+for (var i = 0; i < 2000; i++) {
+  a.push((i * 2 * 7919) % 2003);
+}
+a.sort(function(x, y) { return x - y; });
+for (var i = 1; i < a.length; i++) {
+  assert(a[i - 1] <= a[i], "sorted at " + i);
+}
@@ -0,0 +1,9 @@
+/*---
+es6id: 10.1.1
+description: A raw test runs without the harness.
+flags: [raw]
+---*/
+
+if (typeof assert !== "undefined" || typeof Test262Error !== "undefined") {
+  throw new Error("the harness was loaded");
+}
@@ -0,0 +1,7 @@
+/*---
+es6id: 20.2.2.24
+description: A test of a skipped feature is skipped even with an es6id.
+features: [BigInt]
+---*/
+
+throw new Test262Error("the test of a skipped feature ran");
@@ -0,0 +1,6 @@
+/*---
+esid: sec-array.prototype.map
+description: A test with only an esid no prefix admits is skipped.
+---*/
+
+throw new Test262Error("the test of an esid no prefix admits ran");
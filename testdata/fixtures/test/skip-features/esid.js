@@ -0,0 +1,6 @@
+/*---
+esid: sec-arraybuffer.prototype.slice
+description: A test with only an esid runs if a prefix admits it.
+---*/
+
+assert.sameValue(typeof ArrayBuffer.prototype.slice, "function");
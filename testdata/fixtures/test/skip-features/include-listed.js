@@ -0,0 +1,7 @@
+/*---
+es6id: 22.1.3
+description: A test including a listed harness file is skipped instead of failing.
+includes: [compareArray.js, unsupportedHelper.js]
+---*/
+
+assert(compareArray([1], [1]));
@@ -0,0 +1,7 @@
+/*---
+es6id: 22.1.3
+description: A test with only supported harness files runs.
+includes: [compareArray.js]
+---*/
+
+assert(compareArray([1], [1]));
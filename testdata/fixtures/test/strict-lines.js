@@ -0,0 +1,7 @@
+/*---
+es5id: 10.1.1
+description: Both variants throw at the line of the file.
+---*/
+
+var strict = (function() { return this; })() === undefined;
+throw new Error("strict: " + strict);
@@ -0,0 +1,6 @@
+/*---
+description: A test outside test262 using its harness
+---*/
+
+assert.sameValue(typeof ownHarness, "undefined");
+assert.sameValue([1, 2, 3].indexOf(2), 1);
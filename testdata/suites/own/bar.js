@@ -0,0 +1,5 @@
+/*---
+description: A test using the harness of its own suite
+---*/
+
+assert.sameValue(ownHarness, true);